@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	handler "github.com/mj3smile/bank-statement-processor/internal/handler/http"
 	"github.com/mj3smile/bank-statement-processor/internal/infra/log"
 	"github.com/mj3smile/bank-statement-processor/internal/infra/server"
+	"github.com/mj3smile/bank-statement-processor/internal/parser"
 	repository "github.com/mj3smile/bank-statement-processor/internal/repository/memory"
 	"github.com/mj3smile/bank-statement-processor/internal/usecase"
 )
@@ -25,22 +27,100 @@ func main() {
 
 	eventBus := event.NewBus(appCtx)
 	uploadRepo := repository.NewUploadRepository()
+	batchRepo := repository.NewBatchRepository()
+	accountRepo := repository.NewAccountRepository()
 	transactionRepo := repository.NewTransactionRepository()
+	profileRepo := repository.NewProfileRepository()
+	fxRateRepo := repository.NewFXRateRepository()
 	defer eventBus.Close()
 
-	statementUseCase := usecase.NewStatement(appCtx, transactionRepo, uploadRepo, eventBus)
-	balanceUseCase := usecase.NewBalance(transactionRepo, uploadRepo)
-	issuesUseCase := usecase.NewIssues(transactionRepo, uploadRepo)
+	var ingestion usecase.IngestionConfig
+	if value := os.Getenv("INGEST_WORKERS"); value != "" {
+		workers, err := strconv.Atoi(value)
+		if err != nil || workers <= 0 {
+			log.Fatal(appCtx, fmt.Sprintf("invalid INGEST_WORKERS '%s': must be a positive number", value))
+		}
+		ingestion.Workers = workers
+	}
+	if value := os.Getenv("INGEST_QUEUE_SIZE"); value != "" {
+		queueSize, err := strconv.Atoi(value)
+		if err != nil || queueSize <= 0 {
+			log.Fatal(appCtx, fmt.Sprintf("invalid INGEST_QUEUE_SIZE '%s': must be a positive number", value))
+		}
+		ingestion.QueueSize = queueSize
+	}
+
+	var archive usecase.ArchiveConfig
+	if value := os.Getenv("ARCHIVE_MAX_ENTRIES"); value != "" {
+		entries, err := strconv.Atoi(value)
+		if err != nil || entries <= 0 {
+			log.Fatal(appCtx, fmt.Sprintf("invalid ARCHIVE_MAX_ENTRIES '%s': must be a positive number", value))
+		}
+		archive.MaxEntries = entries
+	}
+	if value := os.Getenv("ARCHIVE_MAX_SIZE"); value != "" {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size <= 0 {
+			log.Fatal(appCtx, fmt.Sprintf("invalid ARCHIVE_MAX_SIZE '%s': must be a positive number of bytes", value))
+		}
+		archive.MaxSize = size
+	}
+
+	rulesUseCase := usecase.NewRules(os.Getenv("RULES_FILE"))
+	if err := rulesUseCase.Reload(appCtx); err != nil {
+		log.Fatal(appCtx, fmt.Sprintf("failed to load RULES_FILE: %v", err))
+	}
+	rulesReloadInterval := usecase.DefaultRulesReloadInterval
+	if value := os.Getenv("RULES_RELOAD_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			log.Fatal(appCtx, fmt.Sprintf("invalid RULES_RELOAD_INTERVAL '%s': must be a positive duration such as 5s", value))
+		}
+		rulesReloadInterval = interval
+	}
+	go rulesUseCase.Watch(appCtx, rulesReloadInterval)
+
+	statementUseCase := usecase.NewStatement(appCtx, usecase.StatementConfig{
+		TransactionRepo: transactionRepo,
+		UploadRepo:      uploadRepo,
+		BatchRepo:       batchRepo,
+		AccountRepo:     accountRepo,
+		ProfileRepo:     profileRepo,
+		EventBus:        eventBus,
+		Parsers:         parser.NewDefaultRegistry(),
+		Rules:           rulesUseCase,
+		Ingestion:       ingestion,
+		Archive:         archive,
+	})
+	balanceUseCase := usecase.NewBalance(transactionRepo, uploadRepo, fxRateRepo)
+	issuesUseCase := usecase.NewIssues(transactionRepo, uploadRepo, fxRateRepo)
+	profileUseCase := usecase.NewProfile(profileRepo)
+	uploadUseCase := usecase.NewUpload(uploadRepo, batchRepo)
+	fxUseCase := usecase.NewFX(fxRateRepo)
+	accountUseCase := usecase.NewAccount(accountRepo, fxRateRepo)
+
+	var maxUploadSize int64
+	if value := os.Getenv("MAX_UPLOAD_SIZE"); value != "" {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size <= 0 {
+			log.Fatal(appCtx, fmt.Sprintf("invalid MAX_UPLOAD_SIZE '%s': must be a positive number of bytes", value))
+		}
+		maxUploadSize = size
+	}
 
-	statementHandler := handler.NewStatementHandler(statementUseCase)
+	statementHandler := handler.NewStatementHandler(statementUseCase, maxUploadSize)
 	balanceHandler := handler.NewBalanceHandler(balanceUseCase)
 	issuesHandler := handler.NewIssuesHandler(issuesUseCase)
 	healthHandler := handler.NewHealthHandler()
+	profileHandler := handler.NewProfileHandler(profileUseCase)
+	uploadHandler := handler.NewUploadHandler(uploadUseCase)
+	fxHandler := handler.NewFXHandler(fxUseCase)
+	accountHandler := handler.NewAccountHandler(accountUseCase)
 
 	reconciliationConsumer := consumer.NewReconciliationConsumer(eventBus, 3)
 	go reconciliationConsumer.Start(appCtx)
 
-	router := server.NewRouter(statementHandler, balanceHandler, issuesHandler, healthHandler)
+	router := server.NewRouter(statementHandler, balanceHandler, issuesHandler, healthHandler, profileHandler, uploadHandler, fxHandler, accountHandler)
 	addr := ":8080"
 	srv := &http.Server{
 		Addr:    addr,
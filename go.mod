@@ -2,4 +2,4 @@ module github.com/mj3smile/bank-statement-processor
 
 go 1.25.1
 
-require github.com/google/uuid v1.6.0 // indirect
+require github.com/google/uuid v1.6.0
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/mj3smile/bank-statement-processor/internal/usecase"
+)
+
+type AccountHandler struct {
+	accountUseCase usecase.Account
+}
+
+func NewAccountHandler(accountUseCase usecase.Account) *AccountHandler {
+	return &AccountHandler{
+		accountUseCase: accountUseCase,
+	}
+}
+
+func (handler *AccountHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
+	accountID := r.PathValue("id")
+	reportingCurrency, err := parseReportingCurrency(r.URL.Query())
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := handler.accountUseCase.GetBalance(r.Context(), accountID, reportingCurrency)
+	if errors.Is(err, usecase.ErrRateNotFound) {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if errors.Is(err, usecase.ErrAccountNotFound) {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, GetAccountBalanceResponse{
+		AccountID:        result.AccountID,
+		Balance:          result.Balance,
+		Balances:         toCurrencyBalanceDTOs(result.Balances),
+		Reporting:        toReportingBalanceDTO(result.Reporting),
+		Uploads:          result.Uploads,
+		CompletedUploads: result.CompletedUploads,
+	})
+}
+
+func (handler *AccountHandler) GetTransactions(w http.ResponseWriter, r *http.Request) {
+	accountID := r.PathValue("id")
+	page, pageSize, err := parsePagination(r.URL.Query())
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := handler.accountUseCase.GetTransactions(r.Context(), accountID, page, pageSize)
+	if errors.Is(err, usecase.ErrAccountNotFound) {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	transactions := make([]TransactionDTO, 0, len(result.Transactions))
+	for _, t := range result.Transactions {
+		dto := toTransactionDTO(t)
+		dto.UploadID = string(t.UploadID)
+		transactions = append(transactions, dto)
+	}
+
+	respondJSON(w, http.StatusOK, GetAccountTransactionsResponse{
+		AccountID:    result.AccountID,
+		Transactions: transactions,
+		Pagination: PaginationMeta{
+			Page:       page,
+			PageSize:   pageSize,
+			TotalItems: result.TotalCount,
+			TotalPages: (result.TotalCount + pageSize - 1) / pageSize,
+		},
+	})
+}
@@ -1,8 +1,12 @@
 package http
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
 	"github.com/mj3smile/bank-statement-processor/internal/usecase"
 )
 
@@ -23,16 +27,96 @@ func (handler *BalanceHandler) GetBalance(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	result, err := handler.balanceUseCase.Get(r.Context(), uploadID)
+	reportingCurrency, err := parseReportingCurrency(r.URL.Query())
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := handler.balanceUseCase.Get(r.Context(), uploadID, reportingCurrency)
+	if errors.Is(err, usecase.ErrRateNotFound) {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
 	if err != nil || result == nil {
 		respondError(w, http.StatusNotFound, err.Error())
+		return
 	}
 
 	balanceInfo := *result
+	var duplicates *DuplicatesDTO
+	if balanceInfo.Account != "" {
+		duplicates = &DuplicatesDTO{Policy: string(balanceInfo.DedupPolicy), Count: balanceInfo.DuplicateRows}
+	}
 	respondJSON(w, http.StatusOK, GetBalanceResponse{
-		UploadID: balanceInfo.UploadID,
-		Status:   balanceInfo.UploadTaskStatus,
-		Balance:  balanceInfo.Balance,
-		Message:  balanceInfo.UploadTaskMessage,
+		UploadID:       balanceInfo.UploadID,
+		Status:         balanceInfo.UploadTaskStatus,
+		Balance:        balanceInfo.Balance,
+		Balances:       toCurrencyBalanceDTOs(balanceInfo.Balances),
+		OpeningBalance: toStatementBalanceDTO(balanceInfo.OpeningBalance),
+		ClosingBalance: toStatementBalanceDTO(balanceInfo.ClosingBalance),
+		BalanceCheck:   toBalanceCheckDTO(balanceInfo.BalanceCheck),
+		Reporting:      toReportingBalanceDTO(balanceInfo.Reporting),
+		Account:        balanceInfo.Account,
+		Duplicates:     duplicates,
+		QueuePosition:  balanceInfo.QueuePosition,
+		Message:        balanceInfo.UploadTaskMessage,
 	})
 }
+
+func toCurrencyBalanceDTOs(balances []money.Money) []CurrencyBalanceDTO {
+	dtos := make([]CurrencyBalanceDTO, 0, len(balances))
+	for _, b := range balances {
+		dtos = append(dtos, CurrencyBalanceDTO{
+			Currency: string(b.Currency),
+			Amount:   b.Amount,
+			Decimal:  b.Decimal(),
+		})
+	}
+	return dtos
+}
+
+func toReportingBalanceDTO(b *usecase.ReportingBalance) *ReportingBalanceDTO {
+	if b == nil {
+		return nil
+	}
+
+	return &ReportingBalanceDTO{
+		Currency: string(b.Balance.Currency),
+		Amount:   b.Balance.Amount,
+		Decimal:  b.Balance.Decimal(),
+		Rates:    toFXRateDTOs(b.Rates),
+	}
+}
+
+func toStatementBalanceDTO(b *upload.Balance) *StatementBalanceDTO {
+	if b == nil {
+		return nil
+	}
+
+	dto := &StatementBalanceDTO{
+		Amount:   b.Amount,
+		Currency: string(b.Currency),
+	}
+	// balances declared with the upload have no date
+	if !b.Date.IsZero() {
+		dto.Date = b.Date.Format(time.DateOnly)
+	}
+	return dto
+}
+
+func toBalanceCheckDTO(check *upload.BalanceCheck) *BalanceCheckDTO {
+	if check == nil {
+		return nil
+	}
+
+	return &BalanceCheckDTO{
+		Currency:        string(check.Currency),
+		Opening:         check.Opening,
+		Net:             check.Net,
+		ExpectedClosing: check.ExpectedClosing,
+		ComputedClosing: check.ComputedClosing(),
+		Discrepancy:     check.Discrepancy,
+		Balanced:        check.Balanced(),
+	}
+}
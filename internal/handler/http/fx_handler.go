@@ -0,0 +1,80 @@
+package http
+
+import (
+	"errors"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/fx"
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/usecase"
+)
+
+// rateDecimals is how many decimals a rate is shown with when it is not exact, e.g. an inverted rate
+const rateDecimals = 10
+
+type FXHandler struct {
+	fxUseCase usecase.FX
+}
+
+func NewFXHandler(fxUseCase usecase.FX) *FXHandler {
+	return &FXHandler{
+		fxUseCase: fxUseCase,
+	}
+}
+
+func (handler *FXHandler) LoadRates(w http.ResponseWriter, r *http.Request) {
+	const maxRatesSize = 10 << 20 // 10 MB
+	r.Body = http.MaxBytesReader(w, r.Body, maxRatesSize)
+
+	loaded, err := handler.fxUseCase.LoadRates(r.Context(), r.Body)
+	if errors.Is(err, usecase.ErrInvalidRates) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to load rates: "+err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, LoadRatesResponse{
+		Loaded: loaded,
+	})
+}
+
+// parseReportingCurrency reads the optional reporting_currency query parameter.
+func parseReportingCurrency(query url.Values) (money.Currency, error) {
+	code := query.Get(ReportingCurrencyParam)
+	if code == "" {
+		return "", nil
+	}
+
+	currency, err := money.ParseCurrency(code)
+	if err != nil {
+		return "", errors.New("invalid reporting_currency")
+	}
+	return currency, nil
+}
+
+func toFXRateDTOs(rates []*fx.Rate) []FXRateDTO {
+	dtos := make([]FXRateDTO, 0, len(rates))
+	for _, rate := range rates {
+		dtos = append(dtos, FXRateDTO{
+			Date:  rate.Date.Format(time.DateOnly),
+			Base:  string(rate.Base),
+			Quote: string(rate.Quote),
+			Rate:  formatRate(rate.Value),
+		})
+	}
+	return dtos
+}
+
+func formatRate(rate *big.Rat) string {
+	if rate.IsInt() {
+		return rate.Num().String()
+	}
+	return strings.TrimRight(strings.TrimRight(rate.FloatString(rateDecimals), "0"), ".")
+}
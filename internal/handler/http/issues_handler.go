@@ -5,7 +5,9 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
 	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
 	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
 	"github.com/mj3smile/bank-statement-processor/internal/usecase"
@@ -34,28 +36,39 @@ func (handler *IssuesHandler) GetIssues(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	result, err := handler.getIssuesUseCase.GetIssues(r.Context(), filters)
+	reportingCurrency, err := parseReportingCurrency(r.URL.Query())
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := handler.getIssuesUseCase.GetIssues(r.Context(), filters, reportingCurrency)
+	if errors.Is(err, usecase.ErrRateNotFound) {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
 	if err != nil {
 		respondError(w, http.StatusNotFound, err.Error())
 		return
 	}
 
 	transactions := make([]TransactionDTO, 0)
-	for _, t := range result.Transactions {
-		transactions = append(transactions, TransactionDTO{
-			ID:           string(t.ID),
-			Timestamp:    t.Timestamp,
-			Counterparty: t.Counterparty,
-			Type:         string(t.Type),
-			Amount:       t.Amount,
-			Status:       string(t.Status),
-			Description:  t.Description,
-		})
+	for i, t := range result.Transactions {
+		var reportingAmount *int64
+		if result.ReportingAmounts != nil {
+			reportingAmount = &result.ReportingAmounts[i].Amount
+		}
+
+		dto := toTransactionDTO(t)
+		dto.ReportingAmount = reportingAmount
+		transactions = append(transactions, dto)
 	}
 
 	response := GetIssuesResponse{
-		UploadID:     uploadID,
-		Transactions: transactions,
+		UploadID:          uploadID,
+		ReportingCurrency: string(reportingCurrency),
+		Transactions:      transactions,
+		Rates:             toFXRateDTOs(result.Rates),
 		Pagination: PaginationMeta{
 			Page:       filters.Page,
 			PageSize:   filters.PageSize,
@@ -67,28 +80,62 @@ func (handler *IssuesHandler) GetIssues(w http.ResponseWriter, r *http.Request)
 	respondJSON(w, http.StatusOK, response)
 }
 
-func (handler *IssuesHandler) parseFilters(r *http.Request, uploadID string) (*transaction.IssuesFilters, error) {
-	filters := &transaction.IssuesFilters{
-		UploadID: upload.ID(uploadID),
-		Page:     1,
-		PageSize: 20, // default
+func toTransactionDTO(t *transaction.Transaction) TransactionDTO {
+	return TransactionDTO{
+		ID:           string(t.ID),
+		Timestamp:    t.Timestamp,
+		Counterparty: t.Counterparty,
+		Type:         string(t.Type),
+		Amount:       t.Amount,
+		Currency:     string(t.Currency),
+		Status:       string(t.Status),
+		Description:  t.Description,
+		Remittance:   toRemittanceDTO(t.Remittance),
+		DuplicateOf:  string(t.DuplicateOf),
+		RuleResults:  toRuleResultDTOs(t.RuleResults),
+	}
+}
+
+func toRemittanceDTO(r *transaction.Remittance) *RemittanceDTO {
+	if r == nil {
+		return nil
+	}
+
+	return &RemittanceDTO{
+		EndToEndID:        r.EndToEndID,
+		CreditorReference: r.CreditorReference,
+		DocumentNumbers:   r.DocumentNumbers,
+		Unstructured:      r.Unstructured,
+	}
+}
+
+func toRuleResultDTOs(results []transaction.RuleResult) []RuleResultDTO {
+	if len(results) == 0 {
+		return nil
 	}
 
+	dtos := make([]RuleResultDTO, 0, len(results))
+	for _, result := range results {
+		dtos = append(dtos, RuleResultDTO{
+			Rule:     result.Rule,
+			Severity: string(result.Severity),
+			Message:  result.Message,
+		})
+	}
+	return dtos
+}
+
+func (handler *IssuesHandler) parseFilters(r *http.Request, uploadID string) (*transaction.IssuesFilters, error) {
 	query := r.URL.Query()
-	if pageStr := query.Get("page"); pageStr != "" {
-		page, err := strconv.Atoi(pageStr)
-		if err != nil || page < 1 {
-			return nil, errors.New("invalid page number")
-		}
-		filters.Page = page
+	page, pageSize, err := parsePagination(query)
+	if err != nil {
+		return nil, err
 	}
 
-	if pageSizeStr := query.Get("page_size"); pageSizeStr != "" {
-		pageSize, err := strconv.Atoi(pageSizeStr)
-		if err != nil || pageSize < 1 || pageSize > 100 {
-			return nil, errors.New("invalid page_size (must be between 1 and 100)")
-		}
-		filters.PageSize = pageSize
+	filters := &transaction.IssuesFilters{
+		UploadID: upload.ID(uploadID),
+		Page:     page,
+		PageSize: pageSize,
 	}
 
 	if statusStr := query.Get("status"); statusStr != "" {
@@ -99,6 +146,14 @@ func (handler *IssuesHandler) parseFilters(r *http.Request, uploadID string) (*t
 		filters.Status = &status
 	}
 
+	if currencyStr := query.Get("currency"); currencyStr != "" {
+		currency, err := money.ParseCurrency(currencyStr)
+		if err != nil {
+			return nil, err
+		}
+		filters.Currency = currency
+	}
+
 	if minAmountStr := query.Get("min_amount"); minAmountStr != "" {
 		minAmount, err := strconv.ParseInt(minAmountStr, 10, 64)
 		if err != nil || minAmount < 0 {
@@ -118,9 +173,13 @@ func (handler *IssuesHandler) parseFilters(r *http.Request, uploadID string) (*t
 	if filters.MinAmount != nil && filters.MaxAmount != nil && *filters.MinAmount > *filters.MaxAmount {
 		return nil, errors.New("min_amount must be less than max_amount")
 	}
+	// the same number of minor units is worth very different amounts in different currencies
+	if (filters.MinAmount != nil || filters.MaxAmount != nil) && filters.Currency == "" {
+		return nil, errors.New("min_amount and max_amount require a currency")
+	}
 
 	if fromDateStr := query.Get("from_date"); fromDateStr != "" {
-		fromDate, err := strconv.ParseInt(fromDateStr, 10, 64)
+		fromDate, err := parseDateParam(fromDateStr)
 		if err != nil || fromDate < 0 {
 			return nil, errors.New("invalid from_date")
 		}
@@ -128,7 +187,7 @@ func (handler *IssuesHandler) parseFilters(r *http.Request, uploadID string) (*t
 	}
 
 	if toDateStr := query.Get("to_date"); toDateStr != "" {
-		toDate, err := strconv.ParseInt(toDateStr, 10, 64)
+		toDate, err := parseDateParam(toDateStr)
 		if err != nil || toDate < 0 {
 			return nil, errors.New("invalid to_date")
 		}
@@ -141,3 +200,17 @@ func (handler *IssuesHandler) parseFilters(r *http.Request, uploadID string) (*t
 
 	return filters, nil
 }
+
+// parseDateParam reads a date filter given as Unix seconds or as an RFC 3339 timestamp.
+func parseDateParam(value string) (int64, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return seconds, nil
+	}
+
+	// an unescaped + of an offset arrives as a space
+	t, err := time.Parse(time.RFC3339, strings.Replace(value, " ", "+", 1))
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
@@ -0,0 +1,152 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/profile"
+	"github.com/mj3smile/bank-statement-processor/internal/usecase"
+)
+
+type ProfileHandler struct {
+	profileUseCase usecase.Profile
+}
+
+func NewProfileHandler(profileUseCase usecase.Profile) *ProfileHandler {
+	return &ProfileHandler{
+		profileUseCase: profileUseCase,
+	}
+}
+
+func (handler *ProfileHandler) CreateProfile(w http.ResponseWriter, r *http.Request) {
+	var request ProfileDTO
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	p, err := toProfile(request)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = handler.profileUseCase.Create(r.Context(), p)
+	if errors.Is(err, usecase.ErrInvalidProfile) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, usecase.ErrProfileExists) {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to create profile: "+err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, toProfileDTO(p))
+}
+
+func (handler *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	p, err := handler.profileUseCase.Get(r.Context(), r.PathValue("name"))
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, toProfileDTO(p))
+}
+
+func (handler *ProfileHandler) ListProfiles(w http.ResponseWriter, r *http.Request) {
+	profiles, err := handler.profileUseCase.List(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := ListProfilesResponse{Profiles: make([]ProfileDTO, 0, len(profiles))}
+	for _, p := range profiles {
+		response.Profiles = append(response.Profiles, toProfileDTO(p))
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+func toProfile(dto ProfileDTO) (*profile.Profile, error) {
+	p := &profile.Profile{
+		Name:             dto.Name,
+		SkipRows:         dto.SkipRows,
+		Sheet:            dto.Sheet,
+		Columns:          make(map[profile.Field]string, len(dto.Columns)),
+		TimestampFormats: dto.TimestampFormats,
+		AmountLayout:     profile.AmountLayout(dto.AmountLayout),
+		AmountFormat: profile.AmountFormat{
+			NegativeParentheses: dto.NegativeParentheses,
+		},
+	}
+
+	if dto.Delimiter != "" {
+		if utf8.RuneCountInString(dto.Delimiter) != 1 {
+			return nil, errors.New("delimiter must be a single character")
+		}
+		p.Delimiter, _ = utf8.DecodeRuneInString(dto.Delimiter)
+	}
+
+	if dto.Quote != "" {
+		if utf8.RuneCountInString(dto.Quote) != 1 {
+			return nil, errors.New("quote must be a single character")
+		}
+		p.Quote, _ = utf8.DecodeRuneInString(dto.Quote)
+	}
+
+	if dto.DecimalSeparator != "" {
+		if utf8.RuneCountInString(dto.DecimalSeparator) != 1 {
+			return nil, errors.New("decimal_separator must be a single character")
+		}
+		p.AmountFormat.DecimalSeparator, _ = utf8.DecodeRuneInString(dto.DecimalSeparator)
+	}
+
+	if dto.ThousandsSeparator != "" {
+		if utf8.RuneCountInString(dto.ThousandsSeparator) != 1 {
+			return nil, errors.New("thousands_separator must be a single character")
+		}
+		p.AmountFormat.ThousandsSeparator, _ = utf8.DecodeRuneInString(dto.ThousandsSeparator)
+	}
+
+	for field, header := range dto.Columns {
+		p.Columns[profile.Field(field)] = header
+	}
+
+	return p, nil
+}
+
+func toProfileDTO(p *profile.Profile) ProfileDTO {
+	columns := make(map[string]string, len(p.Columns))
+	for field, header := range p.Columns {
+		columns[string(field)] = header
+	}
+
+	dto := ProfileDTO{
+		Name:                p.Name,
+		Delimiter:           string(p.Delimiter),
+		Quote:               string(p.Quote),
+		SkipRows:            p.SkipRows,
+		Sheet:               p.Sheet,
+		Columns:             columns,
+		TimestampFormats:    p.TimestampFormats,
+		AmountLayout:        string(p.AmountLayout),
+		NegativeParentheses: p.AmountFormat.NegativeParentheses,
+		CreatedAt:           p.CreatedAt.Unix(),
+	}
+	if p.AmountFormat.DecimalSeparator != 0 {
+		dto.DecimalSeparator = string(p.AmountFormat.DecimalSeparator)
+	}
+	if p.AmountFormat.ThousandsSeparator != 0 {
+		dto.ThousandsSeparator = string(p.AmountFormat.ThousandsSeparator)
+	}
+
+	return dto
+}
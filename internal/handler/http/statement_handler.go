@@ -1,59 +1,401 @@
 package http
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
 
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+	"github.com/mj3smile/bank-statement-processor/internal/parser"
 	"github.com/mj3smile/bank-statement-processor/internal/usecase"
 )
 
+// DefaultMaxUploadSize is the upload size limit when none is configured.
+const DefaultMaxUploadSize int64 = 10 << 30 // 10 GB
+
+// maxFieldSize limits the form fields sent along with the file
+const maxFieldSize = 64 << 10
+
 type StatementHandler struct {
 	statementUseCase usecase.Statement
+	maxUploadSize    int64
 }
 
-func NewStatementHandler(uploadStatementUseCase usecase.Statement) *StatementHandler {
+// NewStatementHandler creates the upload handler. maxUploadSize limits the request body in bytes,
+// DefaultMaxUploadSize when zero or less.
+func NewStatementHandler(uploadStatementUseCase usecase.Statement, maxUploadSize int64) *StatementHandler {
+	if maxUploadSize <= 0 {
+		maxUploadSize = DefaultMaxUploadSize
+	}
 	return &StatementHandler{
 		statementUseCase: uploadStatementUseCase,
+		maxUploadSize:    maxUploadSize,
 	}
 }
 
+// UploadStatement streams the file part of a multipart request into the statement use case, which parses
+// rows as they arrive. Form fields must come before the file part. The upload_id, or the batch_id of an
+// archive, is sent as soon as the format is detected and the request stays open until the file is read.
+// A JSON or NDJSON body is taken as the statement itself, see readStatement.
 func (handler *StatementHandler) UploadStatement(w http.ResponseWriter, r *http.Request) {
-	const maxUploadSize = 100 << 20 // 100 MB
-	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	body, filename, opts, ok := handler.readStatement(w, r)
+	if !ok {
+		return
+	}
+
+	handler.upload(w, r, newStreamedFile(body), filename, opts)
+}
 
-	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
-		respondError(w, http.StatusRequestEntityTooLarge, "file too large or invalid form data")
+// ValidateStatement runs a statement through parsing and validation without keeping anything and
+// answers with what was found, as JSON or with format=csv as a CSV file of the invalid rows.
+func (handler *StatementHandler) ValidateStatement(w http.ResponseWriter, r *http.Request) {
+	reportFormat := r.URL.Query().Get(ReportFormatParam)
+	if reportFormat != "" && reportFormat != ReportFormatJSON && reportFormat != ReportFormatCSV {
+		respondError(w, http.StatusBadRequest, "format must be json or csv")
 		return
 	}
 
-	file, header, err := r.FormFile("file")
+	body, filename, opts, ok := handler.readStatement(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := handler.statementUseCase.Validate(r.Context(), body, filename, opts)
+	if isInvalidUpload(err) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
-		respondError(w, http.StatusBadRequest, "missing or invalid file parameter")
+		respondReadError(w, err)
 		return
 	}
-	defer file.Close()
 
-	if !isCSVFile(header.Filename) {
-		file.Close()
-		respondError(w, http.StatusBadRequest, "file must be a CSV")
+	if reportFormat == ReportFormatCSV {
+		respondErrorReport(w, filename, report)
 		return
 	}
+	respondJSON(w, http.StatusOK, toValidateStatementResponse(filename, report))
+}
+
+func toValidateStatementResponse(filename string, report *usecase.ValidationReport) ValidateStatementResponse {
+	layout := report.Layout
+	errs := make([]RejectionDTO, 0, len(report.Errors))
+	for _, rejection := range report.Errors {
+		errs = append(errs, RejectionDTO{
+			Line:  rejection.Line,
+			Raw:   rejection.Raw,
+			Error: rejection.Error,
+		})
+	}
+
+	return ValidateStatementResponse{
+		Filename: filename,
+		Valid:    report.Valid(),
+		Layout: LayoutDTO{
+			Format:    layout.Format,
+			Archive:   layout.Archive,
+			Encoding:  layout.Encoding,
+			BOM:       layout.BOM,
+			Delimiter: layout.Delimiter,
+			Profile:   layout.Profile,
+			Currency:  string(layout.Currency),
+			TimeZone:  layout.TimeZone,
+		},
+		Rows:            report.Rows,
+		ValidRows:       report.ValidRows,
+		InvalidRows:     report.InvalidRows,
+		Errors:          errs,
+		ErrorsTruncated: report.Truncated,
+		FatalError:      report.Fatal,
+		ContentHash:     report.ContentHash,
+		BalanceCheck:    toBalanceCheckDTO(report.BalanceCheck),
+		Statement:       toStatementDetailsDTO(report.Details, report.TrailerMismatches),
+	}
+}
+
+// respondErrorReport sends the invalid rows of a validation report as a CSV download. An error that
+// stopped the statement from being read to the end comes last, without a line number.
+func respondErrorReport(w http.ResponseWriter, filename string, report *usecase.ValidationReport) {
+	name := strings.TrimSuffix(path.Base(filename), path.Ext(filename)) + "-errors.csv"
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	w.WriteHeader(http.StatusOK)
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"line", "error", "raw"})
+	for _, rejection := range report.Errors {
+		out.Write([]string{strconv.Itoa(rejection.Line), rejection.Error, rejection.Raw})
+	}
+	if report.Fatal != "" {
+		out.Write([]string{"", report.Fatal, ""})
+	}
+	out.Flush()
+}
+
+// readStatement finds the statement of an upload request with its options. A multipart form carries the
+// file after its fields, while a JSON or NDJSON body is the statement itself with options in the query
+// string. It answers the request and returns false when there is no statement to read.
+func (handler *StatementHandler) readStatement(w http.ResponseWriter, r *http.Request) (io.Reader, string, usecase.UploadOptions, bool) {
+	if r.ContentLength > handler.maxUploadSize {
+		respondError(w, http.StatusRequestEntityTooLarge, "file too large")
+		return nil, "", usecase.UploadOptions{}, false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, handler.maxUploadSize)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if filename, ok := transactionMediaTypes[mediaType]; ok {
+		opts, err := uploadOptions(r.URL.Query().Get)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return nil, "", usecase.UploadOptions{}, false
+		}
+		return r.Body, filename, opts, true
+	}
 
-	uploadID, err := handler.statementUseCase.Upload(r.Context(), file, header.Filename)
+	form, err := r.MultipartReader()
 	if err != nil {
-		file.Close()
-		respondError(w, http.StatusInternalServerError, "failed to process upload: "+err.Error())
+		respondError(w, http.StatusBadRequest, "invalid form data: "+err.Error())
+		return nil, "", usecase.UploadOptions{}, false
+	}
+
+	fields := make(map[string]string)
+	var file *multipart.Part
+	for file == nil {
+		part, err := form.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			respondReadError(w, err)
+			return nil, "", usecase.UploadOptions{}, false
+		}
+
+		if part.FormName() == "file" {
+			file = part
+			continue
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxFieldSize+1))
+		if err != nil {
+			respondReadError(w, err)
+			return nil, "", usecase.UploadOptions{}, false
+		}
+		if len(value) > maxFieldSize {
+			respondError(w, http.StatusBadRequest, "form field "+part.FormName()+" is too long")
+			return nil, "", usecase.UploadOptions{}, false
+		}
+		fields[part.FormName()] = string(value)
+	}
+	if file == nil {
+		respondError(w, http.StatusBadRequest, "missing or invalid file parameter")
+		return nil, "", usecase.UploadOptions{}, false
+	}
+
+	opts, err := uploadOptions(func(name string) string { return fields[name] })
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return nil, "", usecase.UploadOptions{}, false
+	}
+	return file, file.FileName(), opts, true
+}
+
+// CancelUpload stops a queued or processing upload. Processing stops at the next row, so the upload
+// only shows up as cancelled shortly after.
+func (handler *StatementHandler) CancelUpload(w http.ResponseWriter, r *http.Request) {
+	uploadID := r.PathValue("id")
+	err := handler.statementUseCase.Cancel(r.Context(), uploadID)
+	if errors.Is(err, usecase.ErrUploadNotFound) {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, usecase.ErrUploadFinished) {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to cancel upload: "+err.Error())
 		return
 	}
 
-	respondJSON(w, http.StatusAccepted, UploadStatementResponse{
-		UploadID: string(uploadID),
-		Message:  "CSV upload accepted and processing started",
+	respondJSON(w, http.StatusAccepted, CancelUploadResponse{
+		UploadID: uploadID,
+		Message:  "upload is being cancelled, its transactions are discarded",
 	})
 }
 
-func isCSVFile(filename string) bool {
-	return len(filename) > 4 && filename[len(filename)-4:] == ".csv"
+// transactionMediaTypes are the content types of bodies that are a statement of transactions, with the name
+// the statement is given
+var transactionMediaTypes = map[string]string{
+	"application/json":     "transactions.json",
+	"application/x-ndjson": "transactions.ndjson",
+	"application/ndjson":   "transactions.ndjson",
+	"application/jsonl":    "transactions.jsonl",
+}
+
+// uploadOptions reads the upload options from form fields or query parameters.
+func uploadOptions(param func(name string) string) (usecase.UploadOptions, error) {
+	mode := param(ModeParam)
+	if mode != "" && mode != ModeStrict && mode != ModeLenient {
+		return usecase.UploadOptions{}, errors.New("mode must be strict or lenient")
+	}
+
+	headerRow := 0
+	if value := param(HeaderRowParam); value != "" {
+		var err error
+		headerRow, err = strconv.Atoi(value)
+		if err != nil || headerRow < 1 {
+			return usecase.UploadOptions{}, errors.New("header_row must be a positive number")
+		}
+	}
+
+	openingBalance, err := balanceParam(param, OpeningBalanceParam)
+	if err != nil {
+		return usecase.UploadOptions{}, err
+	}
+	closingBalance, err := balanceParam(param, ClosingBalanceParam)
+	if err != nil {
+		return usecase.UploadOptions{}, err
+	}
+
+	return usecase.UploadOptions{
+		Profile:         param(ProfileParam),
+		Lenient:         mode == ModeLenient,
+		Currency:        param(CurrencyParam),
+		TimeZone:        param(TimeZoneParam),
+		TimestampFormat: param(TimestampFormatParam),
+		Sheet:           param(SheetParam),
+		HeaderRow:       headerRow,
+		OnDuplicate:     usecase.DuplicatePolicy(param(OnDuplicateParam)),
+		Account:         param(AccountParam),
+		Dedup:           upload.DedupPolicy(param(DedupParam)),
+		OpeningBalance:  openingBalance,
+		ClosingBalance:  closingBalance,
+	}, nil
+}
+
+// balanceParam reads a declared statement balance, a signed whole number of minor units.
+func balanceParam(param func(name string) string, name string) (*int64, error) {
+	value := param(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	amount, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, errors.New(name + " must be a whole number of minor units")
+	}
+	return &amount, nil
+}
+
+// upload hands the body to the statement use case and answers with the upload_id, keeping the
+// request open until the use case is done reading it. A queued upload, and one with an on_duplicate
+// policy, has been read by the time it is answered.
+func (handler *StatementHandler) upload(w http.ResponseWriter, r *http.Request, body *streamedFile, filename string, opts usecase.UploadOptions) {
+	// the size of a form includes its other fields, close enough to tell the progress
+	opts.Size = max(r.ContentLength, 0)
+	result, err := handler.statementUseCase.Upload(r.Context(), body, filename, opts)
+	if isInvalidUpload(err) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var duplicateErr *usecase.DuplicateUploadError
+	if errors.As(err, &duplicateErr) {
+		respondJSON(w, http.StatusConflict, DuplicateUploadResponse{Error: err.Error(), UploadID: string(duplicateErr.UploadID)})
+		return
+	}
+	var queueFullErr *usecase.QueueFullError
+	if errors.As(err, &queueFullErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(queueFullErr.RetryAfter.Seconds())))
+		respondError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || errors.Is(err, usecase.ErrArchiveTooLarge) {
+		respondReadError(w, err)
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to process upload: "+err.Error())
+		return
+	}
+	if result.Duplicate {
+		respondJSON(w, http.StatusOK, UploadStatementResponse{
+			UploadID: string(result.UploadID),
+			Message:  "statement has already been uploaded",
+		})
+		return
+	}
+
+	// by default the server reads the rest of the body before writing a response,
+	// full duplex lets the upload_id reach the client while the file is still being sent
+	controller := http.NewResponseController(w)
+	controller.EnableFullDuplex()
+	response := UploadStatementResponse{
+		UploadID:        string(result.UploadID),
+		QueuePosition:   result.QueuePosition,
+		AssumedCurrency: string(result.AssumedCurrency),
+		Message:         "statement upload accepted and processing started",
+	}
+	if result.QueuePosition > 0 {
+		response.Message = "statement upload accepted and waiting to be processed"
+	}
+	if result.BatchID != "" {
+		response.BatchID = string(result.BatchID)
+		response.Message = "archive accepted, each statement is processed as an upload of the batch"
+	}
+	respondJSON(w, http.StatusAccepted, response)
+	controller.Flush()
+
+	// the request body is only readable while the handler runs
+	<-body.closed
+}
+
+// isInvalidUpload reports whether the statement or the options of an upload were rejected.
+func isInvalidUpload(err error) bool {
+	return errors.Is(err, parser.ErrUnsupportedFormat) || errors.Is(err, usecase.ErrInvalidArchive) || errors.Is(err, usecase.ErrProfileNotFound) || errors.Is(err, money.ErrUnknownCurrency) ||
+		errors.Is(err, usecase.ErrInvalidTimeZone) || errors.Is(err, usecase.ErrInvalidTimestampFormat) || errors.Is(err, usecase.ErrInvalidHeaderRow) ||
+		errors.Is(err, usecase.ErrInvalidDuplicatePolicy) || errors.Is(err, usecase.ErrInvalidDedupPolicy)
+}
+
+// streamedFile hands the file part of a request to the use case and reports when it is done with it.
+type streamedFile struct {
+	io.Reader
+	once   sync.Once
+	closed chan struct{}
+}
+
+func newStreamedFile(r io.Reader) *streamedFile {
+	return &streamedFile{
+		Reader: r,
+		closed: make(chan struct{}),
+	}
+}
+
+func (f *streamedFile) Close() error {
+	f.once.Do(func() { close(f.closed) })
+	return nil
+}
+
+func respondReadError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		respondError(w, http.StatusRequestEntityTooLarge, "file too large")
+		return
+	}
+	if errors.Is(err, usecase.ErrArchiveTooLarge) {
+		respondError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	respondError(w, http.StatusBadRequest, "failed to read upload: "+err.Error())
 }
 
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
@@ -1,31 +1,251 @@
 package http
 
 type UploadStatementResponse struct {
+	UploadID      string `json:"upload_id,omitempty"`
+	BatchID       string `json:"batch_id,omitempty"`
+	QueuePosition int    `json:"queue_position,omitempty"`
+	// AssumedCurrency is set when the upload declares no currency, see usecase.UploadResult
+	AssumedCurrency string `json:"assumed_currency,omitempty"`
+	Message         string `json:"message,omitempty"`
+}
+
+// ValidateStatementResponse is the outcome of a dry run. Valid tells whether a strict upload would succeed.
+type ValidateStatementResponse struct {
+	Filename        string               `json:"filename"`
+	Valid           bool                 `json:"valid"`
+	Layout          LayoutDTO            `json:"layout"`
+	Rows            int                  `json:"rows"`
+	ValidRows       int                  `json:"valid_rows"`
+	InvalidRows     int                  `json:"invalid_rows"`
+	Errors          []RejectionDTO       `json:"errors"`
+	ErrorsTruncated bool                 `json:"errors_truncated,omitempty"`
+	FatalError      string               `json:"fatal_error,omitempty"`
+	ContentHash     string               `json:"content_hash,omitempty"`
+	BalanceCheck    *BalanceCheckDTO     `json:"balance_check,omitempty"`
+	Statement       *StatementDetailsDTO `json:"statement,omitempty"`
+}
+
+// LayoutDTO is how a statement was detected to be laid out.
+type LayoutDTO struct {
+	Format    string `json:"format"`
+	Archive   string `json:"archive,omitempty"`
+	Encoding  string `json:"encoding,omitempty"`
+	BOM       bool   `json:"bom,omitempty"`
+	Delimiter string `json:"delimiter,omitempty"`
+	Profile   string `json:"profile,omitempty"`
+	Currency  string `json:"currency,omitempty"`
+	TimeZone  string `json:"timezone,omitempty"`
+}
+
+type CancelUploadResponse struct {
 	UploadID string `json:"upload_id"`
-	Message  string `json:"message,omitempty"`
+	Message  string `json:"message"`
+}
+
+type GetUploadResponse struct {
+	UploadID      string               `json:"upload_id"`
+	BatchID       string               `json:"batch_id,omitempty"`
+	Filename      string               `json:"filename"`
+	Status        string               `json:"status"`
+	Message       string               `json:"message,omitempty"`
+	QueuePosition int                  `json:"queue_position,omitempty"`
+	Format        string               `json:"format,omitempty"`
+	Archive       string               `json:"archive,omitempty"`
+	Encoding      string               `json:"encoding,omitempty"`
+	Delimiter     string               `json:"delimiter,omitempty"`
+	Profile       string               `json:"profile,omitempty"`
+	Currency      string               `json:"currency,omitempty"`
+	Mode          string               `json:"mode"`
+	ContentHash   string               `json:"content_hash,omitempty"`
+	DuplicateOf   string               `json:"duplicate_of,omitempty"`
+	Account       string               `json:"account,omitempty"`
+	RejectedRows  int                  `json:"rejected_rows"`
+	DuplicateRows int                  `json:"duplicate_rows"`
+	BalanceCheck  *BalanceCheckDTO     `json:"balance_check,omitempty"`
+	Statement     *StatementDetailsDTO `json:"statement,omitempty"`
+	Progress      *ProgressDTO         `json:"progress,omitempty"`
+	StartedAt     int64                `json:"started_at"`
+	CompletedAt   int64                `json:"completed_at,omitempty"`
+}
+
+// ProgressDTO tells how far processing has come. Percent and ETA need the size of the file, the ETA is
+// only given while the upload is processed.
+type ProgressDTO struct {
+	RowsProcessed int      `json:"rows_processed"`
+	BytesRead     int64    `json:"bytes_read"`
+	TotalBytes    int64    `json:"total_bytes,omitempty"`
+	Percent       *float64 `json:"percent,omitempty"`
+	RowsPerSecond float64  `json:"rows_per_second"`
+	ETASeconds    *int64   `json:"eta_seconds,omitempty"`
+	StartedAt     int64    `json:"started_at"`
+	UpdatedAt     int64    `json:"updated_at"`
+}
+
+type GetBatchResponse struct {
+	BatchID       string           `json:"batch_id"`
+	Filename      string           `json:"filename"`
+	Archive       string           `json:"archive"`
+	Status        string           `json:"status"`
+	Message       string           `json:"message,omitempty"`
+	QueuePosition int              `json:"queue_position,omitempty"`
+	StatusCounts  map[string]int   `json:"status_counts"`
+	Uploads       []BatchUploadDTO `json:"uploads"`
+	StartedAt     int64            `json:"started_at"`
+	CompletedAt   int64            `json:"completed_at,omitempty"`
+}
+
+type BatchUploadDTO struct {
+	UploadID     string `json:"upload_id"`
+	Filename     string `json:"filename"`
+	Format       string `json:"format,omitempty"`
+	Status       string `json:"status"`
+	Message      string `json:"message,omitempty"`
+	RejectedRows int    `json:"rejected_rows,omitempty"`
+	ContentHash  string `json:"content_hash,omitempty"`
+	DuplicateOf  string `json:"duplicate_of,omitempty"`
 }
 
 type GetBalanceResponse struct {
-	UploadID string `json:"upload_id"`
-	Status   string `json:"status"`
-	Balance  *int64 `json:"balance,omitempty"`
-	Message  string `json:"message,omitempty"`
+	UploadID       string               `json:"upload_id"`
+	Status         string               `json:"status"`
+	Balance        *int64               `json:"balance,omitempty"`
+	Balances       []CurrencyBalanceDTO `json:"balances,omitempty"`
+	OpeningBalance *StatementBalanceDTO `json:"opening_balance,omitempty"`
+	ClosingBalance *StatementBalanceDTO `json:"closing_balance,omitempty"`
+	BalanceCheck   *BalanceCheckDTO     `json:"balance_check,omitempty"`
+	Reporting      *ReportingBalanceDTO `json:"reporting,omitempty"`
+	Account        string               `json:"account,omitempty"`
+	Duplicates     *DuplicatesDTO       `json:"duplicates,omitempty"`
+	QueuePosition  int                  `json:"queue_position,omitempty"`
+	Message        string               `json:"message,omitempty"`
 }
 
-type GetIssuesResponse struct {
-	UploadID     string           `json:"upload_id"`
+// GetAccountBalanceResponse is the balance of an account over the transactions of all its completed uploads.
+type GetAccountBalanceResponse struct {
+	AccountID        string               `json:"account_id"`
+	Balance          *int64               `json:"balance,omitempty"`
+	Balances         []CurrencyBalanceDTO `json:"balances"`
+	Reporting        *ReportingBalanceDTO `json:"reporting,omitempty"`
+	Uploads          int                  `json:"uploads"`
+	CompletedUploads int                  `json:"completed_uploads"`
+}
+
+type GetAccountTransactionsResponse struct {
+	AccountID    string           `json:"account_id"`
 	Transactions []TransactionDTO `json:"transactions"`
 	Pagination   PaginationMeta   `json:"pagination"`
 }
 
+// DuplicatesDTO sums up the transactions of an upload already imported for its account.
+type DuplicatesDTO struct {
+	Policy string `json:"policy"`
+	Count  int    `json:"count"`
+}
+
+type ReportingBalanceDTO struct {
+	Currency string      `json:"currency"`
+	Amount   int64       `json:"amount"`
+	Decimal  string      `json:"decimal"`
+	Rates    []FXRateDTO `json:"rates"`
+}
+
+type FXRateDTO struct {
+	Date  string `json:"date"`
+	Base  string `json:"base"`
+	Quote string `json:"quote"`
+	Rate  string `json:"rate"`
+}
+
+type LoadRatesResponse struct {
+	Loaded int `json:"loaded"`
+}
+
+type CurrencyBalanceDTO struct {
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+	Decimal  string `json:"decimal"`
+}
+
+type StatementBalanceDTO struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	Date     string `json:"date,omitempty"`
+}
+
+// BalanceCheckDTO compares the declared closing balance of a statement with the one its transactions add up to.
+type BalanceCheckDTO struct {
+	Currency        string `json:"currency"`
+	Opening         int64  `json:"opening"`
+	Net             int64  `json:"net"`
+	ExpectedClosing int64  `json:"expected_closing"`
+	ComputedClosing int64  `json:"computed_closing"`
+	Discrepancy     int64  `json:"discrepancy"`
+	Balanced        bool   `json:"balanced"`
+}
+
+// StatementDetailsDTO is what a statement states about itself in the preamble rows above its transactions
+// and the trailer rows below them.
+type StatementDetailsDTO struct {
+	AccountNumber     string               `json:"account_number,omitempty"`
+	IBAN              string               `json:"iban,omitempty"`
+	PeriodStart       string               `json:"period_start,omitempty"`
+	PeriodEnd         string               `json:"period_end,omitempty"`
+	Currency          string               `json:"currency,omitempty"`
+	Trailer           *TrailerDTO          `json:"trailer,omitempty"`
+	TrailerMismatches []TrailerMismatchDTO `json:"trailer_mismatches,omitempty"`
+}
+
+// TrailerDTO holds the totals a trailer states, amounts in minor units of the statement currency.
+type TrailerDTO struct {
+	RowCount     *int64 `json:"row_count,omitempty"`
+	TotalCredits *int64 `json:"total_credits,omitempty"`
+	TotalDebits  *int64 `json:"total_debits,omitempty"`
+	NetTotal     *int64 `json:"net_total,omitempty"`
+}
+
+type TrailerMismatchDTO struct {
+	Field    string `json:"field"`
+	Stated   int64  `json:"stated"`
+	Ingested int64  `json:"ingested"`
+}
+
+type GetIssuesResponse struct {
+	UploadID          string           `json:"upload_id"`
+	ReportingCurrency string           `json:"reporting_currency,omitempty"`
+	Transactions      []TransactionDTO `json:"transactions"`
+	Rates             []FXRateDTO      `json:"rates,omitempty"`
+	Pagination        PaginationMeta   `json:"pagination"`
+}
+
+// TransactionDTO is a transaction. UploadID is set when the transactions of several uploads are listed together.
 type TransactionDTO struct {
-	ID           string `json:"id"`
-	Timestamp    int64  `json:"timestamp"`
-	Counterparty string `json:"counterparty"`
-	Type         string `json:"type"`
-	Amount       int64  `json:"amount"`
-	Status       string `json:"status"`
-	Description  string `json:"description"`
+	ID              string          `json:"id"`
+	UploadID        string          `json:"upload_id,omitempty"`
+	Timestamp       int64           `json:"timestamp"`
+	Counterparty    string          `json:"counterparty"`
+	Type            string          `json:"type"`
+	Amount          int64           `json:"amount"`
+	Currency        string          `json:"currency"`
+	ReportingAmount *int64          `json:"reporting_amount,omitempty"`
+	Status          string          `json:"status"`
+	Description     string          `json:"description"`
+	Remittance      *RemittanceDTO  `json:"remittance,omitempty"`
+	DuplicateOf     string          `json:"duplicate_of,omitempty"`
+	RuleResults     []RuleResultDTO `json:"rule_results,omitempty"`
+}
+
+// RuleResultDTO is a business rule a transaction broke.
+type RuleResultDTO struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+type RemittanceDTO struct {
+	EndToEndID        string   `json:"end_to_end_id,omitempty"`
+	CreditorReference string   `json:"creditor_reference,omitempty"`
+	DocumentNumbers   []string `json:"document_numbers,omitempty"`
+	Unstructured      []string `json:"unstructured,omitempty"`
 }
 
 type PaginationMeta struct {
@@ -35,10 +255,66 @@ type PaginationMeta struct {
 	TotalPages int `json:"total_pages"`
 }
 
+type GetRejectionsResponse struct {
+	UploadID   string         `json:"upload_id"`
+	Status     string         `json:"status"`
+	Rejections []RejectionDTO `json:"rejections"`
+	Pagination PaginationMeta `json:"pagination"`
+}
+
+type GetDuplicatesResponse struct {
+	UploadID   string             `json:"upload_id"`
+	Status     string             `json:"status"`
+	Account    string             `json:"account,omitempty"`
+	Policy     string             `json:"policy,omitempty"`
+	Duplicates []DedupDecisionDTO `json:"duplicates"`
+	Pagination PaginationMeta     `json:"pagination"`
+}
+
+type DedupDecisionDTO struct {
+	Line              int    `json:"line"`
+	Action            string `json:"action"`
+	TransactionID     string `json:"transaction_id,omitempty"`
+	DuplicateOf       string `json:"duplicate_of"`
+	DuplicateOfUpload string `json:"duplicate_of_upload"`
+	Fingerprint       string `json:"fingerprint"`
+}
+
+type RejectionDTO struct {
+	Line  int    `json:"line"`
+	Raw   string `json:"raw,omitempty"`
+	Error string `json:"error"`
+}
+
+type ProfileDTO struct {
+	Name                string            `json:"name"`
+	Delimiter           string            `json:"delimiter,omitempty"`
+	Quote               string            `json:"quote,omitempty"`
+	SkipRows            int               `json:"skip_rows"`
+	Sheet               string            `json:"sheet,omitempty"`
+	Columns             map[string]string `json:"columns"`
+	TimestampFormats    []string          `json:"timestamp_formats,omitempty"`
+	AmountLayout        string            `json:"amount_layout,omitempty"`
+	DecimalSeparator    string            `json:"decimal_separator,omitempty"`
+	ThousandsSeparator  string            `json:"thousands_separator,omitempty"`
+	NegativeParentheses bool              `json:"negative_parentheses,omitempty"`
+	CreatedAt           int64             `json:"created_at,omitempty"`
+}
+
+type ListProfilesResponse struct {
+	Profiles []ProfileDTO `json:"profiles"`
+}
+
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// DuplicateUploadResponse rejects an upload of already processed content, naming the upload that processed it.
+type DuplicateUploadResponse struct {
+	Error    string `json:"error"`
+	UploadID string `json:"upload_id"`
+}
+
 type GetHealthResponse struct {
 	Status    string `json:"status"`
 	Timestamp int64  `json:"timestamp,omitempty"`
@@ -46,4 +322,31 @@ type GetHealthResponse struct {
 
 const (
 	UploadIDParam = "upload_id"
+	ProfileParam  = "profile"
+	ModeParam     = "mode"
+	CurrencyParam = "currency"
+	TimeZoneParam = "timezone"
+	// TimestampFormatParam overrides the timestamp formats of the profile for a single upload
+	TimestampFormatParam = "timestamp_format"
+	// SheetParam and HeaderRowParam pick the worksheet and header row of a spreadsheet
+	SheetParam     = "sheet"
+	HeaderRowParam = "header_row"
+	// OnDuplicateParam is existing or reject, see usecase.DuplicatePolicy
+	OnDuplicateParam = "on_duplicate"
+	AccountParam     = "account"
+	// DedupParam is skip, flag or keep, see upload.DedupPolicy
+	DedupParam = "dedup"
+
+	// OpeningBalanceParam and ClosingBalanceParam declare the balances of a statement in minor units
+	OpeningBalanceParam = "opening_balance"
+	ClosingBalanceParam = "closing_balance"
+
+	ReportingCurrencyParam = "reporting_currency"
+	// ReportFormatParam picks how a validation report is sent, json by default or csv for a download
+	ReportFormatParam = "format"
+	ReportFormatJSON  = "json"
+	ReportFormatCSV   = "csv"
+
+	ModeStrict  = "strict"
+	ModeLenient = "lenient"
 )
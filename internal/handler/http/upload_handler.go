@@ -0,0 +1,260 @@
+package http
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+	"github.com/mj3smile/bank-statement-processor/internal/usecase"
+)
+
+type UploadHandler struct {
+	uploadUseCase usecase.Upload
+}
+
+func NewUploadHandler(uploadUseCase usecase.Upload) *UploadHandler {
+	return &UploadHandler{
+		uploadUseCase: uploadUseCase,
+	}
+}
+
+func (handler *UploadHandler) GetUpload(w http.ResponseWriter, r *http.Request) {
+	task, err := handler.uploadUseCase.GetUpload(r.Context(), r.PathValue("id"))
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	mode := ModeStrict
+	if task.Lenient {
+		mode = ModeLenient
+	}
+	response := GetUploadResponse{
+		UploadID:      string(task.ID),
+		BatchID:       string(task.BatchID),
+		Filename:      task.Filename,
+		Status:        string(task.Status),
+		Message:       task.Message,
+		QueuePosition: task.QueuePosition,
+		Format:        task.Format,
+		Archive:       task.Archive,
+		Encoding:      task.Encoding,
+		Delimiter:     task.Delimiter,
+		Profile:       task.Profile,
+		Currency:      string(task.Currency),
+		Mode:          mode,
+		ContentHash:   task.ContentHash,
+		DuplicateOf:   string(task.DuplicateOf),
+		Account:       task.Account,
+		RejectedRows:  task.RejectedRows,
+		DuplicateRows: task.DuplicateRows,
+		BalanceCheck:  toBalanceCheckDTO(task.BalanceCheck),
+		Statement:     toStatementDetailsDTO(task.Details, task.TrailerMismatches),
+		Progress:      toProgressDTO(task.Progress, task.Status),
+		StartedAt:     task.StartedAt.Unix(),
+	}
+	if !task.CompletedAt.IsZero() {
+		response.CompletedAt = task.CompletedAt.Unix()
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+func toStatementDetailsDTO(details *upload.StatementDetails, mismatches []upload.TrailerMismatch) *StatementDetailsDTO {
+	if details == nil {
+		return nil
+	}
+
+	dto := &StatementDetailsDTO{
+		AccountNumber: details.AccountNumber,
+		IBAN:          details.IBAN,
+		Currency:      string(details.Currency),
+	}
+	if !details.PeriodStart.IsZero() {
+		dto.PeriodStart = details.PeriodStart.Format(time.DateOnly)
+		dto.PeriodEnd = details.PeriodEnd.Format(time.DateOnly)
+	}
+	if details.HasTrailer() {
+		dto.Trailer = &TrailerDTO{
+			RowCount:     details.RowCount,
+			TotalCredits: details.TotalCredits,
+			TotalDebits:  details.TotalDebits,
+			NetTotal:     details.NetTotal,
+		}
+	}
+	for _, mismatch := range mismatches {
+		dto.TrailerMismatches = append(dto.TrailerMismatches, TrailerMismatchDTO{Field: mismatch.Field, Stated: mismatch.Stated, Ingested: mismatch.Ingested})
+	}
+	return dto
+}
+
+func toProgressDTO(progress upload.Progress, status upload.Status) *ProgressDTO {
+	if progress.StartedAt.IsZero() {
+		return nil
+	}
+
+	dto := &ProgressDTO{
+		RowsProcessed: progress.Rows,
+		BytesRead:     progress.BytesRead,
+		TotalBytes:    progress.TotalBytes,
+		RowsPerSecond: math.Round(progress.RowsPerSecond()*10) / 10,
+		StartedAt:     progress.StartedAt.Unix(),
+		UpdatedAt:     progress.UpdatedAt.Unix(),
+	}
+	if fraction, ok := progress.Fraction(); ok {
+		percent := math.Round(fraction*1000) / 10
+		dto.Percent = &percent
+	}
+	if remaining, ok := progress.Remaining(); ok && status == upload.StatusProcessing {
+		eta := int64(math.Ceil(remaining.Seconds()))
+		dto.ETASeconds = &eta
+	}
+	return dto
+}
+
+func (handler *UploadHandler) GetRejections(w http.ResponseWriter, r *http.Request) {
+	uploadID := r.PathValue("id")
+	page, pageSize, err := parsePagination(r.URL.Query())
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := handler.uploadUseCase.GetRejections(r.Context(), uploadID, page, pageSize)
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	rejections := make([]RejectionDTO, 0, len(result.Rejections))
+	for _, rejection := range result.Rejections {
+		rejections = append(rejections, RejectionDTO{
+			Line:  rejection.Line,
+			Raw:   rejection.Raw,
+			Error: rejection.Error,
+		})
+	}
+
+	respondJSON(w, http.StatusOK, GetRejectionsResponse{
+		UploadID:   uploadID,
+		Status:     result.UploadTaskStatus,
+		Rejections: rejections,
+		Pagination: PaginationMeta{
+			Page:       page,
+			PageSize:   pageSize,
+			TotalItems: result.TotalCount,
+			TotalPages: (result.TotalCount + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+func (handler *UploadHandler) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	uploadID := r.PathValue("id")
+	page, pageSize, err := parsePagination(r.URL.Query())
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := handler.uploadUseCase.GetDuplicates(r.Context(), uploadID, page, pageSize)
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	duplicates := make([]DedupDecisionDTO, 0, len(result.Decisions))
+	for _, decision := range result.Decisions {
+		duplicates = append(duplicates, DedupDecisionDTO{
+			Line:              decision.Line,
+			Action:            string(decision.Action),
+			TransactionID:     decision.TransactionID,
+			DuplicateOf:       decision.DuplicateOf,
+			DuplicateOfUpload: string(decision.DuplicateOfUpload),
+			Fingerprint:       decision.Fingerprint,
+		})
+	}
+
+	respondJSON(w, http.StatusOK, GetDuplicatesResponse{
+		UploadID:   uploadID,
+		Status:     string(result.Task.Status),
+		Account:    result.Task.Account,
+		Policy:     string(result.Task.DedupPolicy),
+		Duplicates: duplicates,
+		Pagination: PaginationMeta{
+			Page:       page,
+			PageSize:   pageSize,
+			TotalItems: result.TotalCount,
+			TotalPages: (result.TotalCount + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+func (handler *UploadHandler) GetBatch(w http.ResponseWriter, r *http.Request) {
+	result, err := handler.uploadUseCase.GetBatch(r.Context(), r.PathValue("id"))
+	if errors.Is(err, usecase.ErrBatchNotFound) {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	batch := result.Batch
+	response := GetBatchResponse{
+		BatchID:       string(batch.ID),
+		Filename:      batch.Filename,
+		Archive:       batch.Archive,
+		Status:        string(batch.Status),
+		Message:       batch.Message,
+		QueuePosition: batch.QueuePosition,
+		StatusCounts:  make(map[string]int, len(result.StatusCounts)),
+		Uploads:       make([]BatchUploadDTO, 0, len(result.Uploads)),
+		StartedAt:     batch.StartedAt.Unix(),
+	}
+	if !batch.CompletedAt.IsZero() {
+		response.CompletedAt = batch.CompletedAt.Unix()
+	}
+	for status, count := range result.StatusCounts {
+		response.StatusCounts[string(status)] = count
+	}
+	for _, task := range result.Uploads {
+		response.Uploads = append(response.Uploads, BatchUploadDTO{
+			UploadID:     string(task.ID),
+			Filename:     task.Filename,
+			Format:       task.Format,
+			Status:       string(task.Status),
+			Message:      task.Message,
+			RejectedRows: task.RejectedRows,
+			ContentHash:  task.ContentHash,
+			DuplicateOf:  string(task.DuplicateOf),
+		})
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+func parsePagination(query url.Values) (int, int, error) {
+	page, pageSize := 1, 20 // default
+	if pageStr := query.Get("page"); pageStr != "" {
+		p, err := strconv.Atoi(pageStr)
+		if err != nil || p < 1 {
+			return 0, 0, errors.New("invalid page number")
+		}
+		page = p
+	}
+
+	if pageSizeStr := query.Get("page_size"); pageSizeStr != "" {
+		ps, err := strconv.Atoi(pageSizeStr)
+		if err != nil || ps < 1 || ps > 100 {
+			return 0, 0, errors.New("invalid page_size (must be between 1 and 100)")
+		}
+		pageSize = ps
+	}
+
+	return page, pageSize, nil
+}
@@ -11,13 +11,30 @@ func NewRouter(
 	balanceHandler *handler.BalanceHandler,
 	issuesHandler *handler.IssuesHandler,
 	healthHandler *handler.HealthHandler,
+	profileHandler *handler.ProfileHandler,
+	uploadHandler *handler.UploadHandler,
+	fxHandler *handler.FXHandler,
+	accountHandler *handler.AccountHandler,
 ) http.Handler {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("GET /health", healthHandler.GetHealth)
 	mux.HandleFunc("POST /statements", statementHandler.UploadStatement)
+	mux.HandleFunc("POST /statements/validate", statementHandler.ValidateStatement)
 	mux.HandleFunc("GET /balance", balanceHandler.GetBalance)
 	mux.HandleFunc("GET /transactions/issues", issuesHandler.GetIssues)
+	mux.HandleFunc("GET /uploads/{id}", uploadHandler.GetUpload)
+	mux.HandleFunc("DELETE /uploads/{id}", statementHandler.CancelUpload)
+	mux.HandleFunc("POST /uploads/{id}/cancel", statementHandler.CancelUpload)
+	mux.HandleFunc("GET /uploads/{id}/rejections", uploadHandler.GetRejections)
+	mux.HandleFunc("GET /uploads/{id}/duplicates", uploadHandler.GetDuplicates)
+	mux.HandleFunc("GET /batches/{id}", uploadHandler.GetBatch)
+	mux.HandleFunc("GET /accounts/{id}/balance", accountHandler.GetBalance)
+	mux.HandleFunc("GET /accounts/{id}/transactions", accountHandler.GetTransactions)
+	mux.HandleFunc("POST /profiles", profileHandler.CreateProfile)
+	mux.HandleFunc("GET /profiles", profileHandler.ListProfiles)
+	mux.HandleFunc("GET /profiles/{name}", profileHandler.GetProfile)
+	mux.HandleFunc("POST /admin/fx-rates", fxHandler.LoadRates)
 
 	return handler.Logger(mux)
 }
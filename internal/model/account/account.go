@@ -0,0 +1,23 @@
+package account
+
+import (
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+)
+
+type ID string
+
+// Account is a bank account statements are uploaded for, named by the account field of the upload.
+// It is created by its first upload.
+type Account struct {
+	ID ID
+	// UploadIDs are the uploads of the account in the order they were made
+	UploadIDs []upload.ID
+	// CompletedUploads counts the uploads whose transactions have been committed to the account
+	CompletedUploads int
+	// Balances holds the net of the transactions of the completed uploads, one per currency
+	Balances  map[money.Currency]money.Money
+	CreatedAt time.Time
+}
@@ -0,0 +1,26 @@
+package fx
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+)
+
+// Rate is the price of one unit of Base in Quote, in effect from Date until the next rate of the pair.
+type Rate struct {
+	Date  time.Time
+	Base  money.Currency
+	Quote money.Currency
+	Value *big.Rat
+}
+
+// Inverse returns the rate of the opposite direction on the same date.
+func (r *Rate) Inverse() *Rate {
+	return &Rate{
+		Date:  r.Date,
+		Base:  r.Quote,
+		Quote: r.Base,
+		Value: new(big.Rat).Inv(r.Value),
+	}
+}
@@ -0,0 +1,172 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestMoney_Add(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       Money
+		other   Money
+		want    Money
+		wantErr error
+	}{
+		{
+			name:  "it should add amounts of the same currency",
+			m:     New(1050, "USD"),
+			other: New(-50, "USD"),
+			want:  New(1000, "USD"),
+		},
+		{
+			name:    "it should return error when given different currencies",
+			m:       New(1050, "USD"),
+			other:   New(50, "EUR"),
+			wantErr: ErrCurrencyMismatch,
+		},
+		{
+			name:    "it should return error when sum overflows",
+			m:       New(math.MaxInt64, "USD"),
+			other:   New(1, "USD"),
+			wantErr: ErrOverflow,
+		},
+		{
+			name:    "it should return error when sum underflows",
+			m:       New(math.MinInt64, "USD"),
+			other:   New(-1, "USD"),
+			wantErr: ErrOverflow,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.m.Add(tt.other)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Add() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Add() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoney_Sub(t *testing.T) {
+	if _, err := New(0, "USD").Sub(New(math.MinInt64, "USD")); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Sub() error = %v, want %v", err, ErrOverflow)
+	}
+
+	got, err := New(100, "USD").Sub(New(250, "USD"))
+	if err != nil || got != New(-150, "USD") {
+		t.Errorf("Sub() got = %v, %v, want %v", got, err, New(-150, "USD"))
+	}
+}
+
+func TestMoney_Decimal(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Money
+		want string
+	}{
+		{name: "it should use two decimals for USD", m: New(123456, "USD"), want: "1234.56"},
+		{name: "it should pad amounts below one major unit", m: New(-5, "EUR"), want: "-0.05"},
+		{name: "it should use no decimals for JPY", m: New(1500, "JPY"), want: "1500"},
+		{name: "it should use three decimals for KWD", m: New(1500, "KWD"), want: "1.500"},
+		{name: "it should format the smallest amount", m: New(math.MinInt64, "USD"), want: "-92233720368547758.08"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.Decimal(); got != tt.want {
+				t.Errorf("Decimal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCurrency(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		want    Currency
+		wantErr bool
+	}{
+		{name: "it should normalize the currency code", code: " usd ", want: "USD"},
+		{name: "it should return error when given unknown currency", code: "ABC", wantErr: true},
+		{name: "it should return error when given empty currency", code: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCurrency(tt.code)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseCurrency() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseCurrency() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoney_Convert(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       Money
+		to      Currency
+		rate    string
+		want    Money
+		wantErr bool
+	}{
+		{
+			name: "it should convert between currencies with the same minor unit",
+			m:    New(10000, "USD"),
+			to:   "EUR",
+			rate: "0.9215",
+			want: New(9215, "EUR"),
+		},
+		{
+			name: "it should convert into currency without minor unit",
+			m:    New(1050, "USD"),
+			to:   "JPY",
+			rate: "148.2",
+			want: New(1556, "JPY"),
+		},
+		{
+			name: "it should convert from currency with fewer decimals",
+			m:    New(1500, "JPY"),
+			to:   "IDR",
+			rate: "104.5",
+			want: New(15675000, "IDR"),
+		},
+		{
+			name: "it should round negative amounts half away from zero",
+			m:    New(-5, "USD"),
+			to:   "EUR",
+			rate: "0.5",
+			want: New(-3, "EUR"),
+		},
+		{
+			name:    "it should return error when result overflows",
+			m:       New(math.MaxInt64, "USD"),
+			to:      "IDR",
+			rate:    "15000",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, _ := new(big.Rat).SetString(tt.rate)
+			got, err := tt.m.Convert(tt.to, rate)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Convert() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Convert() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package profile
+
+import "time"
+
+type Field string
+
+const (
+	FieldTimestamp    Field = "timestamp"
+	FieldCounterparty Field = "counterparty"
+	FieldType         Field = "type"
+	FieldAmount       Field = "amount"
+	FieldStatus       Field = "status"
+	FieldDescription  Field = "description"
+	FieldCurrency     Field = "currency"
+	FieldDebit        Field = "debit"
+	FieldCredit       Field = "credit"
+)
+
+// Fields lists every field in the canonical column order of a statement record.
+var Fields = []Field{FieldTimestamp, FieldCounterparty, FieldType, FieldAmount, FieldStatus, FieldDescription}
+
+// OptionalFields may be mapped by a profile but are not part of the canonical record.
+var OptionalFields = []Field{FieldCurrency, FieldDebit, FieldCredit}
+
+// AmountLayout tells how the direction of a transaction is written.
+type AmountLayout string
+
+const (
+	// AmountLayoutType has a positive amount and a type column with CREDIT or DEBIT
+	AmountLayoutType AmountLayout = "type"
+	// AmountLayoutSigned has a single signed amount column, negative amounts are debits
+	AmountLayoutSigned AmountLayout = "signed"
+	// AmountLayoutDebitCredit has separate debit and credit columns, one of them filled per row
+	AmountLayoutDebitCredit AmountLayout = "debit_credit"
+)
+
+const (
+	DefaultDelimiter = ','
+	DefaultQuote     = '"'
+)
+
+// AmountFormat describes how numbers are written in the amount columns.
+type AmountFormat struct {
+	// DecimalSeparator marks amounts written in major units, e.g. ',' for 1.234,56.
+	// Without it amounts are whole minor units.
+	DecimalSeparator   rune
+	ThousandsSeparator rune
+	// NegativeParentheses reads (250.00) as -250.00
+	NegativeParentheses bool
+}
+
+// Profile describes how to read a delimited bank export: its dialect and which header maps to which field.
+type Profile struct {
+	Name      string
+	Delimiter rune
+	Quote     rune
+	// SkipRows is the number of rows before the header row
+	SkipRows int
+	// Sheet is the worksheet read from spreadsheets, the first one when empty
+	Sheet   string
+	Columns map[Field]string
+	// TimestampFormats are tried in order on the timestamp column, e.g. "DD/MM/YYYY HH:mm"
+	TimestampFormats []string
+	AmountLayout     AmountLayout
+	AmountFormat     AmountFormat
+	CreatedAt        time.Time
+}
+
+// RequiredFields returns the fields the profile must map for its amount layout.
+func (p *Profile) RequiredFields() []Field {
+	switch p.AmountLayout {
+	case AmountLayoutSigned:
+		return []Field{FieldTimestamp, FieldCounterparty, FieldAmount, FieldStatus, FieldDescription}
+	case AmountLayoutDebitCredit:
+		return []Field{FieldTimestamp, FieldCounterparty, FieldDebit, FieldCredit, FieldStatus, FieldDescription}
+	default:
+		return Fields
+	}
+}
+
+// NormalizesAmounts reports whether amounts need more than the canonical handling of
+// a type column and whole minor units.
+func (p *Profile) NormalizesAmounts() bool {
+	return (p.AmountLayout != "" && p.AmountLayout != AmountLayoutType) || p.AmountFormat != AmountFormat{}
+}
@@ -0,0 +1,30 @@
+package transaction
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// Fingerprint identifies a transaction across the statements of an account, so that one that shows up
+// in overlapping statement periods can be recognized. Counterparty and description are compared
+// case-insensitively and regardless of spacing.
+func Fingerprint(account string, t *Transaction) string {
+	parts := []string{
+		account,
+		strconv.FormatInt(t.Timestamp, 10),
+		normalizeText(t.Counterparty),
+		string(t.Type),
+		strconv.FormatInt(t.Amount, 10),
+		string(t.Currency),
+		normalizeText(t.Description),
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x1f")))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeText(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), " "))
+}
@@ -0,0 +1,44 @@
+package transaction
+
+import "testing"
+
+func TestFingerprint(t *testing.T) {
+	base := Transaction{Timestamp: 1674507883, Counterparty: "JOHN DOE", Type: TypeDebit, Amount: 250000, Currency: "IDR", Description: "restaurant"}
+
+	tests := []struct {
+		name    string
+		account string
+		other   Transaction
+		same    bool
+	}{
+		{
+			name:    "it should match a transaction of another upload regardless of case and spacing",
+			account: "acc-1",
+			other:   Transaction{ID: "other", UploadID: "other", Timestamp: 1674507883, Counterparty: " john  doe", Type: TypeDebit, Amount: 250000, Currency: "IDR", Description: "Restaurant ", Status: StatusPending},
+			same:    true,
+		},
+		{
+			name:    "it should not match a transaction with another amount",
+			account: "acc-1",
+			other:   Transaction{Timestamp: 1674507883, Counterparty: "JOHN DOE", Type: TypeDebit, Amount: 250001, Currency: "IDR", Description: "restaurant"},
+		},
+		{
+			name:    "it should not match a transaction of the same amount in another currency",
+			account: "acc-1",
+			other:   Transaction{Timestamp: 1674507883, Counterparty: "JOHN DOE", Type: TypeDebit, Amount: 250000, Currency: "JPY", Description: "restaurant"},
+		},
+		{
+			name:    "it should not match the same transaction of another account",
+			account: "acc-2",
+			other:   base,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Fingerprint(tt.account, &tt.other) == Fingerprint("acc-1", &base)
+			if got != tt.same {
+				t.Errorf("Fingerprint() same = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
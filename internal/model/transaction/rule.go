@@ -0,0 +1,140 @@
+package transaction
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+)
+
+// Severity tells what breaking a business rule does to a transaction.
+type Severity string
+
+const (
+	// SeverityReject makes the row invalid, so a strict upload fails and a lenient upload quarantines it
+	SeverityReject Severity = "reject"
+	// SeverityFlag keeps the transaction and lists it with the issues to be reviewed
+	SeverityFlag Severity = "flag"
+	// SeverityWarn keeps the transaction and lists it with the issues as a notice only
+	SeverityWarn Severity = "warn"
+)
+
+type RuleKind string
+
+const (
+	// RuleMaxAmount limits the amount of a transaction, optionally of one type or currency only
+	RuleMaxAmount RuleKind = "max_amount"
+	// RuleCounterpartyAllowList only accepts the listed counterparties
+	RuleCounterpartyAllowList RuleKind = "counterparty_allowlist"
+	// RuleCounterpartyBlockList turns down the listed counterparties
+	RuleCounterpartyBlockList RuleKind = "counterparty_blocklist"
+	// RuleNoFutureTimestamp turns down transactions dated after the time they are read
+	RuleNoFutureTimestamp RuleKind = "no_future_timestamp"
+	// RuleDescriptionPattern requires descriptions to match a regular expression, or not to match it
+	RuleDescriptionPattern RuleKind = "description_pattern"
+)
+
+// Rule is a business rule checked on every transaction of an upload, on top of the checks that make a
+// row a transaction at all. Which fields apply depends on Kind.
+type Rule struct {
+	Name     string
+	Kind     RuleKind
+	Severity Severity
+	// Type and Currency limit RuleMaxAmount to transactions of that type or currency
+	Type      Type
+	Currency  money.Currency
+	MaxAmount int64
+	// Counterparties are compared case-insensitively and regardless of spacing
+	Counterparties []string
+	// Tolerance allows RuleNoFutureTimestamp for clocks that are a little ahead
+	Tolerance time.Duration
+	Pattern   *regexp.Regexp
+	// Forbid makes RuleDescriptionPattern turn down descriptions that match Pattern
+	Forbid bool
+
+	counterparties map[string]struct{}
+}
+
+// RuleResult is a rule a transaction broke.
+type RuleResult struct {
+	Rule     string
+	Severity Severity
+	Message  string
+}
+
+func (r RuleResult) Error() string {
+	return fmt.Sprintf("rule %s: %s", r.Rule, r.Message)
+}
+
+// RuleSet holds the business rules in effect. The zero value, like a nil RuleSet, has no rules.
+type RuleSet struct {
+	Rules    []*Rule
+	LoadedAt time.Time
+}
+
+// NewRuleSet prepares rules to be checked. The rules must not be changed afterwards.
+func NewRuleSet(rules []*Rule, loadedAt time.Time) *RuleSet {
+	for _, r := range rules {
+		r.counterparties = make(map[string]struct{}, len(r.Counterparties))
+		for _, counterparty := range r.Counterparties {
+			r.counterparties[normalizeText(counterparty)] = struct{}{}
+		}
+	}
+	return &RuleSet{Rules: rules, LoadedAt: loadedAt}
+}
+
+// Check runs every rule on a transaction read at now. It returns the results of the rules that keep the
+// transaction, and the first rule that rejects it, if any.
+func (s *RuleSet) Check(t *Transaction, now time.Time) (results []RuleResult, rejected *RuleResult) {
+	if s == nil {
+		return nil, nil
+	}
+
+	for _, r := range s.Rules {
+		message, broken := r.check(t, now)
+		if !broken {
+			continue
+		}
+
+		result := RuleResult{Rule: r.Name, Severity: r.Severity, Message: message}
+		if r.Severity == SeverityReject {
+			return nil, &result
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// check reports whether t breaks the rule and why.
+func (r *Rule) check(t *Transaction, now time.Time) (string, bool) {
+	switch r.Kind {
+	case RuleMaxAmount:
+		if (r.Type != "" && t.Type != r.Type) || (r.Currency != "" && t.Currency != r.Currency) {
+			return "", false
+		}
+		if t.Amount > r.MaxAmount {
+			return fmt.Sprintf("amount %d exceeds %d", t.Amount, r.MaxAmount), true
+		}
+	case RuleCounterpartyAllowList:
+		if _, ok := r.counterparties[normalizeText(t.Counterparty)]; !ok {
+			return fmt.Sprintf("counterparty '%s' is not on the allow-list", t.Counterparty), true
+		}
+	case RuleCounterpartyBlockList:
+		if _, ok := r.counterparties[normalizeText(t.Counterparty)]; ok {
+			return fmt.Sprintf("counterparty '%s' is on the block-list", t.Counterparty), true
+		}
+	case RuleNoFutureTimestamp:
+		if at := time.Unix(t.Timestamp, 0); at.After(now.Add(r.Tolerance)) {
+			return fmt.Sprintf("timestamp %s is in the future", at.UTC().Format(time.RFC3339)), true
+		}
+	case RuleDescriptionPattern:
+		if r.Pattern.MatchString(t.Description) == r.Forbid {
+			if r.Forbid {
+				return fmt.Sprintf("description matches '%s'", r.Pattern), true
+			}
+			return fmt.Sprintf("description does not match '%s'", r.Pattern), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,86 @@
+package transaction
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestRuleSet_Check(t *testing.T) {
+	now := time.Date(2024, time.January, 23, 10, 0, 0, 0, time.UTC)
+	base := Transaction{Timestamp: now.Add(-time.Hour).Unix(), Counterparty: "JOHN DOE", Type: TypeDebit, Amount: 250000, Currency: "IDR", Description: "INV-42 restaurant"}
+
+	tests := []struct {
+		name         string
+		rules        []*Rule
+		transaction  func(t Transaction) Transaction
+		wantResults  []RuleResult
+		wantRejected *RuleResult
+	}{
+		{
+			name: "it should flag an amount over the maximum of its type",
+			rules: []*Rule{
+				{Name: "large-credit", Kind: RuleMaxAmount, Severity: SeverityFlag, Type: TypeCredit, MaxAmount: 100000},
+				{Name: "large-debit", Kind: RuleMaxAmount, Severity: SeverityFlag, Type: TypeDebit, MaxAmount: 100000},
+			},
+			transaction: func(t Transaction) Transaction { return t },
+			wantResults: []RuleResult{{Rule: "large-debit", Severity: SeverityFlag, Message: "amount 250000 exceeds 100000"}},
+		},
+		{
+			name:        "it should not apply a maximum of another currency",
+			rules:       []*Rule{{Name: "large-usd", Kind: RuleMaxAmount, Severity: SeverityReject, Currency: "USD", MaxAmount: 100}},
+			transaction: func(t Transaction) Transaction { return t },
+		},
+		{
+			name:         "it should reject a counterparty on the block-list regardless of case and spacing",
+			rules:        []*Rule{{Name: "blocked", Kind: RuleCounterpartyBlockList, Severity: SeverityReject, Counterparties: []string{"john  doe"}}},
+			transaction:  func(t Transaction) Transaction { return t },
+			wantRejected: &RuleResult{Rule: "blocked", Severity: SeverityReject, Message: "counterparty 'JOHN DOE' is on the block-list"},
+		},
+		{
+			name:        "it should warn about a counterparty missing from the allow-list",
+			rules:       []*Rule{{Name: "known", Kind: RuleCounterpartyAllowList, Severity: SeverityWarn, Counterparties: []string{"ACME CORP"}}},
+			transaction: func(t Transaction) Transaction { return t },
+			wantResults: []RuleResult{{Rule: "known", Severity: SeverityWarn, Message: "counterparty 'JOHN DOE' is not on the allow-list"}},
+		},
+		{
+			name:  "it should reject a timestamp in the future beyond the tolerance",
+			rules: []*Rule{{Name: "no-future", Kind: RuleNoFutureTimestamp, Severity: SeverityReject, Tolerance: time.Minute}},
+			transaction: func(t Transaction) Transaction {
+				t.Timestamp = now.Add(time.Hour).Unix()
+				return t
+			},
+			wantRejected: &RuleResult{Rule: "no-future", Severity: SeverityReject, Message: "timestamp 2024-01-23T11:00:00Z is in the future"},
+		},
+		{
+			name:  "it should allow a timestamp within the tolerance",
+			rules: []*Rule{{Name: "no-future", Kind: RuleNoFutureTimestamp, Severity: SeverityReject, Tolerance: time.Minute}},
+			transaction: func(t Transaction) Transaction {
+				t.Timestamp = now.Add(30 * time.Second).Unix()
+				return t
+			},
+		},
+		{
+			name: "it should check descriptions against required and forbidden patterns",
+			rules: []*Rule{
+				{Name: "invoice", Kind: RuleDescriptionPattern, Severity: SeverityWarn, Pattern: regexp.MustCompile(`^INV-\d+`)},
+				{Name: "no-restaurants", Kind: RuleDescriptionPattern, Severity: SeverityFlag, Pattern: regexp.MustCompile(`(?i)restaurant`), Forbid: true},
+			},
+			transaction: func(t Transaction) Transaction { return t },
+			wantResults: []RuleResult{{Rule: "no-restaurants", Severity: SeverityFlag, Message: "description matches '(?i)restaurant'"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transaction := tt.transaction(base)
+			gotResults, gotRejected := NewRuleSet(tt.rules, now).Check(&transaction, now)
+			if !reflect.DeepEqual(gotResults, tt.wantResults) {
+				t.Errorf("Check() results = %v, want %v", gotResults, tt.wantResults)
+			}
+			if !reflect.DeepEqual(gotRejected, tt.wantRejected) {
+				t.Errorf("Check() rejected = %v, want %v", gotRejected, tt.wantRejected)
+			}
+		})
+	}
+}
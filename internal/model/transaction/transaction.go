@@ -1,6 +1,9 @@
 package transaction
 
-import "github.com/mj3smile/bank-statement-processor/internal/model/upload"
+import (
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+)
 
 type (
 	ID     string
@@ -24,13 +27,48 @@ type Transaction struct {
 	Counterparty string
 	Type         Type
 	Amount       int64
+	Currency     money.Currency
 	Status       Status
 	Description  string
+	Remittance   *Remittance
+	// Fingerprint is set for transactions of uploads to an account, see Fingerprint
+	Fingerprint string
+	// DuplicateOf is the transaction of an earlier upload with the same fingerprint, set when the
+	// upload flags duplicates
+	DuplicateOf ID
+	// RuleResults are the business rules the transaction broke without being rejected, see RuleSet
+	RuleResults []RuleResult
+}
+
+// Money returns the amount of the transaction together with its currency.
+func (t *Transaction) Money() money.Money {
+	return money.New(t.Amount, t.Currency)
+}
+
+// CountsTowardsBalance reports whether the transaction moves a balance: it succeeded and is not flagged
+// as a duplicate of a transaction the account has already, which is counted once.
+func (t *Transaction) CountsTowardsBalance() bool {
+	return t.Status == StatusSuccess && t.DuplicateOf == ""
+}
+
+// Remittance holds the payment references a bank passes along with a transaction.
+type Remittance struct {
+	EndToEndID        string
+	CreditorReference string
+	DocumentNumbers   []string
+	Unstructured      []string
+}
+
+func (r *Remittance) IsEmpty() bool {
+	return r.EndToEndID == "" && r.CreditorReference == "" && len(r.DocumentNumbers) == 0 && len(r.Unstructured) == 0
 }
 
 type IssuesFilters struct {
-	UploadID  upload.ID
-	Status    *Status
+	UploadID upload.ID
+	Status   *Status
+	// Currency keeps transactions of that currency only, any when empty. MinAmount and MaxAmount are in its
+	// minor units and only apply along with it
+	Currency  money.Currency
 	MinAmount *int64
 	MaxAmount *int64
 	FromDate  *int64
@@ -0,0 +1,25 @@
+package upload
+
+import "github.com/mj3smile/bank-statement-processor/internal/model/money"
+
+// BalanceCheck compares the closing balance a statement was declared to end with against its opening
+// balance plus the net of its successful transactions, which catches truncated or tampered statements.
+// Amounts are in minor units of Currency.
+type BalanceCheck struct {
+	Currency        money.Currency
+	Opening         int64
+	Net             int64
+	ExpectedClosing int64
+	// Discrepancy is the computed closing balance less the expected one, 0 when they agree
+	Discrepancy int64
+}
+
+// ComputedClosing is the balance the statement ends with according to its transactions.
+func (c *BalanceCheck) ComputedClosing() int64 {
+	return c.Opening + c.Net
+}
+
+// Balanced reports whether the statement ends with the closing balance it was declared to end with.
+func (c *BalanceCheck) Balanced() bool {
+	return c.Discrepancy == 0
+}
@@ -0,0 +1,20 @@
+package upload
+
+import "time"
+
+type BatchID string
+
+// Batch groups the uploads of the statements found in one archive.
+type Batch struct {
+	ID       BatchID
+	Filename string
+	Archive  string
+	// Status is processing until every statement of the archive has been processed
+	Status  Status
+	Message string
+	// QueuePosition is the 1-based place of a queued archive in the ingestion queue
+	QueuePosition int
+	UploadIDs     []ID
+	StartedAt     time.Time
+	CompletedAt   time.Time
+}
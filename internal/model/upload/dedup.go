@@ -0,0 +1,30 @@
+package upload
+
+// DedupPolicy decides what happens to a transaction already imported by an earlier upload of the same account.
+type DedupPolicy string
+
+const (
+	// DedupSkip leaves the transaction out
+	DedupSkip DedupPolicy = "skip"
+	// DedupFlag keeps the transaction and marks it as a duplicate for review
+	DedupFlag DedupPolicy = "flag"
+	// DedupKeep keeps the transaction as it is, the decision is only recorded
+	DedupKeep DedupPolicy = "keep"
+)
+
+// IsValid reports whether p is one of the known policies.
+func (p DedupPolicy) IsValid() bool {
+	return p == DedupSkip || p == DedupFlag || p == DedupKeep
+}
+
+// DedupDecision records a transaction of an upload that matched one imported before.
+type DedupDecision struct {
+	Line   int
+	Action DedupPolicy
+	// TransactionID is the transaction saved for the row, empty when it was skipped
+	TransactionID string
+	// DuplicateOf is the earlier transaction with the same fingerprint, and DuplicateOfUpload its upload
+	DuplicateOf       string
+	DuplicateOfUpload ID
+	Fingerprint       string
+}
@@ -0,0 +1,45 @@
+package upload
+
+import "time"
+
+// Progress tells how far processing of an upload has come. It is recorded every now and then while
+// the statement is read, so a Progress whose UpdatedAt lags behind belongs to an upload that is stuck.
+type Progress struct {
+	// Rows counts the rows read so far, rejected and skipped rows included
+	Rows int
+	// BytesRead counts the bytes of the file read so far, as uploaded and before decompression
+	BytesRead int64
+	// TotalBytes is the size of the file, 0 when it is not known
+	TotalBytes int64
+	// StartedAt is when a worker started processing the upload
+	StartedAt time.Time
+	UpdatedAt time.Time
+}
+
+// RowsPerSecond is the average rate between the start of processing and the last update.
+func (p Progress) RowsPerSecond() float64 {
+	elapsed := p.UpdatedAt.Sub(p.StartedAt).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Rows) / elapsed
+}
+
+// Fraction is the share of the file read so far, ok is false when the size of the file is not known.
+func (p Progress) Fraction() (fraction float64, ok bool) {
+	if p.TotalBytes <= 0 {
+		return 0, false
+	}
+	return min(float64(p.BytesRead)/float64(p.TotalBytes), 1), true
+}
+
+// Remaining estimates the time left to read the rest of the file at the rate it has been read so far.
+// ok is false when there is nothing to estimate from yet.
+func (p Progress) Remaining() (remaining time.Duration, ok bool) {
+	fraction, ok := p.Fraction()
+	elapsed := p.UpdatedAt.Sub(p.StartedAt)
+	if !ok || fraction == 0 || elapsed <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(elapsed) * (1 - fraction) / fraction), true
+}
@@ -0,0 +1,52 @@
+package upload
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgress_Remaining(t *testing.T) {
+	started := time.Date(2024, time.January, 23, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name              string
+		progress          Progress
+		wantRowsPerSecond float64
+		wantRemaining     time.Duration
+		wantOK            bool
+	}{
+		{
+			name:              "it should estimate the time left from the share of the file read",
+			progress:          Progress{Rows: 5000, BytesRead: 250, TotalBytes: 1000, StartedAt: started, UpdatedAt: started.Add(10 * time.Second)},
+			wantRowsPerSecond: 500,
+			wantRemaining:     30 * time.Second,
+			wantOK:            true,
+		},
+		{
+			name:              "it should not estimate the time left without the size of the file",
+			progress:          Progress{Rows: 5000, BytesRead: 250, StartedAt: started, UpdatedAt: started.Add(10 * time.Second)},
+			wantRowsPerSecond: 500,
+		},
+		{
+			name:     "it should not estimate anything before the first update",
+			progress: Progress{TotalBytes: 1000, StartedAt: started, UpdatedAt: started},
+		},
+		{
+			name:              "it should not report time left once more than the expected size was read",
+			progress:          Progress{Rows: 10, BytesRead: 1200, TotalBytes: 1000, StartedAt: started, UpdatedAt: started.Add(time.Second)},
+			wantRowsPerSecond: 10,
+			wantOK:            true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.progress.RowsPerSecond(); got != tt.wantRowsPerSecond {
+				t.Errorf("RowsPerSecond() = %v, want %v", got, tt.wantRowsPerSecond)
+			}
+			got, ok := tt.progress.Remaining()
+			if got != tt.wantRemaining || ok != tt.wantOK {
+				t.Errorf("Remaining() = %v, %v, want %v, %v", got, ok, tt.wantRemaining, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package upload
+
+import (
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+)
+
+// trailer fields a statement can state its totals for
+const (
+	TrailerRowCount     = "row_count"
+	TrailerTotalCredits = "total_credits"
+	TrailerTotalDebits  = "total_debits"
+	TrailerNetTotal     = "net_total"
+)
+
+// StatementDetails is what a statement states about itself in the preamble rows above its transactions
+// and the trailer rows below them. Fields the statement does not state are left zero.
+type StatementDetails struct {
+	AccountNumber string
+	IBAN          string
+	PeriodStart   time.Time
+	PeriodEnd     time.Time
+	Currency      money.Currency
+
+	// trailer totals, nil when not stated. Amounts are in minor units of the statement currency.
+	RowCount     *int64
+	TotalCredits *int64
+	TotalDebits  *int64
+	NetTotal     *int64
+}
+
+// IsZero reports whether the statement states nothing about itself.
+func (d StatementDetails) IsZero() bool {
+	return d.AccountNumber == "" && d.IBAN == "" && d.PeriodStart.IsZero() && d.PeriodEnd.IsZero() &&
+		d.Currency == "" && !d.HasTrailer()
+}
+
+// HasTrailer reports whether the statement states any total to check the ingested rows against.
+func (d StatementDetails) HasTrailer() bool {
+	return d.RowCount != nil || d.TotalCredits != nil || d.TotalDebits != nil || d.NetTotal != nil
+}
+
+// TrailerMismatch is a trailer total that does not agree with the rows read from the statement.
+type TrailerMismatch struct {
+	// Field is one of the Trailer* constants
+	Field    string
+	Stated   int64
+	Ingested int64
+}
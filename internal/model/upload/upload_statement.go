@@ -2,6 +2,8 @@ package upload
 
 import (
 	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
 )
 
 type (
@@ -10,18 +12,88 @@ type (
 )
 
 const (
-	StatusCompleted  Status = "completed"
-	StatusFailed     Status = "failed"
-	StatusProcessing Status = "processing"
+	StatusCompleted           Status = "completed"
+	StatusCompletedWithErrors Status = "completed_with_errors"
+	StatusFailed              Status = "failed"
+	StatusProcessing          Status = "processing"
+	// StatusQueued is an upload waiting for a worker to process it
+	StatusQueued Status = "queued"
+	// StatusDuplicate is an upload whose content was already processed, its transactions are discarded
+	StatusDuplicate Status = "duplicate"
+	// StatusCancelled is an upload stopped on request, its transactions are discarded
+	StatusCancelled Status = "cancelled"
 
-	MessageProcessing string = "CSV is still being processed"
+	MessageProcessing string = "statement is still being processed"
+	MessageQueued     string = "statement is waiting to be processed"
 )
 
 type Task struct {
-	ID          ID
-	Status      Status
-	Filename    string
-	Message     string
+	ID     ID
+	Status Status
+	// BatchID is set when the statement came out of an archive
+	BatchID  BatchID
+	Filename string
+	// Archive is the compression the file was decompressed from, empty when it was not compressed
+	Archive string
+	Format  string
+	Profile string
+	// Currency applies to every row that does not state its own
+	Currency money.Currency
+	// TimeZone is the IANA zone of source timestamps without an offset
+	TimeZone string
+	// Encoding is the detected character encoding of the file, which is transcoded to UTF-8 for parsing
+	Encoding string
+	// BOM reports whether the file started with a byte order mark
+	BOM bool
+	// Delimiter is the detected field delimiter of a delimited file read without a profile
+	Delimiter string
+	// ContentHash is the hex encoded SHA-256 of the statement, after decompression and before transcoding
+	ContentHash string
+	// DuplicateOf is the upload that processed the same content first
+	DuplicateOf ID
+	// Account scopes the transaction fingerprints, duplicates are only looked for when it is set
+	Account     string
+	DedupPolicy DedupPolicy
+	// DuplicateRows counts the transactions found in an earlier upload of the account
+	DuplicateRows int
+	Message       string
+	// QueuePosition is the 1-based place of a queued upload in the ingestion queue
+	QueuePosition int
+	// Progress is recorded from the moment a worker starts processing the upload
+	Progress    Progress
 	StartedAt   time.Time
 	CompletedAt time.Time
+
+	// lenient uploads quarantine invalid rows instead of failing
+	Lenient      bool
+	RejectedRows int
+
+	// statement balances, declared with the upload or by the source format
+	OpeningBalance *Balance
+	ClosingBalance *Balance
+	// BalanceCheck is set once processed when both statement balances are known
+	BalanceCheck *BalanceCheck
+	// Details is what the statement states about itself, set once processed when it states anything
+	Details *StatementDetails
+	// TrailerMismatches lists the trailer totals that disagree with the rows read
+	TrailerMismatches []TrailerMismatch
+}
+
+// IsCompleted reports whether processing finished and the transactions of the upload can be queried.
+func (s Status) IsCompleted() bool {
+	return s == StatusCompleted || s == StatusCompletedWithErrors
+}
+
+// Rejection is a row of a lenient upload that was left out because it could not be read or was invalid.
+type Rejection struct {
+	Line  int
+	Raw   string
+	Error string
+}
+
+// Balance is a signed amount in minor units as of the given date.
+type Balance struct {
+	Amount   int64
+	Currency money.Currency
+	Date     time.Time
 }
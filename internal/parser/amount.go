@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/profile"
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+)
+
+// parseAmount reads an amount written in format and returns it in minor units of the given scale.
+// A leading or trailing minus marks a negative amount, and so do parentheses when the format allows them.
+func parseAmount(value string, format profile.AmountFormat, scale int) (int64, error) {
+	original := value
+	value = strings.TrimSpace(value)
+
+	negative := false
+	if format.NegativeParentheses && strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = strings.TrimSpace(value[1 : len(value)-1])
+	}
+	if strings.HasSuffix(value, "-") {
+		negative = !negative
+		value = strings.TrimSpace(strings.TrimSuffix(value, "-"))
+	} else if strings.HasPrefix(value, "-") {
+		negative = !negative
+		value = strings.TrimSpace(strings.TrimPrefix(value, "-"))
+	} else {
+		value = strings.TrimPrefix(value, "+")
+	}
+
+	if format.ThousandsSeparator != 0 {
+		value = strings.ReplaceAll(value, string(format.ThousandsSeparator), "")
+	}
+
+	if value == "" {
+		return 0, errors.New("empty amount")
+	}
+	if strings.ContainsAny(value, "+-") {
+		return 0, fmt.Errorf("invalid amount '%s'", original)
+	}
+
+	var amount int64
+	if format.DecimalSeparator == 0 {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid amount '%s'", original)
+		}
+		amount = parsed
+	} else {
+		if format.DecimalSeparator != '.' {
+			if strings.Contains(value, ".") {
+				return 0, fmt.Errorf("invalid amount '%s'", original)
+			}
+			value = strings.Replace(value, string(format.DecimalSeparator), ".", 1)
+		}
+		parsed, _, err := parseDecimalAmount(value, scale)
+		if err != nil {
+			return 0, fmt.Errorf("invalid amount '%s': %v", original, err)
+		}
+		amount = parsed
+	}
+
+	if negative {
+		return -amount, nil
+	}
+	return amount, nil
+}
+
+// parseDecimalAmount converts a decimal string with at most one leading sign into an absolute amount
+// in minor units.
+func parseDecimalAmount(value string, scale int) (int64, bool, error) {
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-")
+	if negative || strings.HasPrefix(value, "+") {
+		value = value[1:]
+	}
+	if strings.ContainsAny(value, "+-") {
+		return 0, false, errors.New("more than one sign")
+	}
+
+	whole, fraction, _ := strings.Cut(value, ".")
+	if whole == "" && fraction == "" {
+		return 0, false, errors.New("empty amount")
+	}
+	if len(fraction) > scale {
+		if strings.Trim(fraction[scale:], "0") != "" {
+			return 0, false, fmt.Errorf("more than %d decimal places", scale)
+		}
+		fraction = fraction[:scale]
+	}
+	fraction += strings.Repeat("0", scale-len(fraction))
+
+	amount, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return amount, negative, nil
+}
+
+// amountColumns locates the columns a profile reads amounts from, -1 for the ones it does not use.
+type amountColumns struct {
+	layout profile.AmountLayout
+	format profile.AmountFormat
+	debit  int
+	credit int
+}
+
+// normalize rewrites the type and amount of canonical fields into a CREDIT or DEBIT type with a
+// positive amount in minor units. row is the source row the debit and credit columns are read from.
+func (c *amountColumns) normalize(fields, row []string, scale int) error {
+	switch c.layout {
+	case profile.AmountLayoutSigned:
+		amount, err := parseAmount(fields[ColumnAmount], c.format, scale)
+		if err != nil {
+			return err
+		}
+		setAmount(fields, amount)
+	case profile.AmountLayoutDebitCredit:
+		debit, err := c.optionalAmount(row, c.debit, scale)
+		if err != nil {
+			return err
+		}
+		credit, err := c.optionalAmount(row, c.credit, scale)
+		if err != nil {
+			return err
+		}
+		if (debit == 0) == (credit == 0) {
+			return errors.New("exactly one of the debit and credit columns must hold an amount")
+		}
+		if debit != 0 {
+			fields[ColumnType], fields[ColumnAmount] = string(transaction.TypeDebit), strconv.FormatInt(abs(debit), 10)
+		} else {
+			fields[ColumnType], fields[ColumnAmount] = string(transaction.TypeCredit), strconv.FormatInt(abs(credit), 10)
+		}
+	default:
+		amount, err := parseAmount(fields[ColumnAmount], c.format, scale)
+		if err != nil {
+			return err
+		}
+		// the type column states the direction, a negative amount is left for validation to reject
+		fields[ColumnAmount] = strconv.FormatInt(amount, 10)
+	}
+	return nil
+}
+
+func (c *amountColumns) optionalAmount(row []string, position, scale int) (int64, error) {
+	if position >= len(row) {
+		return 0, fmt.Errorf("expected at least %d columns, got %d", position+1, len(row))
+	}
+	if strings.TrimSpace(row[position]) == "" {
+		return 0, nil
+	}
+	return parseAmount(row[position], c.format, scale)
+}
+
+func setAmount(fields []string, amount int64) {
+	if amount < 0 {
+		fields[ColumnType] = string(transaction.TypeDebit)
+	} else {
+		fields[ColumnType] = string(transaction.TypeCredit)
+	}
+	fields[ColumnAmount] = strconv.FormatInt(abs(amount), 10)
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
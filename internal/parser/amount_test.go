@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/profile"
+)
+
+func Test_parseAmount(t *testing.T) {
+	european := profile.AmountFormat{DecimalSeparator: ',', ThousandsSeparator: '.', NegativeParentheses: true}
+
+	tests := []struct {
+		name    string
+		value   string
+		format  profile.AmountFormat
+		scale   int
+		want    int64
+		wantErr bool
+	}{
+		{name: "it should read whole minor units when no decimal separator is given", value: "-75000", want: -75000},
+		{name: "it should read decimal comma with thousands dots", value: "1.234,56", format: european, scale: 2, want: 123456},
+		{name: "it should read parentheses as negative", value: "(250,00)", format: european, scale: 2, want: -25000},
+		{name: "it should read trailing minus as negative", value: "250,00-", format: european, scale: 2, want: -25000},
+		{name: "it should pad missing decimals", value: "1.500", format: european, scale: 2, want: 150000},
+		{name: "it should use the scale of the currency", value: "1.500", format: european, scale: 0, want: 1500},
+		{name: "it should return error when given too many decimals", value: "1,234", format: european, scale: 2, wantErr: true},
+		{name: "it should return error when given decimal point in decimal comma format", value: "1234.56", format: profile.AmountFormat{DecimalSeparator: ','}, scale: 2, wantErr: true},
+		{name: "it should return error when parentheses are not allowed", value: "(250)", wantErr: true},
+		{name: "it should return error when given empty amount", value: " ", format: european, scale: 2, wantErr: true},
+		{name: "it should return error when given two signs", value: "--5", wantErr: true},
+		{name: "it should return error when given a plus and a minus", value: "+-5", wantErr: true},
+		{name: "it should return error when given two signs in decimal format", value: "--5,00", format: european, scale: 2, wantErr: true},
+		{name: "it should return error when given a plus and a minus in decimal format", value: "+-5,00", format: european, scale: 2, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAmount(tt.value, tt.format, tt.scale)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseAmount() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parseAmount() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseDecimalAmount(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        string
+		want         int64
+		wantNegative bool
+		wantErr      bool
+	}{
+		{name: "it should read an unsigned amount", value: "12.5", want: 1250},
+		{name: "it should read a leading plus", value: "+12.5", want: 1250},
+		{name: "it should read a leading minus as negative", value: "-12.5", want: 1250, wantNegative: true},
+		{name: "it should return error when given two minus signs", value: "--5", wantErr: true},
+		{name: "it should return error when given a plus and a minus", value: "+-5", wantErr: true},
+		{name: "it should return error when given a sign after the digits", value: "5-", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, negative, err := parseDecimalAmount(tt.value, 2)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseDecimalAmount() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want || negative != tt.wantNegative {
+				t.Errorf("parseDecimalAmount() got = %v, %v, want %v, %v", got, negative, tt.want, tt.wantNegative)
+			}
+		})
+	}
+}
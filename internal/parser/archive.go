@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"path"
+	"strings"
+)
+
+// Archive is the container a statement file is wrapped in.
+type Archive string
+
+const (
+	ArchiveNone Archive = ""
+	// ArchiveGzip is a single gzip compressed statement, e.g. statement.csv.gz
+	ArchiveGzip    Archive = "gzip"
+	ArchiveZip     Archive = "zip"
+	ArchiveTar     Archive = "tar"
+	ArchiveTarGzip Archive = "tar.gz"
+)
+
+var (
+	magicGzip = []byte{0x1F, 0x8B}
+	magicZip  = []byte("PK\x03\x04")
+	// magicTar is found at offset 257 of a tar header
+	magicTar = []byte("ustar")
+)
+
+// DetectArchive tells whether a file is an archive or compressed from its name and first bytes.
+// Workbooks are zip files as well but are read as a single statement.
+func DetectArchive(filename string, head []byte) Archive {
+	name := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(name, ".xlsx"), IsWorkbook(head):
+		return ArchiveNone
+	case bytes.HasPrefix(head, magicZip), strings.HasSuffix(name, ".zip"):
+		return ArchiveZip
+	case bytes.HasPrefix(head, magicGzip), strings.HasSuffix(name, ".gz"), strings.HasSuffix(name, ".tgz"):
+		if strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz") || isTar(gunzipHead(head)) {
+			return ArchiveTarGzip
+		}
+		return ArchiveGzip
+	case isTar(head), strings.HasSuffix(name, ".tar"):
+		return ArchiveTar
+	}
+	return ArchiveNone
+}
+
+// IsMultiFile reports whether the archive may hold several statements.
+func (a Archive) IsMultiFile() bool {
+	return a == ArchiveZip || a == ArchiveTar || a == ArchiveTarGzip
+}
+
+// TrimArchiveExtension returns the name of the statement inside a single compressed file.
+func TrimArchiveExtension(filename string) string {
+	if strings.HasSuffix(strings.ToLower(filename), ".gz") {
+		return filename[:len(filename)-len(".gz")]
+	}
+	return filename
+}
+
+// IsArchiveMember reports whether an archive entry is worth reading as a statement. Directories,
+// hidden files and the resource forks macOS adds to zip files are left out.
+func IsArchiveMember(name string) bool {
+	if name == "" || strings.HasSuffix(name, "/") || strings.HasPrefix(name, "__MACOSX/") {
+		return false
+	}
+	return !strings.HasPrefix(path.Base(name), ".")
+}
+
+func isTar(head []byte) bool {
+	return len(head) >= 262 && bytes.Equal(head[257:262], magicTar)
+}
+
+// gunzipHead decompresses as much of a gzip stream as its first bytes allow.
+func gunzipHead(head []byte) []byte {
+	r, err := gzip.NewReader(bytes.NewReader(head))
+	if err != nil {
+		return nil
+	}
+	decompressed, _ := io.ReadAll(io.LimitReader(r, 512))
+	return decompressed
+}
@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func TestDetectArchive(t *testing.T) {
+	var tarball bytes.Buffer
+	tarWriter := tar.NewWriter(&tarball)
+	tarWriter.WriteHeader(&tar.Header{Name: "a.csv", Mode: 0600, Size: 1, Typeflag: tar.TypeReg})
+	tarWriter.Write([]byte("x"))
+	tarWriter.Close()
+
+	var tarGzip bytes.Buffer
+	gzipWriter := gzip.NewWriter(&tarGzip)
+	gzipWriter.Write(tarball.Bytes())
+	gzipWriter.Close()
+
+	var csvGzip bytes.Buffer
+	gzipWriter = gzip.NewWriter(&csvGzip)
+	gzipWriter.Write([]byte("timestamp,counterparty\n"))
+	gzipWriter.Close()
+
+	tests := []struct {
+		name     string
+		filename string
+		head     []byte
+		want     Archive
+	}{
+		{name: "it should detect a plain statement", filename: "statement.csv", head: []byte("timestamp,counterparty\n"), want: ArchiveNone},
+		{name: "it should detect zip by content", filename: "bundle", head: []byte("PK\x03\x04rest"), want: ArchiveZip},
+		{name: "it should not take a workbook for an archive", filename: "statement", head: []byte("PK\x03\x04\x14\x00[Content_Types].xml"), want: ArchiveNone},
+		{name: "it should detect gzip compressed statement", filename: "statement.csv.gz", head: csvGzip.Bytes(), want: ArchiveGzip},
+		{name: "it should detect gzip compressed tar by content", filename: "bundle.gz", head: tarGzip.Bytes(), want: ArchiveTarGzip},
+		{name: "it should detect gzip compressed tar by name", filename: "bundle.tgz", head: csvGzip.Bytes(), want: ArchiveTarGzip},
+		{name: "it should detect tar by content", filename: "bundle", head: tarball.Bytes(), want: ArchiveTar},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectArchive(tt.filename, tt.head); got != tt.want {
+				t.Errorf("DetectArchive() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsArchiveMember(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "statements/account-1.csv", want: true},
+		{name: "statements/", want: false},
+		{name: ".DS_Store", want: false},
+		{name: "__MACOSX/statements/._account-1.csv", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsArchiveMember(tt.name); got != tt.want {
+				t.Errorf("IsArchiveMember() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
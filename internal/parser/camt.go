@@ -0,0 +1,288 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+)
+
+// ISO 20022 bank to customer statement (camt.053) and account report (camt.052).
+// Elements are matched by local name so every message version is accepted.
+type camtParser struct{}
+
+func NewCAMTParser() StatementParser {
+	return &camtParser{}
+}
+
+func (p *camtParser) Format() Format {
+	return FormatCAMT
+}
+
+func (p *camtParser) Extensions() []string {
+	return []string{".camt", ".053", ".052"}
+}
+
+func (p *camtParser) Sniff(head []byte) bool {
+	return bytes.Contains(head, []byte("camt.053")) || bytes.Contains(head, []byte("camt.052")) ||
+		bytes.Contains(head, []byte("<BkToCstmrStmt>")) || bytes.Contains(head, []byte("<BkToCstmrAcctRpt>"))
+}
+
+func (p *camtParser) NewReader(r io.Reader, opts Options) (Reader, error) {
+	return &camtReader{
+		decoder:         xml.NewDecoder(r),
+		defaultCurrency: opts.Currency,
+		location:        locationOrUTC(opts.Location),
+	}, nil
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+// Sts is a plain code up to message version 7 and wraps it in Cd from version 8 on
+type camtStatus struct {
+	Code  string `xml:"Cd"`
+	Value string `xml:",chardata"`
+}
+
+type camtParty struct {
+	Name    string `xml:"Nm"`
+	PtyName string `xml:"Pty>Nm"`
+}
+
+type camtEntry struct {
+	Reference        string     `xml:"NtryRef"`
+	Amount           camtAmount `xml:"Amt"`
+	CreditDebit      string     `xml:"CdtDbtInd"`
+	Status           camtStatus `xml:"Sts"`
+	BookingDate      camtDate   `xml:"BookgDt"`
+	ValueDate        camtDate   `xml:"ValDt"`
+	ServicerRef      string     `xml:"AcctSvcrRef"`
+	AdditionalInfo   string     `xml:"AddtlNtryInf"`
+	TransactionInfos []struct {
+		EndToEndID string    `xml:"Refs>EndToEndId"`
+		Debtor     camtParty `xml:"RltdPties>Dbtr"`
+		Creditor   camtParty `xml:"RltdPties>Cdtr"`
+		Remittance struct {
+			Unstructured []string `xml:"Ustrd"`
+			Structured   []struct {
+				CreditorReference string   `xml:"CdtrRefInf>Ref"`
+				DocumentNumbers   []string `xml:"RfrdDocInf>Nb"`
+			} `xml:"Strd"`
+		} `xml:"RmtInf"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+type camtBalance struct {
+	TypeCode    string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount      camtAmount `xml:"Amt"`
+	CreditDebit string     `xml:"CdtDbtInd"`
+	Date        camtDate   `xml:"Dt"`
+}
+
+type camtReader struct {
+	decoder       *xml.Decoder
+	metadata      Metadata
+	closingBooked bool
+	// Amt always carries Ccy in valid messages, this covers the ones that leave it out
+	defaultCurrency money.Currency
+	// location of booking dates and of date times without offset
+	location *time.Location
+}
+
+func (r *camtReader) Metadata() Metadata {
+	return r.metadata
+}
+
+func (r *camtReader) Read() (*Record, error) {
+	for {
+		tok, err := r.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "Ntry":
+			line, _ := r.decoder.InputPos()
+			var entry camtEntry
+			if err := r.decoder.DecodeElement(&entry, &start); err != nil {
+				return nil, fmt.Errorf("line %d: invalid Ntry: %w", line, err)
+			}
+			return r.toRecord(line, &entry)
+		case "Bal":
+			line, _ := r.decoder.InputPos()
+			var balance camtBalance
+			if err := r.decoder.DecodeElement(&balance, &start); err != nil {
+				return nil, fmt.Errorf("line %d: invalid Bal: %w", line, err)
+			}
+			if err := r.applyBalance(&balance); err != nil {
+				return nil, fmt.Errorf("line %d: invalid balance: %w", line, err)
+			}
+		}
+	}
+}
+
+func (r *camtReader) applyBalance(b *camtBalance) error {
+	switch b.TypeCode {
+	case "OPBD", "PRCD":
+		if r.metadata.OpeningBalance != nil {
+			return nil
+		}
+	case "CLBD", "ITBD":
+	default:
+		return nil
+	}
+
+	amount, _, err := parseDecimalAmount(b.Amount.Value, currencyScale(b.Amount.Currency, r.defaultCurrency))
+	if err != nil {
+		return err
+	}
+	if b.CreditDebit == "DBIT" {
+		amount = -amount
+	}
+
+	date, err := parseCAMTDate(b.Date, time.UTC)
+	if err != nil {
+		return err
+	}
+
+	balance := &upload.Balance{
+		Amount:   amount,
+		Currency: money.Currency(strings.ToUpper(b.Amount.Currency)),
+		Date:     date,
+	}
+	if b.TypeCode == "OPBD" || b.TypeCode == "PRCD" {
+		r.metadata.OpeningBalance = balance
+		return nil
+	}
+
+	// a closing booked balance takes precedence over the interim one of a camt.052 report
+	if b.TypeCode == "ITBD" && r.closingBooked {
+		return nil
+	}
+	r.metadata.ClosingBalance = balance
+	r.closingBooked = b.TypeCode == "CLBD"
+	return nil
+}
+
+func (r *camtReader) toRecord(line int, entry *camtEntry) (*Record, error) {
+	var transactionType transaction.Type
+	switch entry.CreditDebit {
+	case "CRDT":
+		transactionType = transaction.TypeCredit
+	case "DBIT":
+		transactionType = transaction.TypeDebit
+	default:
+		return nil, &RowError{Line: line, Err: fmt.Errorf("invalid CdtDbtInd '%s'", entry.CreditDebit)}
+	}
+
+	statusCode := firstNonEmpty(strings.TrimSpace(entry.Status.Code), strings.TrimSpace(entry.Status.Value))
+	status, err := toCAMTStatus(statusCode)
+	if err != nil {
+		return nil, &RowError{Line: line, Err: err}
+	}
+
+	amount, _, err := parseDecimalAmount(entry.Amount.Value, currencyScale(entry.Amount.Currency, r.defaultCurrency))
+	if err != nil {
+		return nil, &RowError{Line: line, Err: fmt.Errorf("invalid Amt '%s': %w", entry.Amount.Value, err)}
+	}
+
+	bookedAt, err := parseCAMTDate(entry.BookingDate, r.location)
+	if err != nil {
+		bookedAt, err = parseCAMTDate(entry.ValueDate, r.location)
+	}
+	if err != nil {
+		return nil, &RowError{Line: line, Err: fmt.Errorf("invalid booking date: %w", err)}
+	}
+
+	remittance := &transaction.Remittance{}
+	var counterparty string
+	for _, info := range entry.TransactionInfos {
+		party := info.Debtor
+		if transactionType == transaction.TypeDebit {
+			party = info.Creditor
+		}
+		counterparty = firstNonEmpty(counterparty, party.Name, party.PtyName)
+
+		remittance.EndToEndID = firstNonEmpty(remittance.EndToEndID, info.EndToEndID)
+		for _, text := range info.Remittance.Unstructured {
+			if text = strings.TrimSpace(text); text != "" {
+				remittance.Unstructured = append(remittance.Unstructured, text)
+			}
+		}
+		for _, structured := range info.Remittance.Structured {
+			remittance.CreditorReference = firstNonEmpty(remittance.CreditorReference, strings.TrimSpace(structured.CreditorReference))
+			remittance.DocumentNumbers = append(remittance.DocumentNumbers, structured.DocumentNumbers...)
+		}
+	}
+	if remittance.EndToEndID == "NOTPROVIDED" {
+		remittance.EndToEndID = ""
+	}
+
+	counterparty = firstNonEmpty(counterparty, strings.TrimSpace(entry.AdditionalInfo), entry.Reference, entry.ServicerRef)
+	description := firstNonEmpty(strings.Join(remittance.Unstructured, " "), remittance.CreditorReference, strings.TrimSpace(entry.AdditionalInfo))
+
+	record := &Record{
+		Line: line,
+		Fields: []string{
+			strconv.FormatInt(bookedAt.Unix(), 10),
+			counterparty,
+			string(transactionType),
+			strconv.FormatInt(amount, 10),
+			string(status),
+			description,
+		},
+		Currency: entry.Amount.Currency,
+	}
+	if !remittance.IsEmpty() {
+		record.Remittance = remittance
+	}
+
+	return record, nil
+}
+
+func toCAMTStatus(code string) (transaction.Status, error) {
+	switch code {
+	case "BOOK":
+		return transaction.StatusSuccess, nil
+	case "PDNG", "INFO", "FUTR":
+		return transaction.StatusPending, nil
+	case "RJCT":
+		return transaction.StatusFailed, nil
+	}
+	return "", fmt.Errorf("invalid entry status '%s'", code)
+}
+
+// parseCAMTDate reads an ISODate or ISODateTime, the offset of the latter is optional.
+func parseCAMTDate(d camtDate, location *time.Location) (time.Time, error) {
+	if dateTime := strings.TrimSpace(d.DateTime); dateTime != "" {
+		if t, err := time.Parse(time.RFC3339, dateTime); err == nil {
+			return t, nil
+		}
+		return time.ParseInLocation("2006-01-02T15:04:05", dateTime, location)
+	}
+	if d.Date != "" {
+		return time.ParseInLocation(time.DateOnly, strings.TrimSpace(d.Date), location)
+	}
+	return time.Time{}, errors.New("missing date")
+}
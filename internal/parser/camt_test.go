@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+)
+
+const camt053Statement = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="EUR">1000.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Dt><Dt>2023-01-01</Dt></Dt>
+      </Bal>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="EUR">13500.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Dt><Dt>2023-01-31</Dt></Dt>
+      </Bal>
+      <Ntry>
+        <Amt Ccy="EUR">2500.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2023-01-23</Dt></BookgDt>
+        <NtryDtls>
+          <TxDtls>
+            <Refs><EndToEndId>E2E-001</EndToEndId></Refs>
+            <RltdPties><Cdtr><Nm>JOHN DOE</Nm></Cdtr></RltdPties>
+            <RmtInf><Ustrd>restaurant</Ustrd></RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">15000</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>PDNG</Cd></Sts>
+        <BookgDt><DtTm>2023-01-24T10:00:00+01:00</DtTm></BookgDt>
+        <NtryDtls>
+          <TxDtls>
+            <Refs><EndToEndId>NOTPROVIDED</EndToEndId></Refs>
+            <RltdPties><Dbtr><Pty><Nm>ACME CORP</Nm></Pty></Dbtr></RltdPties>
+            <RmtInf>
+              <Strd>
+                <RfrdDocInf><Nb>INV-42</Nb></RfrdDocInf>
+                <CdtrRefInf><Ref>RF18539007547034</Ref></CdtrRefInf>
+              </Strd>
+            </RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+func Test_camtReader_Read(t *testing.T) {
+	tests := []struct {
+		name           string
+		content        string
+		want           [][]string
+		wantRemittance []*transaction.Remittance
+		wantErr        bool
+	}{
+		{
+			name:    "it should return records with remittance information when given camt.053 statement",
+			content: camt053Statement,
+			want: [][]string{
+				{"1674432000", "JOHN DOE", "DEBIT", "250000", "SUCCESS", "restaurant"},
+				{"1674550800", "ACME CORP", "CREDIT", "1500000", "PENDING", "RF18539007547034"},
+			},
+			wantRemittance: []*transaction.Remittance{
+				{EndToEndID: "E2E-001", Unstructured: []string{"restaurant"}},
+				{CreditorReference: "RF18539007547034", DocumentNumbers: []string{"INV-42"}},
+			},
+		},
+		{
+			name:    "it should return error when given entry with unknown status",
+			content: `<Document><BkToCstmrAcctRpt><Rpt><Ntry><Amt Ccy="EUR">1</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts>XXXX</Sts></Ntry></Rpt></BkToCstmrAcctRpt></Document>`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewCAMTParser().NewReader(strings.NewReader(tt.content), Options{})
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+
+			var (
+				got           [][]string
+				gotRemittance []*transaction.Remittance
+			)
+			for {
+				record, err := reader.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					if !tt.wantErr {
+						t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+					}
+					return
+				}
+				got = append(got, record.Fields)
+				gotRemittance = append(gotRemittance, record.Remittance)
+			}
+
+			if tt.wantErr {
+				t.Errorf("Read() error = nil, wantErr %v", tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(gotRemittance, tt.wantRemittance) {
+				t.Errorf("Read() remittance = %+v, want %+v", gotRemittance, tt.wantRemittance)
+			}
+
+			metadata := reader.(MetadataReader).Metadata()
+			if metadata.OpeningBalance == nil || metadata.OpeningBalance.Amount != 100000 {
+				t.Errorf("Metadata() opening balance = %+v, want amount 100000", metadata.OpeningBalance)
+			}
+			if metadata.ClosingBalance == nil || metadata.ClosingBalance.Amount != 1350000 {
+				t.Errorf("Metadata() closing balance = %+v, want amount 1350000", metadata.ClosingBalance)
+			}
+		})
+	}
+}
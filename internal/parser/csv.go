@@ -0,0 +1,280 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/profile"
+)
+
+type csvParser struct{}
+
+func NewCSVParser() StatementParser {
+	return &csvParser{}
+}
+
+func (p *csvParser) Format() Format {
+	return FormatCSV
+}
+
+func (p *csvParser) Extensions() []string {
+	return []string{".csv", ".tsv"}
+}
+
+func (p *csvParser) Sniff(head []byte) bool {
+	firstLine, _, _ := bytes.Cut(head, []byte("\n"))
+	return utf8.Valid(firstLine) && bytes.ContainsAny(firstLine, ",;\t|")
+}
+
+func (p *csvParser) NewReader(r io.Reader, opts Options) (Reader, error) {
+	delimiter, quote := rune(profile.DefaultDelimiter), rune(profile.DefaultQuote)
+	if opts.Delimiter != 0 {
+		delimiter = opts.Delimiter
+	}
+	skipRows := 0
+	if opts.Profile != nil {
+		delimiter, quote = opts.Profile.Delimiter, opts.Profile.Quote
+		skipRows = opts.Profile.SkipRows
+	}
+	if opts.HeaderRow > 0 {
+		skipRows = opts.HeaderRow - 1
+	}
+	rows := newDelimitedReader(r, delimiter, quote)
+
+	details, err := newStatementDetails(opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < skipRows; i++ {
+		cells, line, _, err := rows.readRecord()
+		if err != nil {
+			return nil, fmt.Errorf("failed to skip leading rows: %w", err)
+		}
+		// skipped rows that are not labelled are ignored, as they always were
+		if _, err := details.addRow(cells); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+
+	return newTabularReader(rows, opts, details)
+}
+
+// rowReader returns the cells of a table row by row, with the line each row starts at and its source text.
+type rowReader interface {
+	readRecord() ([]string, int, string, error)
+}
+
+// newTabularReader reads the header row of a table and maps its columns to the canonical ones.
+// Labelled rows above the header, such as the account number or opening balance, are the preamble of
+// the statement and go into details.
+func newTabularReader(rows rowReader, opts Options, details *statementDetails) (*csvRecordReader, error) {
+	var header []string
+	for {
+		cells, line, _, err := rows.readRecord()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read header: %w", err)
+		}
+		preamble, err := details.addRow(cells)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if !preamble {
+			header = cells
+			break
+		}
+	}
+
+	reader := &csvRecordReader{
+		rows:            rows,
+		currency:        -1,
+		defaultCurrency: details.rowCurrency(),
+		timestamps:      details.timestamps,
+		details:         details,
+	}
+	var err error
+	if opts.Profile != nil {
+		reader.columns, err = mapColumns(header, opts.Profile)
+		if err != nil {
+			return nil, err
+		}
+		if reader.currency, err = optionalColumn(header, opts.Profile, profile.FieldCurrency); err != nil {
+			return nil, err
+		}
+		if opts.Profile.NormalizesAmounts() {
+			reader.amounts = &amountColumns{layout: opts.Profile.AmountLayout, format: opts.Profile.AmountFormat}
+			if reader.amounts.debit, err = optionalColumn(header, opts.Profile, profile.FieldDebit); err != nil {
+				return nil, err
+			}
+			if reader.amounts.credit, err = optionalColumn(header, opts.Profile, profile.FieldCredit); err != nil {
+				return nil, err
+			}
+		}
+	} else if reader.currency = findColumn(header, string(profile.FieldCurrency)); reader.currency >= 0 {
+		// without a profile the columns are positional, a currency column may sit anywhere among them
+		for i := range header {
+			if i != reader.currency {
+				reader.columns = append(reader.columns, i)
+			}
+		}
+	}
+
+	return reader, nil
+}
+
+// csvRecordReader reads the rows of a delimited file, or of a spreadsheet, as records.
+type csvRecordReader struct {
+	rows rowReader
+	// columns holds the header position of each canonical column, nil keeps the row as is
+	columns []int
+	// currency is the header position of the currency column, -1 when there is none
+	currency        int
+	defaultCurrency money.Currency
+	// amounts converts the amount columns of profiles with another layout or number format, nil when not needed
+	amounts    *amountColumns
+	timestamps *timestampParser
+	// details holds what the labelled rows around the transactions state
+	details *statementDetails
+}
+
+// Metadata returns what the preamble states as soon as the reader is created, and what the trailer
+// states once Read has returned io.EOF.
+func (r *csvRecordReader) Metadata() Metadata {
+	return r.details.metadata
+}
+
+func (r *csvRecordReader) Read() (*Record, error) {
+	var (
+		fields []string
+		line   int
+		raw    string
+		err    error
+	)
+	for {
+		fields, line, raw, err = r.rows.readRecord()
+		if err != nil {
+			return nil, err
+		}
+		// labelled rows among or below the transactions, such as a row count or total, are the trailer
+		trailer, err := r.details.addRow(fields)
+		if err != nil {
+			return nil, &RowError{Line: line, Raw: raw, Err: err}
+		}
+		if !trailer {
+			break
+		}
+	}
+
+	var currency string
+	if r.currency >= 0 {
+		if r.currency >= len(fields) {
+			return nil, &RowError{Line: line, Raw: raw, Err: fmt.Errorf("expected at least %d columns, got %d", r.currency+1, len(fields))}
+		}
+		currency = fields[r.currency]
+	}
+
+	row := fields
+	if r.columns != nil {
+		fields, err = selectColumns(fields, r.columns)
+		if err != nil {
+			return nil, &RowError{Line: line, Raw: raw, Err: err}
+		}
+	}
+
+	// rows with a wrong number of columns are left for validation to report
+	if len(fields) == ColumnCount {
+		timestamp, err := r.timestamps.parse(fields[ColumnTimestamp])
+		if err != nil {
+			return nil, &RowError{Line: line, Raw: raw, Err: err}
+		}
+		fields[ColumnTimestamp] = strconv.FormatInt(timestamp.Unix(), 10)
+
+		if r.amounts != nil {
+			if err := r.amounts.normalize(fields, row, currencyScale(currency, r.defaultCurrency)); err != nil {
+				return nil, &RowError{Line: line, Raw: raw, Err: err}
+			}
+		}
+	}
+
+	return &Record{
+		Line:     line,
+		Raw:      raw,
+		Fields:   fields,
+		Currency: currency,
+	}, nil
+}
+
+// mapColumns resolves the header names of a profile to positions, matching names case-insensitively.
+// Canonical fields the profile's amount layout does not need are -1 when they are not mapped.
+func mapColumns(header []string, p *profile.Profile) ([]int, error) {
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if _, exists := positions[key]; !exists {
+			positions[key] = i
+		}
+	}
+
+	required := make(map[profile.Field]bool)
+	for _, field := range p.RequiredFields() {
+		required[field] = true
+	}
+
+	columns := make([]int, len(profile.Fields))
+	for i, field := range profile.Fields {
+		name, mapped := p.Columns[field]
+		if !mapped && !required[field] {
+			columns[i] = -1
+			continue
+		}
+		position, ok := positions[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("column '%s' for field %s not found in header", name, field)
+		}
+		columns[i] = position
+	}
+
+	return columns, nil
+}
+
+// optionalColumn returns the position of a field the profile may leave unmapped, -1 when it does.
+func optionalColumn(header []string, p *profile.Profile, field profile.Field) (int, error) {
+	name := p.Columns[field]
+	if name == "" {
+		return -1, nil
+	}
+	position := findColumn(header, name)
+	if position < 0 {
+		return -1, fmt.Errorf("column '%s' for field %s not found in header", name, field)
+	}
+	return position, nil
+}
+
+// findColumn returns the position of the first header matching name case-insensitively, or -1.
+func findColumn(header []string, name string) int {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i, h := range header {
+		if strings.ToLower(strings.TrimSpace(h)) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func selectColumns(row []string, columns []int) ([]string, error) {
+	fields := make([]string, len(columns))
+	for i, position := range columns {
+		if position < 0 {
+			continue
+		}
+		if position >= len(row) {
+			return nil, fmt.Errorf("expected at least %d columns, got %d", position+1, len(row))
+		}
+		fields[i] = row[position]
+	}
+	return fields, nil
+}
@@ -0,0 +1,366 @@
+package parser
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/profile"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+)
+
+func Test_csvRecordReader_Read(t *testing.T) {
+	bankProfile := &profile.Profile{
+		Name:      "bank",
+		Delimiter: ';',
+		Quote:     '\'',
+		SkipRows:  2,
+		Columns: map[profile.Field]string{
+			profile.FieldTimestamp:    "Posted At",
+			profile.FieldCounterparty: "Payee",
+			profile.FieldType:         "Direction",
+			profile.FieldAmount:       "Amount",
+			profile.FieldStatus:       "State",
+			profile.FieldDescription:  "Memo",
+		},
+	}
+
+	tests := []struct {
+		name           string
+		content        string
+		opts           Options
+		want           [][]string
+		wantLines      []int
+		wantCurrencies []string
+		wantErr        bool
+	}{
+		{
+			name: "it should return rows as they are when no profile is given",
+			content: "timestamp,counterparty,type,amount,status,description\n" +
+				"1674507883,JOHN DOE,DEBIT,250000,SUCCESS,restaurant\n" +
+				"\n" +
+				"1674508123,\"ACME, CORP\",CREDIT,1500000,SUCCESS,\"salary\n\"\"january\"\"\"\n" +
+				"1674508456,JANE SMITH,DEBIT,75000,FAILED,payment failed\r\n",
+			want: [][]string{
+				{"1674507883", "JOHN DOE", "DEBIT", "250000", "SUCCESS", "restaurant"},
+				{"1674508123", "ACME, CORP", "CREDIT", "1500000", "SUCCESS", "salary\n\"january\""},
+				{"1674508456", "JANE SMITH", "DEBIT", "75000", "FAILED", "payment failed"},
+			},
+			wantLines: []int{2, 4, 6},
+		},
+		{
+			name: "it should map reordered, renamed and extra columns when given profile",
+			content: "Bank export\nGenerated 2023-01-31\n" +
+				"Memo;State;Amount;Reference;Direction;Payee;posted at\n" +
+				"'restaurant; dinner';SUCCESS;250000;R1;DEBIT;JOHN DOE;1674507883\n",
+			opts: Options{Profile: bankProfile},
+			want: [][]string{
+				{"1674507883", "JOHN DOE", "DEBIT", "250000", "SUCCESS", "restaurant; dinner"},
+			},
+			wantLines: []int{4},
+		},
+		{
+			name: "it should take the currency column out of rows when no profile is given",
+			content: "timestamp,counterparty,currency,type,amount,status,description\n" +
+				"1674507883,JOHN DOE,USD,DEBIT,2500,SUCCESS,restaurant\n" +
+				"1674508123,ACME CORP,eur,CREDIT,150000,SUCCESS,salary\n",
+			want: [][]string{
+				{"1674507883", "JOHN DOE", "DEBIT", "2500", "SUCCESS", "restaurant"},
+				{"1674508123", "ACME CORP", "CREDIT", "150000", "SUCCESS", "salary"},
+			},
+			wantLines:      []int{2, 3},
+			wantCurrencies: []string{"USD", "eur"},
+		},
+		{
+			name: "it should read the currency column mapped by profile",
+			content: "Posted At;Payee;Direction;Amount;State;Memo;Ccy\n" +
+				"1674507883;JOHN DOE;DEBIT;2500;SUCCESS;restaurant;JPY\n",
+			opts: Options{Profile: &profile.Profile{
+				Name:      "multi-currency",
+				Delimiter: ';',
+				Quote:     '"',
+				Columns: map[profile.Field]string{
+					profile.FieldTimestamp:    "Posted At",
+					profile.FieldCounterparty: "Payee",
+					profile.FieldType:         "Direction",
+					profile.FieldAmount:       "Amount",
+					profile.FieldStatus:       "State",
+					profile.FieldDescription:  "Memo",
+					profile.FieldCurrency:     "Ccy",
+				},
+			}},
+			want: [][]string{
+				{"1674507883", "JOHN DOE", "DEBIT", "2500", "SUCCESS", "restaurant"},
+			},
+			wantLines:      []int{2},
+			wantCurrencies: []string{"JPY"},
+		},
+		{
+			name: "it should derive the type from the sign of a locale formatted amount",
+			content: "Datum;Empfänger;Betrag;Status;Text\n" +
+				"1674507883;JOHN DOE;-1.234,56;SUCCESS;restaurant\n" +
+				"1674508123;ACME CORP;(250,00);SUCCESS;fee\n" +
+				"1674508456;JANE SMITH;15.000,5;SUCCESS;salary\n",
+			opts: Options{Currency: "EUR", Profile: &profile.Profile{
+				Name:      "signed",
+				Delimiter: ';',
+				Quote:     '"',
+				Columns: map[profile.Field]string{
+					profile.FieldTimestamp:    "Datum",
+					profile.FieldCounterparty: "Empfänger",
+					profile.FieldAmount:       "Betrag",
+					profile.FieldStatus:       "Status",
+					profile.FieldDescription:  "Text",
+				},
+				AmountLayout: profile.AmountLayoutSigned,
+				AmountFormat: profile.AmountFormat{DecimalSeparator: ',', ThousandsSeparator: '.', NegativeParentheses: true},
+			}},
+			want: [][]string{
+				{"1674507883", "JOHN DOE", "DEBIT", "123456", "SUCCESS", "restaurant"},
+				{"1674508123", "ACME CORP", "DEBIT", "25000", "SUCCESS", "fee"},
+				{"1674508456", "JANE SMITH", "CREDIT", "1500050", "SUCCESS", "salary"},
+			},
+			wantLines: []int{2, 3, 4},
+		},
+		{
+			name: "it should derive the type from the filled column when given debit and credit columns",
+			content: "Date,Payee,Debit,Credit,Status,Memo\n" +
+				"1674507883,JOHN DOE,\"2,500.00\",,SUCCESS,restaurant\n" +
+				"1674508123,ACME CORP,,15000.00,SUCCESS,salary\n",
+			opts: Options{Profile: &profile.Profile{
+				Name:      "debit-credit",
+				Delimiter: ',',
+				Quote:     '"',
+				Columns: map[profile.Field]string{
+					profile.FieldTimestamp:    "Date",
+					profile.FieldCounterparty: "Payee",
+					profile.FieldDebit:        "Debit",
+					profile.FieldCredit:       "Credit",
+					profile.FieldStatus:       "Status",
+					profile.FieldDescription:  "Memo",
+				},
+				AmountLayout: profile.AmountLayoutDebitCredit,
+				AmountFormat: profile.AmountFormat{DecimalSeparator: '.', ThousandsSeparator: ','},
+			}},
+			want: [][]string{
+				{"1674507883", "JOHN DOE", "DEBIT", "250000", "SUCCESS", "restaurant"},
+				{"1674508123", "ACME CORP", "CREDIT", "1500000", "SUCCESS", "salary"},
+			},
+			wantLines: []int{2, 3},
+		},
+		{
+			name: "it should return error when both debit and credit columns are filled",
+			content: "Date,Payee,Debit,Credit,Status,Memo\n" +
+				"1674507883,JOHN DOE,2500,2500,SUCCESS,restaurant\n",
+			opts: Options{Profile: &profile.Profile{
+				Name:      "debit-credit",
+				Delimiter: ',',
+				Quote:     '"',
+				Columns: map[profile.Field]string{
+					profile.FieldTimestamp:    "Date",
+					profile.FieldCounterparty: "Payee",
+					profile.FieldDebit:        "Debit",
+					profile.FieldCredit:       "Credit",
+					profile.FieldStatus:       "Status",
+					profile.FieldDescription:  "Memo",
+				},
+				AmountLayout: profile.AmountLayoutDebitCredit,
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "it should return error when header does not contain a mapped column",
+			content: "a\nb\nMemo;State;Amount;Direction;Payee\n",
+			opts:    Options{Profile: bankProfile},
+			wantErr: true,
+		},
+		{
+			name: "it should return error when given row with unterminated quoted field",
+			content: "timestamp,counterparty,type,amount,status,description\n" +
+				"1674507883,\"JOHN DOE,DEBIT,250000,SUCCESS,restaurant\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewCSVParser().NewReader(strings.NewReader(tt.content), tt.opts)
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("NewReader() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			var (
+				got           [][]string
+				gotLines      []int
+				gotCurrencies []string
+			)
+			for {
+				record, err := reader.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					if !tt.wantErr {
+						t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+					}
+					return
+				}
+				got = append(got, record.Fields)
+				gotLines = append(gotLines, record.Line)
+				gotCurrencies = append(gotCurrencies, record.Currency)
+			}
+
+			if tt.wantErr {
+				t.Errorf("Read() error = nil, wantErr %v", tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read() got = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(gotLines, tt.wantLines) {
+				t.Errorf("Read() lines = %v, want %v", gotLines, tt.wantLines)
+			}
+			if tt.wantCurrencies != nil && !reflect.DeepEqual(gotCurrencies, tt.wantCurrencies) {
+				t.Errorf("Read() currencies = %q, want %q", gotCurrencies, tt.wantCurrencies)
+			}
+		})
+	}
+}
+
+func Test_csvRecordReader_Metadata(t *testing.T) {
+	header := "timestamp,counterparty,type,amount,status,description\n"
+	rows := "1674507883,JOHN DOE,DEBIT,250000,SUCCESS,restaurant\n" +
+		"1674508123,ACME CORP,CREDIT,1500000,SUCCESS,salary\n"
+	int64Ptr := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		name        string
+		content     string
+		opts        Options
+		wantRows    int
+		wantDetails upload.StatementDetails
+		wantOpening *upload.Balance
+		wantClosing *upload.Balance
+		wantErr     string
+	}{
+		{
+			name: "it should read labelled rows above the header as the preamble",
+			content: "Account Number,1234567890\n" +
+				"IBAN,de89 3704 0044 0532 0130 00\n" +
+				"Statement Period,2024-01-01 - 2024-01-31\n" +
+				"Currency: usd\n" +
+				"Opening Balance,100000\n" +
+				header + rows,
+			opts:     Options{Currency: "IDR"},
+			wantRows: 2,
+			wantDetails: upload.StatementDetails{
+				AccountNumber: "1234567890",
+				IBAN:          "DE89370400440532013000",
+				PeriodStart:   time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+				PeriodEnd:     time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC),
+				Currency:      "USD",
+			},
+			wantOpening: &upload.Balance{Amount: 100000, Currency: "USD"},
+		},
+		{
+			name: "it should read labelled rows below the transactions as the trailer",
+			content: header + rows +
+				"Total Rows,2\n" +
+				"Total Credits,1500000\n" +
+				"Total Debits,250000\n" +
+				"Closing Balance,1350000\n",
+			opts:     Options{Currency: "IDR"},
+			wantRows: 2,
+			wantDetails: upload.StatementDetails{
+				RowCount:     int64Ptr(2),
+				TotalCredits: int64Ptr(1500000),
+				TotalDebits:  int64Ptr(250000),
+			},
+			wantClosing: &upload.Balance{Amount: 1350000, Currency: "IDR"},
+		},
+		{
+			name: "it should read amounts of the preamble and trailer in the number format of the profile",
+			content: "Bank export\n" +
+				"Account No.;ACC-1\n" +
+				"Posted At;Payee;Direction;Amount;State;Memo\n" +
+				"1674507883;JOHN DOE;DEBIT;2.500,00;SUCCESS;restaurant\n" +
+				"Total;-2.500,00\n",
+			opts: Options{Currency: "IDR", Profile: &profile.Profile{
+				Name:         "bank",
+				Delimiter:    ';',
+				Quote:        '"',
+				SkipRows:     2,
+				AmountFormat: profile.AmountFormat{DecimalSeparator: ',', ThousandsSeparator: '.'},
+				Columns: map[profile.Field]string{
+					profile.FieldTimestamp:    "Posted At",
+					profile.FieldCounterparty: "Payee",
+					profile.FieldType:         "Direction",
+					profile.FieldAmount:       "Amount",
+					profile.FieldStatus:       "State",
+					profile.FieldDescription:  "Memo",
+				},
+			}},
+			wantRows:    1,
+			wantDetails: upload.StatementDetails{AccountNumber: "ACC-1", NetTotal: int64Ptr(-250000)},
+		},
+		{
+			name:    "it should return error when the statement period is not two dates",
+			content: "Statement Period,January 2024\n" + header + rows,
+			wantErr: "line 1: invalid statement period 'January 2024'",
+		},
+		{
+			name:     "it should return a row error when a trailer amount cannot be read",
+			content:  header + rows + "Total Credits,lots\n",
+			wantRows: 2,
+			wantErr:  "line 4: invalid amount 'lots'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewCSVParser().NewReader(strings.NewReader(tt.content), tt.opts)
+			if err != nil {
+				if tt.wantErr == "" || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("NewReader() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			gotRows := 0
+			for {
+				_, err := reader.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					var rowErr *RowError
+					if tt.wantErr == "" || !errors.As(err, &rowErr) || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Errorf("Read() error = %v, want %v", err, tt.wantErr)
+					}
+					continue
+				}
+				gotRows++
+			}
+
+			if gotRows != tt.wantRows {
+				t.Errorf("Read() got %v rows, want %v", gotRows, tt.wantRows)
+			}
+			metadata := reader.(MetadataReader).Metadata()
+			if tt.wantErr != "" {
+				return
+			}
+			if !reflect.DeepEqual(metadata.Details, tt.wantDetails) {
+				t.Errorf("Metadata() details = %+v, want %+v", metadata.Details, tt.wantDetails)
+			}
+			if !reflect.DeepEqual(metadata.OpeningBalance, tt.wantOpening) {
+				t.Errorf("Metadata() opening balance = %+v, want %+v", metadata.OpeningBalance, tt.wantOpening)
+			}
+			if !reflect.DeepEqual(metadata.ClosingBalance, tt.wantClosing) {
+				t.Errorf("Metadata() closing balance = %+v, want %+v", metadata.ClosingBalance, tt.wantClosing)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// delimitedReader splits delimited text into records. Unlike encoding/csv it supports any quote
+// character and keeps the source line of each record. Quoted fields may span several lines and
+// a quote inside a quoted field is escaped by doubling it. Blank lines are skipped.
+type delimitedReader struct {
+	r         *bufio.Reader
+	delimiter rune
+	quote     rune
+	line      int
+}
+
+func newDelimitedReader(r io.Reader, delimiter, quote rune) *delimitedReader {
+	return &delimitedReader{
+		r:         bufio.NewReader(r),
+		delimiter: delimiter,
+		quote:     quote,
+	}
+}
+
+// readRecord returns the fields of the next record, the line it starts at and its source text.
+func (d *delimitedReader) readRecord() ([]string, int, string, error) {
+	var text string
+	for text == "" {
+		line, err := d.readLine()
+		if err != nil {
+			return nil, 0, "", err
+		}
+		text = line
+	}
+	startLine := d.line
+	raw := text
+
+	var (
+		fields   []string
+		field    strings.Builder
+		inQuotes bool
+		quoted   bool
+	)
+	for {
+		runes := []rune(text)
+		for i := 0; i < len(runes); i++ {
+			c := runes[i]
+			switch {
+			case inQuotes && c == d.quote:
+				if i+1 < len(runes) && runes[i+1] == d.quote {
+					field.WriteRune(c)
+					i++
+					continue
+				}
+				inQuotes = false
+				if i+1 < len(runes) && runes[i+1] != d.delimiter {
+					return nil, startLine, raw, &RowError{
+						Line: startLine,
+						Raw:  raw,
+						Err:  fmt.Errorf("extraneous %q in quoted field", d.quote),
+					}
+				}
+			case inQuotes:
+				field.WriteRune(c)
+			case c == d.delimiter:
+				fields = append(fields, field.String())
+				field.Reset()
+				quoted = false
+			case c == d.quote && field.Len() == 0 && !quoted:
+				inQuotes = true
+				quoted = true
+			default:
+				field.WriteRune(c)
+			}
+		}
+
+		if !inQuotes {
+			break
+		}
+
+		// the quoted field continues on the next line
+		next, err := d.readLine()
+		if err == io.EOF {
+			return nil, startLine, raw, fmt.Errorf("line %d: quoted field is not terminated", startLine)
+		}
+		if err != nil {
+			return nil, startLine, raw, err
+		}
+		field.WriteByte('\n')
+		text = next
+		raw += "\n" + next
+	}
+
+	fields = append(fields, field.String())
+	return fields, startLine, raw, nil
+}
+
+func (d *delimitedReader) readLine() (string, error) {
+	line, err := d.r.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return "", io.EOF
+	}
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	d.line++
+	return strings.TrimRight(line, "\r\n"), nil
+}
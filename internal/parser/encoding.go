@@ -0,0 +1,255 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is the character encoding of a statement file.
+type Encoding string
+
+const (
+	EncodingUTF8        Encoding = "utf-8"
+	EncodingUTF16LE     Encoding = "utf-16le"
+	EncodingUTF16BE     Encoding = "utf-16be"
+	EncodingWindows1252 Encoding = "windows-1252"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// DetectEncoding guesses the encoding of a file from its first bytes and reports whether it
+// starts with a byte order mark. Text that is not valid UTF-8 is taken as Windows-1252.
+func DetectEncoding(head []byte) (Encoding, bool) {
+	switch {
+	case bytes.HasPrefix(head, bomUTF8):
+		return EncodingUTF8, true
+	case bytes.HasPrefix(head, bomUTF16LE):
+		return EncodingUTF16LE, true
+	case bytes.HasPrefix(head, bomUTF16BE):
+		return EncodingUTF16BE, true
+	}
+
+	// without a byte order mark UTF-16 shows up as ASCII interleaved with zero bytes
+	var evenZeros, oddZeros int
+	for i, b := range head {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenZeros++
+		} else {
+			oddZeros++
+		}
+	}
+	units := len(head) / 2
+	switch {
+	case units > 0 && oddZeros*10 >= units*4 && evenZeros*10 < units:
+		return EncodingUTF16LE, false
+	case units > 0 && evenZeros*10 >= units*4 && oddZeros*10 < units:
+		return EncodingUTF16BE, false
+	}
+
+	if !utf8.Valid(trimPartialRune(head)) {
+		return EncodingWindows1252, false
+	}
+	return EncodingUTF8, false
+}
+
+// trimPartialRune drops a multi-byte character cut off at the end of b.
+func trimPartialRune(b []byte) []byte {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+// NewDecoder returns a reader of the UTF-8 text of r, which is in the given encoding.
+// A leading byte order mark is dropped.
+func NewDecoder(r io.Reader, encoding Encoding) io.Reader {
+	source := bufio.NewReader(r)
+	switch encoding {
+	case EncodingUTF16LE:
+		skipPrefix(source, bomUTF16LE)
+		return &runeDecoder{next: func() (rune, error) { return readUTF16(source, false) }}
+	case EncodingUTF16BE:
+		skipPrefix(source, bomUTF16BE)
+		return &runeDecoder{next: func() (rune, error) { return readUTF16(source, true) }}
+	case EncodingWindows1252:
+		return &runeDecoder{next: func() (rune, error) {
+			b, err := source.ReadByte()
+			if err != nil {
+				return 0, err
+			}
+			return windows1252Rune(b), nil
+		}}
+	default:
+		skipPrefix(source, bomUTF8)
+		return source
+	}
+}
+
+func skipPrefix(r *bufio.Reader, prefix []byte) {
+	if head, _ := r.Peek(len(prefix)); bytes.Equal(head, prefix) {
+		r.Discard(len(prefix))
+	}
+}
+
+// runeDecoder writes the characters returned by next as UTF-8.
+type runeDecoder struct {
+	next    func() (rune, error)
+	pending []byte
+	err     error
+}
+
+func (d *runeDecoder) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(d.pending) > 0 {
+			copied := copy(p[n:], d.pending)
+			d.pending = d.pending[copied:]
+			n += copied
+			continue
+		}
+		if d.err != nil {
+			break
+		}
+
+		c, err := d.next()
+		if err != nil {
+			d.err = err
+			continue
+		}
+		d.pending = utf8.AppendRune(d.pending[:0], c)
+	}
+
+	if n > 0 {
+		return n, nil
+	}
+	return 0, d.err
+}
+
+// readUTF16 reads one character of UTF-16 text, combining surrogate pairs.
+func readUTF16(r *bufio.Reader, bigEndian bool) (rune, error) {
+	unit := func() (rune, error) {
+		var b [2]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return utf8.RuneError, nil
+			}
+			return 0, err
+		}
+		if bigEndian {
+			return rune(b[0])<<8 | rune(b[1]), nil
+		}
+		return rune(b[1])<<8 | rune(b[0]), nil
+	}
+
+	first, err := unit()
+	if err != nil || !utf16.IsSurrogate(first) {
+		return first, err
+	}
+
+	second, err := unit()
+	if err != nil {
+		return utf8.RuneError, nil
+	}
+	return utf16.DecodeRune(first, second), nil
+}
+
+// windows1252 maps the bytes 0x80 to 0x9F, where Windows-1252 differs from Latin-1.
+// Unassigned bytes keep their Latin-1 code point.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+func windows1252Rune(b byte) rune {
+	if b >= 0x80 && b <= 0x9F {
+		return windows1252[b-0x80]
+	}
+	return rune(b)
+}
+
+// delimiterCandidates are the delimiters SniffDelimiter chooses from, in order of preference
+var delimiterCandidates = []rune{',', ';', '\t', '|'}
+
+// SniffDelimiter guesses the delimiter of UTF-8 delimited text from its first lines. It picks the
+// candidate found the same number of times on every line, falling back to the most frequent one
+// of the header and to a comma.
+func SniffDelimiter(head []byte, quote rune) rune {
+	lines := bytes.Split(head, []byte("\n"))
+	if len(lines) > 1 {
+		// the last line may be cut off
+		lines = lines[:len(lines)-1]
+	}
+
+	var sample [][]byte
+	for _, line := range lines {
+		if line = bytes.TrimRight(line, "\r"); len(bytes.TrimSpace(line)) > 0 {
+			sample = append(sample, line)
+		}
+		if len(sample) == 10 {
+			break
+		}
+	}
+	if len(sample) == 0 {
+		return ','
+	}
+
+	best, bestCount := rune(0), 0
+	consistent, consistentCount := rune(0), 0
+	for _, candidate := range delimiterCandidates {
+		count := countOutsideQuotes(sample[0], candidate, quote)
+		if count == 0 {
+			continue
+		}
+		if count > bestCount {
+			best, bestCount = candidate, count
+		}
+
+		same := true
+		for _, line := range sample[1:] {
+			if countOutsideQuotes(line, candidate, quote) != count {
+				same = false
+				break
+			}
+		}
+		if same && count > consistentCount {
+			consistent, consistentCount = candidate, count
+		}
+	}
+
+	switch {
+	case consistent != 0:
+		return consistent
+	case best != 0:
+		return best
+	default:
+		return ','
+	}
+}
+
+func countOutsideQuotes(line []byte, delimiter, quote rune) int {
+	count, inQuotes := 0, false
+	for _, c := range string(line) {
+		switch {
+		case c == quote:
+			inQuotes = !inQuotes
+		case c == delimiter && !inQuotes:
+			count++
+		}
+	}
+	return count
+}
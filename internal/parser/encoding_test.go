@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func encodeUTF16(s string, bigEndian bool) []byte {
+	var b []byte
+	for _, unit := range utf16.Encode([]rune(s)) {
+		if bigEndian {
+			b = append(b, byte(unit>>8), byte(unit))
+		} else {
+			b = append(b, byte(unit), byte(unit>>8))
+		}
+	}
+	return b
+}
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		head    []byte
+		want    Encoding
+		wantBOM bool
+	}{
+		{name: "it should detect plain UTF-8", head: []byte("timestamp,counterparty\n1,JOSÉ"), want: EncodingUTF8},
+		{name: "it should detect UTF-8 with BOM", head: append([]byte{0xEF, 0xBB, 0xBF}, "a,b"...), want: EncodingUTF8, wantBOM: true},
+		{name: "it should detect UTF-16LE with BOM", head: append([]byte{0xFF, 0xFE}, encodeUTF16("a;b", false)...), want: EncodingUTF16LE, wantBOM: true},
+		{name: "it should detect UTF-16BE with BOM", head: append([]byte{0xFE, 0xFF}, encodeUTF16("a;b", true)...), want: EncodingUTF16BE, wantBOM: true},
+		{name: "it should detect UTF-16LE without BOM", head: encodeUTF16("timestamp;counterparty", false), want: EncodingUTF16LE},
+		{name: "it should detect Windows-1252 when text is not valid UTF-8", head: []byte("1,JOS\xc9 M\xdcLLER,\x80"), want: EncodingWindows1252},
+		{name: "it should ignore a character cut off at the end", head: []byte("1,JOS\xc3"), want: EncodingUTF8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotBOM := DetectEncoding(tt.head)
+			if got != tt.want || gotBOM != tt.wantBOM {
+				t.Errorf("DetectEncoding() got = %v, %v, want %v, %v", got, gotBOM, tt.want, tt.wantBOM)
+			}
+		})
+	}
+}
+
+func TestNewDecoder(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		encoding Encoding
+		want     string
+	}{
+		{name: "it should drop the UTF-8 BOM", content: append([]byte{0xEF, 0xBB, 0xBF}, "a,b\n"...), encoding: EncodingUTF8, want: "a,b\n"},
+		{name: "it should transcode UTF-16LE and drop the BOM", content: append([]byte{0xFF, 0xFE}, encodeUTF16("Müller;€ 5\r\n😀", false)...), encoding: EncodingUTF16LE, want: "Müller;€ 5\r\n😀"},
+		{name: "it should transcode UTF-16BE", content: encodeUTF16("Zoë", true), encoding: EncodingUTF16BE, want: "Zoë"},
+		{name: "it should transcode Windows-1252", content: []byte("JOS\xc9 \x80 5 \x93x\x94"), encoding: EncodingWindows1252, want: "JOSÉ € 5 “x”"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := io.ReadAll(NewDecoder(strings.NewReader(string(tt.content)), tt.encoding))
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("NewDecoder() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSniffDelimiter(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want rune
+	}{
+		{name: "it should detect commas", head: "a,b,c\n1,2,3\n", want: ','},
+		{name: "it should detect semicolons with decimal commas", head: "a;b;c\n1;2,50;3\n4;5,00;6\n", want: ';'},
+		{name: "it should detect tabs", head: "a\tb\tc\n1\t2\t3\n", want: '\t'},
+		{name: "it should detect pipes", head: "a|b|c\n1|2|3\n", want: '|'},
+		{name: "it should ignore delimiters inside quotes", head: "a;b\n\"x, y, z\";2\n", want: ';'},
+		{name: "it should fall back to the most frequent delimiter of the header", head: "a;b;c\n1;2\n", want: ';'},
+		{name: "it should fall back to comma", head: "single column\n", want: ','},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SniffDelimiter([]byte(tt.head), '"'); got != tt.want {
+				t.Errorf("SniffDelimiter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+)
+
+type jsonParser struct{}
+
+// NewJSONParser reads transactions shaped like the ones the API returns, either as a JSON array or
+// as newline-delimited JSON with one transaction per line.
+func NewJSONParser() StatementParser {
+	return &jsonParser{}
+}
+
+func (p *jsonParser) Format() Format {
+	return FormatJSON
+}
+
+func (p *jsonParser) Extensions() []string {
+	return []string{".json", ".ndjson", ".jsonl"}
+}
+
+// Sniff looks for an array or an object as the first value.
+func (p *jsonParser) Sniff(head []byte) bool {
+	for _, b := range head {
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '[', '{':
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+func (p *jsonParser) NewReader(r io.Reader, opts Options) (Reader, error) {
+	source := bufio.NewReader(r)
+
+	// a leading '[' makes the input an array, anything else is read as a stream of objects
+	array := false
+	for {
+		b, err := source.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read transactions: %w", err)
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
+		}
+		source.UnreadByte()
+		array = b == '['
+		break
+	}
+
+	decoder := json.NewDecoder(source)
+	if array {
+		if _, err := decoder.Token(); err != nil {
+			return nil, fmt.Errorf("failed to read transactions: %w", err)
+		}
+	}
+	return &jsonReader{decoder: decoder, array: array}, nil
+}
+
+// jsonTransaction is a transaction as the API returns it. Fields left out stay empty and are
+// reported by validation.
+type jsonTransaction struct {
+	Timestamp    *int64 `json:"timestamp"`
+	Counterparty string `json:"counterparty"`
+	Type         string `json:"type"`
+	Amount       *int64 `json:"amount"`
+	Currency     string `json:"currency"`
+	Status       string `json:"status"`
+	Description  string `json:"description"`
+	Remittance   *struct {
+		EndToEndID        string   `json:"end_to_end_id"`
+		CreditorReference string   `json:"creditor_reference"`
+		DocumentNumbers   []string `json:"document_numbers"`
+		Unstructured      []string `json:"unstructured"`
+	} `json:"remittance"`
+}
+
+// jsonReader decodes one transaction at a time. Line is the position of a transaction in the
+// array or stream, starting at 1.
+type jsonReader struct {
+	decoder *json.Decoder
+	array   bool
+	index   int
+	done    bool
+}
+
+func (r *jsonReader) Read() (*Record, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+
+	if r.array && !r.decoder.More() {
+		r.done = true
+		if token, err := r.decoder.Token(); err != nil || token != json.Delim(']') {
+			return nil, fmt.Errorf("invalid JSON after transaction %d: unterminated array", r.index)
+		}
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := r.decoder.Decode(&raw); err != nil {
+		r.done = true
+		if err == io.EOF && !r.array {
+			return nil, io.EOF
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("invalid JSON after transaction %d: %w", r.index, err)
+	}
+	r.index++
+
+	if len(raw) == 0 || raw[0] != '{' {
+		return nil, &RowError{Line: r.index, Raw: string(raw), Err: errors.New("expected a transaction object")}
+	}
+	var t jsonTransaction
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return nil, &RowError{Line: r.index, Raw: string(raw), Err: fmt.Errorf("invalid transaction: %w", err)}
+	}
+
+	fields := make([]string, ColumnCount)
+	if t.Timestamp != nil {
+		fields[ColumnTimestamp] = strconv.FormatInt(*t.Timestamp, 10)
+	}
+	if t.Amount != nil {
+		fields[ColumnAmount] = strconv.FormatInt(*t.Amount, 10)
+	}
+	fields[ColumnCounterparty] = t.Counterparty
+	fields[ColumnType] = t.Type
+	fields[ColumnStatus] = t.Status
+	fields[ColumnDescription] = t.Description
+
+	record := &Record{
+		Line:     r.index,
+		Raw:      string(raw),
+		Fields:   fields,
+		Currency: t.Currency,
+	}
+	if t.Remittance != nil {
+		remittance := &transaction.Remittance{
+			EndToEndID:        t.Remittance.EndToEndID,
+			CreditorReference: t.Remittance.CreditorReference,
+			DocumentNumbers:   t.Remittance.DocumentNumbers,
+			Unstructured:      t.Remittance.Unstructured,
+		}
+		if !remittance.IsEmpty() {
+			record.Remittance = remittance
+		}
+	}
+
+	return record, nil
+}
@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+)
+
+func Test_jsonReader_Read(t *testing.T) {
+	tests := []struct {
+		name           string
+		content        string
+		want           [][]string
+		wantLines      []int
+		wantCurrencies []string
+		wantRowErrors  []int
+		wantErr        bool
+	}{
+		{
+			name: "it should read a JSON array of transactions",
+			content: `[
+  {"id": "ignored", "timestamp": 1674507883, "counterparty": "JOHN DOE", "type": "DEBIT", "amount": 250000, "currency": "IDR", "status": "SUCCESS", "description": "restaurant"},
+  {"timestamp": 1674508123, "counterparty": "ACME CORP", "type": "CREDIT", "amount": 1500000, "status": "SUCCESS", "description": "salary"}
+]`,
+			want: [][]string{
+				{"1674507883", "JOHN DOE", "DEBIT", "250000", "SUCCESS", "restaurant"},
+				{"1674508123", "ACME CORP", "CREDIT", "1500000", "SUCCESS", "salary"},
+			},
+			wantLines:      []int{1, 2},
+			wantCurrencies: []string{"IDR", ""},
+		},
+		{
+			name: "it should read newline-delimited JSON and leave missing fields empty",
+			content: `{"timestamp": 1674507883, "counterparty": "JOHN DOE", "type": "DEBIT", "amount": 250000, "status": "SUCCESS", "description": "restaurant"}` + "\n\n" +
+				`{"counterparty": "ACME CORP", "type": "CREDIT", "status": "SUCCESS"}` + "\n",
+			want: [][]string{
+				{"1674507883", "JOHN DOE", "DEBIT", "250000", "SUCCESS", "restaurant"},
+				{"", "ACME CORP", "CREDIT", "", "SUCCESS", ""},
+			},
+			wantLines: []int{1, 2},
+		},
+		{
+			name:    "it should read an empty array",
+			content: " [ ] ",
+		},
+		{
+			name: "it should report transactions with wrongly typed fields and continue",
+			content: `[{"timestamp": "yesterday", "counterparty": "JOHN DOE"}, 42,` +
+				`{"timestamp": 1674508123, "counterparty": "ACME CORP", "type": "CREDIT", "amount": 1500000, "status": "SUCCESS", "description": "salary"}]`,
+			want: [][]string{
+				{"1674508123", "ACME CORP", "CREDIT", "1500000", "SUCCESS", "salary"},
+			},
+			wantLines:     []int{3},
+			wantRowErrors: []int{1, 2},
+		},
+		{
+			name:    "it should return error when given malformed JSON",
+			content: `[{"timestamp": 1674507883, "counterparty": "JOHN DOE"`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewJSONParser().NewReader(strings.NewReader(tt.content), Options{})
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+
+			var (
+				got           [][]string
+				gotLines      []int
+				gotCurrencies []string
+				gotRowErrors  []int
+			)
+			for {
+				record, err := reader.Read()
+				if err == io.EOF {
+					break
+				}
+				var rowErr *RowError
+				if errors.As(err, &rowErr) {
+					gotRowErrors = append(gotRowErrors, rowErr.Line)
+					continue
+				}
+				if err != nil {
+					if !tt.wantErr {
+						t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+					}
+					return
+				}
+				got = append(got, record.Fields)
+				gotLines = append(gotLines, record.Line)
+				gotCurrencies = append(gotCurrencies, record.Currency)
+			}
+
+			if tt.wantErr {
+				t.Errorf("Read() error = nil, wantErr %v", tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read() got = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(gotLines, tt.wantLines) {
+				t.Errorf("Read() lines = %v, want %v", gotLines, tt.wantLines)
+			}
+			if tt.wantCurrencies != nil && !reflect.DeepEqual(gotCurrencies, tt.wantCurrencies) {
+				t.Errorf("Read() currencies = %q, want %q", gotCurrencies, tt.wantCurrencies)
+			}
+			if !reflect.DeepEqual(gotRowErrors, tt.wantRowErrors) {
+				t.Errorf("Read() row errors at = %v, want %v", gotRowErrors, tt.wantRowErrors)
+			}
+		})
+	}
+}
+
+func Test_jsonReader_Read_Remittance(t *testing.T) {
+	content := `{"timestamp": 1674507883, "counterparty": "JOHN DOE", "type": "DEBIT", "amount": 250000, "status": "SUCCESS", "description": "invoice",` +
+		`"remittance": {"end_to_end_id": "E2E-1", "document_numbers": ["INV-7"]}}`
+
+	reader, err := NewJSONParser().NewReader(strings.NewReader(content), Options{})
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	record, err := reader.Read()
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	want := &transaction.Remittance{EndToEndID: "E2E-1", DocumentNumbers: []string{"INV-7"}}
+	if !reflect.DeepEqual(record.Remittance, want) {
+		t.Errorf("Read() remittance = %+v, want %+v", record.Remittance, want)
+	}
+}
@@ -0,0 +1,292 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+)
+
+// SWIFT MT940 customer statement. Each :61: statement line becomes a record,
+// enriched with the information to account owner from the :86: field following it.
+type mt940Parser struct{}
+
+func NewMT940Parser() StatementParser {
+	return &mt940Parser{}
+}
+
+func (p *mt940Parser) Format() Format {
+	return FormatMT940
+}
+
+func (p *mt940Parser) Extensions() []string {
+	return []string{".sta", ".mt940", ".940"}
+}
+
+func (p *mt940Parser) Sniff(head []byte) bool {
+	return (bytes.HasPrefix(head, []byte(":20:")) || bytes.Contains(head, []byte("\n:20:")) || bytes.Contains(head, []byte("{4:"))) &&
+		bytes.Contains(head, []byte(":25:"))
+}
+
+func (p *mt940Parser) NewReader(r io.Reader, opts Options) (Reader, error) {
+	return &mt940Reader{
+		scanner:         bufio.NewScanner(r),
+		defaultCurrency: opts.Currency,
+		location:        locationOrUTC(opts.Location),
+	}, nil
+}
+
+var (
+	// value date, optional entry date, debit/credit mark, optional funds code, amount, transaction type, references
+	mt940StatementLine = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+,\d*)([NFS][A-Z0-9]{3})([^/]*)(//.*)?`)
+	// debit/credit mark, date, currency, amount
+	mt940Balance = regexp.MustCompile(`^([CD])(\d{6})([A-Z]{3})(\d+,\d*)`)
+)
+
+type mt940Field struct {
+	tag   string
+	value string
+	line  int
+}
+
+type mt940Reader struct {
+	scanner  *bufio.Scanner
+	line     int
+	current  *mt940Field
+	pending  *mt940Field
+	metadata Metadata
+	// statement lines are in the currency of the opening balance
+	defaultCurrency money.Currency
+	// location of value dates
+	location *time.Location
+}
+
+func (r *mt940Reader) Metadata() Metadata {
+	return r.metadata
+}
+
+func (r *mt940Reader) Read() (*Record, error) {
+	for {
+		field, err := r.nextField()
+		if err == io.EOF && r.pending != nil {
+			statementLine := r.pending
+			r.pending = nil
+			return r.toRecord(statementLine, nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch field.tag {
+		case "61":
+			if r.pending != nil {
+				statementLine := r.pending
+				r.pending = field
+				return r.toRecord(statementLine, nil)
+			}
+			r.pending = field
+		case "86":
+			if r.pending != nil {
+				statementLine := r.pending
+				r.pending = nil
+				return r.toRecord(statementLine, field)
+			}
+		default:
+			if err := r.applyStatementField(field); err != nil {
+				return nil, err
+			}
+			if r.pending != nil {
+				statementLine := r.pending
+				r.pending = nil
+				return r.toRecord(statementLine, nil)
+			}
+		}
+	}
+}
+
+// nextField returns the next complete tag, joining continuation lines to the tag they belong to.
+func (r *mt940Reader) nextField() (*mt940Field, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := strings.TrimRight(r.scanner.Text(), "\r")
+
+		// SWIFT block headers and the block 4 terminator carry no statement data
+		if strings.HasPrefix(line, "{") {
+			if i := strings.Index(line, "{4:"); i >= 0 {
+				line = line[i+3:]
+			} else {
+				continue
+			}
+		}
+		if line == "-" || line == "-}" || strings.HasPrefix(line, "-}") {
+			line = ""
+		}
+
+		if strings.HasPrefix(line, ":") {
+			if tag, value, found := strings.Cut(line[1:], ":"); found {
+				field := r.current
+				r.current = &mt940Field{tag: tag, value: value, line: r.line}
+				if field != nil {
+					return field, nil
+				}
+				continue
+			}
+		}
+
+		if r.current != nil && line != "" {
+			r.current.value += "\n" + line
+		}
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if r.current != nil {
+		field := r.current
+		r.current = nil
+		return field, nil
+	}
+	return nil, io.EOF
+}
+
+func (r *mt940Reader) applyStatementField(field *mt940Field) error {
+	switch field.tag {
+	case "60F", "60M":
+		if r.metadata.OpeningBalance != nil {
+			return nil
+		}
+		balance, err := parseMT940Balance(field.value)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid opening balance: %w", field.line, err)
+		}
+		r.metadata.OpeningBalance = balance
+	case "62F", "62M":
+		balance, err := parseMT940Balance(field.value)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid closing balance: %w", field.line, err)
+		}
+		r.metadata.ClosingBalance = balance
+	}
+	return nil
+}
+
+func (r *mt940Reader) toRecord(statementLine, information *mt940Field) (*Record, error) {
+	raw := ":61:" + statementLine.value
+	if information != nil {
+		raw += "\n:86:" + information.value
+	}
+
+	firstLine, supplementary, _ := strings.Cut(statementLine.value, "\n")
+	m := mt940StatementLine.FindStringSubmatch(firstLine)
+	if m == nil {
+		return nil, &RowError{Line: statementLine.line, Raw: raw, Err: errors.New("invalid :61: statement line")}
+	}
+
+	valueDate, err := time.ParseInLocation("060102", m[1], r.location)
+	if err != nil {
+		return nil, &RowError{Line: statementLine.line, Raw: raw, Err: fmt.Errorf("invalid value date '%s': %w", m[1], err)}
+	}
+
+	var currency string
+	if r.metadata.OpeningBalance != nil {
+		currency = string(r.metadata.OpeningBalance.Currency)
+	}
+	amount, _, err := parseDecimalAmount(strings.Replace(m[5], ",", ".", 1), currencyScale(currency, r.defaultCurrency))
+	if err != nil {
+		return nil, &RowError{Line: statementLine.line, Raw: raw, Err: fmt.Errorf("invalid amount '%s': %w", m[5], err)}
+	}
+
+	// a reversal of a credit takes money out of the account, a reversal of a debit puts it back
+	transactionType := transaction.TypeCredit
+	if m[3] == "D" || m[3] == "RC" {
+		transactionType = transaction.TypeDebit
+	}
+
+	transactionCode := m[6]
+	reference := strings.TrimSpace(m[7])
+	if reference == "NONREF" {
+		reference = ""
+	}
+
+	var counterparty, description string
+	if information != nil {
+		counterparty, description = parseMT940Information(information.value)
+	}
+	counterparty = firstNonEmpty(counterparty, reference, transactionCode)
+	description = firstNonEmpty(description, strings.TrimSpace(supplementary), transactionCode)
+
+	return &Record{
+		Line: statementLine.line,
+		Raw:  raw,
+		Fields: []string{
+			strconv.FormatInt(valueDate.Unix(), 10),
+			counterparty,
+			string(transactionType),
+			strconv.FormatInt(amount, 10),
+			string(transaction.StatusSuccess),
+			description,
+		},
+		Currency: currency,
+	}, nil
+}
+
+// parseMT940Information reads the :86: field. Structured content uses ?NN subfields,
+// where ?20-?29 hold the remittance text and ?32-?33 the counterparty name.
+func parseMT940Information(value string) (string, string) {
+	value = strings.ReplaceAll(value, "\n", "")
+	if !strings.Contains(value, "?") {
+		return "", strings.TrimSpace(value)
+	}
+
+	var counterparty, description strings.Builder
+	for _, subfield := range strings.Split(value, "?")[1:] {
+		if len(subfield) < 2 {
+			continue
+		}
+		code, content := subfield[:2], subfield[2:]
+		switch {
+		case code >= "20" && code <= "29":
+			description.WriteString(content)
+		case code == "32" || code == "33":
+			counterparty.WriteString(content)
+		}
+	}
+
+	return strings.TrimSpace(counterparty.String()), strings.TrimSpace(description.String())
+}
+
+func parseMT940Balance(value string) (*upload.Balance, error) {
+	m := mt940Balance.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return nil, errors.New("unexpected format")
+	}
+
+	date, err := time.Parse("060102", m[2])
+	if err != nil {
+		return nil, err
+	}
+
+	amount, _, err := parseDecimalAmount(strings.Replace(m[4], ",", ".", 1), currencyScale(m[3], ""))
+	if err != nil {
+		return nil, err
+	}
+	if m[1] == "D" {
+		amount = -amount
+	}
+
+	return &upload.Balance{
+		Amount:   amount,
+		Currency: money.Currency(m[3]),
+		Date:     date,
+	}, nil
+}
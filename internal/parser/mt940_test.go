@@ -0,0 +1,107 @@
+package parser
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+)
+
+const mt940Statement = `{1:F01BANKDEFFAXXX0000000000}{2:I940BANKDEFFXXXXN}{4:
+:20:STARTUMS
+:25:10020030/1234567
+:28C:00001/001
+:60F:C230101EUR1000,00
+:61:2301230123D2500,00NTRFNONREF//B4A23
+:86:?00SEPA TRANSFER?20restaurant?32JOHN DOE
+:61:2301240124C15000,NTRFINV-42
+:86:salary payment
+:61:230125RC10,5NCHGNONREF
+:62F:C230131EUR13490,00
+-}`
+
+func Test_mt940Reader_Read(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		want        [][]string
+		wantLines   []int
+		wantOpening *upload.Balance
+		wantClosing *upload.Balance
+		wantErr     bool
+	}{
+		{
+			name:    "it should return records and balances as expected when given MT940 statement",
+			content: mt940Statement,
+			want: [][]string{
+				{"1674432000", "JOHN DOE", "DEBIT", "250000", "SUCCESS", "restaurant"},
+				{"1674518400", "INV-42", "CREDIT", "1500000", "SUCCESS", "salary payment"},
+				{"1674604800", "NCHG", "DEBIT", "1050", "SUCCESS", "NCHG"},
+			},
+			wantLines: []int{6, 8, 10},
+			wantOpening: &upload.Balance{
+				Amount:   100000,
+				Currency: "EUR",
+				Date:     time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			wantClosing: &upload.Balance{
+				Amount:   1349000,
+				Currency: "EUR",
+				Date:     time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:    "it should return error when given malformed statement line",
+			content: ":20:X\n:25:1/2\n:60F:C230101EUR0,\n:61:NOT A STATEMENT LINE\n:62F:C230131EUR0,\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewMT940Parser().NewReader(strings.NewReader(tt.content), Options{})
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+
+			var (
+				got      [][]string
+				gotLines []int
+			)
+			for {
+				record, err := reader.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					if !tt.wantErr {
+						t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+					}
+					return
+				}
+				got = append(got, record.Fields)
+				gotLines = append(gotLines, record.Line)
+			}
+
+			if tt.wantErr {
+				t.Errorf("Read() error = nil, wantErr %v", tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(gotLines, tt.wantLines) {
+				t.Errorf("Read() lines = %v, want %v", gotLines, tt.wantLines)
+			}
+
+			metadata := reader.(MetadataReader).Metadata()
+			if !reflect.DeepEqual(metadata.OpeningBalance, tt.wantOpening) {
+				t.Errorf("Metadata() opening balance = %v, want %v", metadata.OpeningBalance, tt.wantOpening)
+			}
+			if !reflect.DeepEqual(metadata.ClosingBalance, tt.wantClosing) {
+				t.Errorf("Metadata() closing balance = %v, want %v", metadata.ClosingBalance, tt.wantClosing)
+			}
+		})
+	}
+}
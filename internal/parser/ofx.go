@@ -0,0 +1,246 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+)
+
+// ofxParser reads both OFX 1.x (SGML, leaf elements without closing tags) and
+// OFX 2.x (XML) files. QFX is OFX with Intuit specific extensions, which are ignored.
+type ofxParser struct{}
+
+func NewOFXParser() StatementParser {
+	return &ofxParser{}
+}
+
+func (p *ofxParser) Format() Format {
+	return FormatOFX
+}
+
+func (p *ofxParser) Extensions() []string {
+	return []string{".ofx", ".qfx"}
+}
+
+func (p *ofxParser) Sniff(head []byte) bool {
+	upper := bytes.ToUpper(head)
+	return bytes.Contains(upper, []byte("OFXHEADER")) || bytes.Contains(upper, []byte("<OFX>"))
+}
+
+func (p *ofxParser) NewReader(r io.Reader, opts Options) (Reader, error) {
+	return &ofxReader{
+		tokenizer:       newSGMLTokenizer(r),
+		defaultCurrency: opts.Currency,
+	}, nil
+}
+
+type ofxReader struct {
+	tokenizer       *sgmlTokenizer
+	entries         int
+	defaultCurrency money.Currency
+	// currency is the CURDEF of the statement being read
+	currency string
+}
+
+func (r *ofxReader) Read() (*Record, error) {
+	var (
+		inTransaction bool
+		fields        map[string]string
+	)
+
+	for {
+		tok, err := r.tokenizer.next()
+		if err == io.EOF && inTransaction {
+			return nil, errors.New("unexpected end of file inside STMTTRN")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case tok.name == "STMTTRN" && !tok.closing:
+			inTransaction = true
+			fields = make(map[string]string)
+		case tok.name == "STMTTRN" && tok.closing:
+			if !inTransaction {
+				continue
+			}
+			r.entries++
+			return r.toRecord(fields)
+		case tok.name == "CURDEF" && !tok.closing && !inTransaction:
+			r.currency = tok.text
+		case inTransaction && !tok.closing && tok.text != "":
+			// PAYEE is an aggregate with its own NAME, first one wins
+			if _, exists := fields[tok.name]; !exists {
+				fields[tok.name] = tok.text
+			}
+		}
+	}
+}
+
+func (r *ofxReader) toRecord(fields map[string]string) (*Record, error) {
+	posted, err := parseOFXDate(fields["DTPOSTED"])
+	if err != nil {
+		return nil, &RowError{Line: r.entries, Err: fmt.Errorf("invalid DTPOSTED '%s': %w", fields["DTPOSTED"], err)}
+	}
+
+	// OFX allows a comma as decimal separator
+	rawAmount := strings.ReplaceAll(fields["TRNAMT"], ",", ".")
+	amount, negative, err := parseDecimalAmount(rawAmount, currencyScale(r.currency, r.defaultCurrency))
+	if err != nil {
+		return nil, &RowError{Line: r.entries, Err: fmt.Errorf("invalid TRNAMT '%s': %w", rawAmount, err)}
+	}
+
+	transactionType := transaction.TypeCredit
+	if negative {
+		transactionType = transaction.TypeDebit
+	}
+
+	counterparty := firstNonEmpty(fields["NAME"], fields["PAYEEID"], fields["TRNTYPE"])
+	description := firstNonEmpty(fields["MEMO"], fields["NAME"], fields["TRNTYPE"])
+
+	return &Record{
+		Line: r.entries,
+		Fields: []string{
+			strconv.FormatInt(posted.Unix(), 10),
+			counterparty,
+			string(transactionType),
+			strconv.FormatInt(amount, 10),
+			string(transaction.StatusSuccess),
+			description,
+		},
+		Currency: r.currency,
+	}, nil
+}
+
+// parseOFXDate parses the OFX datetime format YYYYMMDD[HHMMSS[.XXX]][[offset:TZ]].
+// Dates without an offset are in GMT.
+func parseOFXDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	location := time.UTC
+	if i := strings.IndexByte(value, '['); i >= 0 {
+		zone := strings.TrimSuffix(value[i+1:], "]")
+		value = value[:i]
+
+		offsetStr, _, _ := strings.Cut(zone, ":")
+		offset, err := strconv.ParseFloat(offsetStr, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time zone offset '%s'", zone)
+		}
+		location = time.FixedZone(zone, int(offset*3600))
+	}
+
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		value = value[:i]
+	}
+
+	var layout string
+	switch len(value) {
+	case 8:
+		layout = "20060102"
+	case 12:
+		layout = "200601021504"
+	case 14:
+		layout = "20060102150405"
+	default:
+		return time.Time{}, errors.New("unexpected length")
+	}
+
+	return time.ParseInLocation(layout, value, location)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+type sgmlToken struct {
+	name    string
+	closing bool
+	text    string
+}
+
+// sgmlTokenizer walks OFX markup tag by tag. The text following an opening tag is attached to
+// that tag, which covers both the SGML leaf syntax (<TRNAMT>-5.00) and the XML one (<TRNAMT>-5.00</TRNAMT>).
+type sgmlTokenizer struct {
+	r *bufio.Reader
+}
+
+var xmlEntities = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&amp;", "&")
+
+func newSGMLTokenizer(r io.Reader) *sgmlTokenizer {
+	return &sgmlTokenizer{r: bufio.NewReader(r)}
+}
+
+func (t *sgmlTokenizer) next() (sgmlToken, error) {
+	for {
+		// everything before the first tag (the OFX 1.x header block) and between tags is skipped
+		if _, err := t.r.ReadString('<'); err != nil {
+			return sgmlToken{}, err
+		}
+
+		tag, err := t.r.ReadString('>')
+		if err != nil {
+			return sgmlToken{}, err
+		}
+		tag = strings.TrimSuffix(tag, ">")
+
+		// processing instructions, comments and declarations
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+
+		tok := sgmlToken{}
+		if strings.HasPrefix(tag, "/") {
+			tok.closing = true
+			tag = tag[1:]
+		}
+		tag = strings.TrimSuffix(tag, "/")
+		name, _, _ := strings.Cut(strings.TrimSpace(tag), " ")
+		tok.name = strings.ToUpper(name)
+
+		if !tok.closing {
+			text, err := t.readText()
+			if err != nil {
+				return sgmlToken{}, err
+			}
+			tok.text = text
+		}
+
+		return tok, nil
+	}
+}
+
+func (t *sgmlTokenizer) readText() (string, error) {
+	var sb strings.Builder
+	for {
+		b, err := t.r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if b == '<' {
+			if err := t.r.UnreadByte(); err != nil {
+				return "", err
+			}
+			break
+		}
+		sb.WriteByte(b)
+	}
+
+	return xmlEntities.Replace(strings.TrimSpace(sb.String())), nil
+}
@@ -0,0 +1,150 @@
+package parser
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const ofxSGMLStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:USASCII
+
+<OFX>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<STMTRS>
+<CURDEF>USD
+<BANKTRANLIST>
+<DTSTART>20230101
+<DTEND>20230131
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20230123210443.000[-5:EST]
+<TRNAMT>-2500.00
+<FITID>1001
+<NAME>JOHN DOE
+<MEMO>restaurant
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20230123
+<TRNAMT>15000
+<FITID>1002
+<NAME>ACME CORP &amp; CO
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+
+const ofxXMLStatement = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <BANKMSGSRSV1>
+    <STMTTRNRS>
+      <STMTRS>
+        <BANKTRANLIST>
+          <STMTTRN>
+            <TRNTYPE>POS</TRNTYPE>
+            <DTPOSTED>20230123120000</DTPOSTED>
+            <TRNAMT>-75,5</TRNAMT>
+            <FITID>2001</FITID>
+            <PAYEE>
+              <NAME>JANE SMITH</NAME>
+            </PAYEE>
+            <MEMO>gift</MEMO>
+          </STMTTRN>
+        </BANKTRANLIST>
+      </STMTRS>
+    </STMTTRNRS>
+  </BANKMSGSRSV1>
+</OFX>
+`
+
+func Test_ofxReader_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name:    "it should return records as expected when given OFX 1.x SGML statement",
+			content: ofxSGMLStatement,
+			want: [][]string{
+				{"1674525883", "JOHN DOE", "DEBIT", "250000", "SUCCESS", "restaurant"},
+				{"1674432000", "ACME CORP & CO", "CREDIT", "1500000", "SUCCESS", "ACME CORP & CO"},
+			},
+		},
+		{
+			name:    "it should return records as expected when given OFX 2.x XML statement",
+			content: ofxXMLStatement,
+			want: [][]string{
+				{"1674475200", "JANE SMITH", "DEBIT", "7550", "SUCCESS", "gift"},
+			},
+		},
+		{
+			name:    "it should scale amounts by the minor unit of CURDEF",
+			content: "<OFX><CURDEF>JPY<BANKTRANLIST><STMTTRN><DTPOSTED>20230123<TRNAMT>-1500<NAME>SHOP</STMTTRN></BANKTRANLIST></OFX>",
+			want: [][]string{
+				{"1674432000", "SHOP", "DEBIT", "1500", "SUCCESS", "SHOP"},
+			},
+		},
+		{
+			name:    "it should return error when given transaction with invalid amount",
+			content: "<OFX><STMTTRN><DTPOSTED>20230123<TRNAMT>abc</STMTTRN></OFX>",
+			wantErr: true,
+		},
+		{
+			name:    "it should return error when given transaction with two minus signs",
+			content: "<OFX><STMTTRN><DTPOSTED>20230123<TRNAMT>--5</STMTTRN></OFX>",
+			wantErr: true,
+		},
+		{
+			name:    "it should return error when given transaction with a plus and a minus sign",
+			content: "<OFX><STMTTRN><DTPOSTED>20230123<TRNAMT>+-5</STMTTRN></OFX>",
+			wantErr: true,
+		},
+		{
+			name:    "it should return error when given truncated transaction",
+			content: "<OFX><STMTTRN><DTPOSTED>20230123<TRNAMT>10.00",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewOFXParser().NewReader(strings.NewReader(tt.content), Options{})
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+
+			var got [][]string
+			for {
+				record, err := reader.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					if !tt.wantErr {
+						t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+					}
+					return
+				}
+				got = append(got, record.Fields)
+			}
+
+			if tt.wantErr {
+				t.Errorf("Read() error = nil, wantErr %v", tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/profile"
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+)
+
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatOFX   Format = "ofx"
+	FormatMT940 Format = "mt940"
+	FormatCAMT  Format = "camt"
+	FormatXLSX  Format = "xlsx"
+	FormatJSON  Format = "json"
+)
+
+// canonical column order of Record.Fields, matches profile.Fields
+const (
+	ColumnTimestamp = iota
+	ColumnCounterparty
+	ColumnType
+	ColumnAmount
+	ColumnStatus
+	ColumnDescription
+
+	ColumnCount
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported statement format")
+
+// Record is a single statement entry with its fields laid out in the canonical column order.
+// Line is the position of the entry in the source file and Raw its source text, when the format has one.
+// Currency is the currency code stated by the source, empty when it has none.
+type Record struct {
+	Line       int
+	Raw        string
+	Fields     []string
+	Currency   string
+	Remittance *transaction.Remittance
+}
+
+// RowError reports an entry that could not be read. The reader stays usable and
+// the next call to Read continues with the following entry.
+type RowError struct {
+	Line int
+	Raw  string
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Reader streams records out of a statement file, returning io.EOF when there are no more.
+// Errors other than *RowError are not recoverable.
+type Reader interface {
+	Read() (*Record, error)
+}
+
+// Metadata is statement level information found alongside the transactions.
+type Metadata struct {
+	OpeningBalance *upload.Balance
+	ClosingBalance *upload.Balance
+	// Details is what a table states about itself in labelled rows around its transactions
+	Details upload.StatementDetails
+}
+
+// MetadataReader is implemented by readers of formats that carry statement level information.
+// Metadata is complete once Read has returned io.EOF.
+type MetadataReader interface {
+	Metadata() Metadata
+}
+
+type StatementParser interface {
+	Format() Format
+	Extensions() []string
+	// Sniff reports whether the first bytes of a file look like this format.
+	Sniff(head []byte) bool
+	NewReader(r io.Reader, opts Options) (Reader, error)
+}
+
+// Options tune how a statement is read. Formats that describe their own layout ignore them.
+type Options struct {
+	// Profile maps the header of a delimited file to the canonical columns,
+	// without it the columns are expected in canonical order
+	Profile *profile.Profile
+	// Delimiter separates the fields of delimited files read without a profile, a comma when zero
+	Delimiter rune
+	// Sheet is the name of the worksheet to read from a spreadsheet, the first one when empty
+	Sheet string
+	// HeaderRow is the 1-based row holding the column names, replacing the skipped rows of the profile.
+	// Spreadsheets count rows by their number in the sheet, delimited files count non-blank records.
+	HeaderRow int
+	// Currency is assumed for entries whose source does not state one. Formats with
+	// decimal amounts use its minor unit to convert them.
+	Currency money.Currency
+	// StatementCurrency makes the currency a table states in its preamble replace Currency
+	StatementCurrency bool
+	// TimestampFormats are tried in order on the timestamps of delimited files, DefaultTimestampFormats when empty
+	TimestampFormats []string
+	// Location is the time zone of dates and times that carry no offset, UTC when nil
+	Location *time.Location
+	// Limit bounds what a workbook unpacks to, the workbook itself and every part read from it counting
+	// against it. Nil when unbounded
+	Limit UnpackLimit
+}
+
+// UnpackLimit bounds the bytes a compressed upload may unpack to.
+type UnpackLimit interface {
+	// Reader counts what is read from r against the limit and fails every read once it is exceeded
+	Reader(r io.Reader) io.Reader
+	// Check fails when size more bytes would exceed the limit, before any of them is read
+	Check(size uint64) error
+}
+
+func locationOrUTC(location *time.Location) *time.Location {
+	if location == nil {
+		return time.UTC
+	}
+	return location
+}
+
+// currencyScale returns the minor unit of a currency code read from a statement, or of the
+// fallback currency when the code is empty.
+func currencyScale(code string, fallback money.Currency) int {
+	if code = strings.TrimSpace(code); code != "" {
+		return money.Currency(strings.ToUpper(code)).Scale()
+	}
+	return fallback.Scale()
+}
+
+type Registry struct {
+	parsers []StatementParser
+}
+
+func NewRegistry(parsers ...StatementParser) *Registry {
+	return &Registry{
+		parsers: parsers,
+	}
+}
+
+func NewDefaultRegistry() *Registry {
+	// CSV goes last since its content sniffing is the least specific
+	return NewRegistry(NewOFXParser(), NewMT940Parser(), NewCAMTParser(), NewXLSXParser(), NewJSONParser(), NewCSVParser())
+}
+
+func (r *Registry) Register(p StatementParser) {
+	r.parsers = append(r.parsers, p)
+}
+
+// Resolve picks a parser by file extension first and falls back to content sniffing.
+func (r *Registry) Resolve(filename string, head []byte) (StatementParser, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext != "" {
+		for _, p := range r.parsers {
+			for _, e := range p.Extensions() {
+				if e == ext {
+					return p, nil
+				}
+			}
+		}
+	}
+
+	for _, p := range r.parsers {
+		if p.Sniff(head) {
+			return p, nil
+		}
+	}
+
+	return nil, ErrUnsupportedFormat
+}
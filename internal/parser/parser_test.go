@@ -0,0 +1,68 @@
+package parser
+
+import "testing"
+
+func TestRegistry_Resolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		head     string
+		want     Format
+		wantErr  bool
+	}{
+		{
+			name:     "it should resolve parser by file extension",
+			filename: "statement.QFX",
+			head:     "anything",
+			want:     FormatOFX,
+		},
+		{
+			name:     "it should resolve parser by content when extension is unknown",
+			filename: "statement.txt",
+			head:     "OFXHEADER:100\nDATA:OFXSGML",
+			want:     FormatOFX,
+		},
+		{
+			name:     "it should resolve CSV parser by content when extension is missing",
+			filename: "statement",
+			head:     "timestamp,counterparty,type,amount,status,description\n",
+			want:     FormatCSV,
+		},
+		{
+			name:     "it should resolve CSV parser by content when given semicolon delimiter",
+			filename: "statement",
+			head:     "timestamp;counterparty;type;amount;status;description\n",
+			want:     FormatCSV,
+		},
+		{
+			name:     "it should resolve XLSX parser by content when extension is missing",
+			filename: "statement",
+			head:     "PK\x03\x04\x14\x00\x00\x00[Content_Types].xml",
+			want:     FormatXLSX,
+		},
+		{
+			name:     "it should resolve JSON parser by content when extension is missing",
+			filename: "statement",
+			head:     "\n{\"timestamp\": 1674507883, \"counterparty\": \"JOHN DOE\"}",
+			want:     FormatJSON,
+		},
+		{
+			name:     "it should return error when format is not recognized",
+			filename: "statement.pdf",
+			head:     "%PDF-1.7",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewDefaultRegistry().Resolve(tt.filename, []byte(tt.head))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.Format() != tt.want {
+				t.Errorf("Resolve() got = %v, want %v", got.Format(), tt.want)
+			}
+		})
+	}
+}
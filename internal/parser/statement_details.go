@@ -0,0 +1,210 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/profile"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+)
+
+// detailLabel is what a labelled row above or below the transactions of a table states.
+type detailLabel int
+
+const (
+	labelAccount detailLabel = iota + 1
+	labelIBAN
+	labelPeriod
+	labelCurrency
+	labelOpeningBalance
+	labelClosingBalance
+	labelRowCount
+	labelTotalCredits
+	labelTotalDebits
+	labelNetTotal
+)
+
+// detailLabels are matched against the first cell of a row, lowercased and without trailing punctuation
+var detailLabels = map[string]detailLabel{
+	"account":                labelAccount,
+	"account number":         labelAccount,
+	"account no":             labelAccount,
+	"account id":             labelAccount,
+	"iban":                   labelIBAN,
+	"period":                 labelPeriod,
+	"statement period":       labelPeriod,
+	"currency":               labelCurrency,
+	"account currency":       labelCurrency,
+	"opening balance":        labelOpeningBalance,
+	"beginning balance":      labelOpeningBalance,
+	"closing balance":        labelClosingBalance,
+	"ending balance":         labelClosingBalance,
+	"row count":              labelRowCount,
+	"total rows":             labelRowCount,
+	"transaction count":      labelRowCount,
+	"number of transactions": labelRowCount,
+	"total credits":          labelTotalCredits,
+	"total debits":           labelTotalDebits,
+	"net total":              labelNetTotal,
+	"total":                  labelNetTotal,
+}
+
+// periodSeparators split a statement period into its first and last day
+var periodSeparators = []string{" - ", " to ", " – ", " — "}
+
+// detailRow returns the label and value of a row that states something about the statement rather than
+// a transaction: a known label in the first non-empty cell and the value in the next, or both in a single
+// cell separated by a colon. Rows with more than two non-empty cells are never details.
+func detailRow(cells []string) (detailLabel, string, bool) {
+	var values []string
+	for _, cell := range cells {
+		if cell = strings.TrimSpace(cell); cell != "" {
+			values = append(values, cell)
+		}
+	}
+
+	var name, value string
+	switch len(values) {
+	case 1:
+		var ok bool
+		if name, value, ok = strings.Cut(values[0], ":"); !ok {
+			return 0, "", false
+		}
+	case 2:
+		name, value = values[0], values[1]
+	default:
+		return 0, "", false
+	}
+
+	name = strings.Join(strings.Fields(strings.ToLower(strings.TrimRight(strings.TrimSpace(name), ":.#"))), " ")
+	label, ok := detailLabels[name]
+	return label, strings.TrimSpace(value), ok
+}
+
+// statementDetails collects the labelled rows of a table into its Metadata. Amounts are written like the
+// amount column: in the number format of the profile, in minor units without one.
+type statementDetails struct {
+	metadata Metadata
+	format   profile.AmountFormat
+	// currency is assumed until the statement states its own
+	currency money.Currency
+	// adoptCurrency makes the currency the statement states apply to its rows that do not state one
+	adoptCurrency bool
+	timestamps    *timestampParser
+}
+
+func newStatementDetails(opts Options) (*statementDetails, error) {
+	timestamps, err := newTimestampParser(opts.TimestampFormats, opts.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &statementDetails{currency: opts.Currency, adoptCurrency: opts.StatementCurrency, timestamps: timestamps}
+	if opts.Profile != nil {
+		d.format = opts.Profile.AmountFormat
+	}
+	return d, nil
+}
+
+// addRow records the row when it is a labelled one and reports whether it was.
+func (d *statementDetails) addRow(cells []string) (bool, error) {
+	label, value, ok := detailRow(cells)
+	if !ok {
+		return false, nil
+	}
+	return true, d.add(label, value)
+}
+
+func (d *statementDetails) add(label detailLabel, value string) error {
+	details := &d.metadata.Details
+	switch label {
+	case labelAccount:
+		details.AccountNumber = value
+	case labelIBAN:
+		details.IBAN = strings.ToUpper(strings.Join(strings.Fields(value), ""))
+	case labelPeriod:
+		return d.period(value)
+	case labelCurrency:
+		currency, err := money.ParseCurrency(value)
+		if err != nil {
+			return err
+		}
+		details.Currency = currency
+	case labelOpeningBalance, labelClosingBalance:
+		amount, err := d.amount(value)
+		if err != nil {
+			return err
+		}
+		balance := &upload.Balance{Amount: amount, Currency: d.statementCurrency()}
+		if label == labelOpeningBalance {
+			d.metadata.OpeningBalance = balance
+		} else {
+			d.metadata.ClosingBalance = balance
+		}
+	case labelRowCount:
+		count, err := strconv.ParseInt(strings.ReplaceAll(value, ",", ""), 10, 64)
+		if err != nil || count < 0 {
+			return fmt.Errorf("invalid row count '%s'", value)
+		}
+		details.RowCount = &count
+	case labelTotalCredits, labelTotalDebits, labelNetTotal:
+		amount, err := d.amount(value)
+		if err != nil {
+			return err
+		}
+		switch label {
+		case labelTotalCredits:
+			details.TotalCredits = &amount
+		case labelTotalDebits:
+			details.TotalDebits = &amount
+		default:
+			details.NetTotal = &amount
+		}
+	}
+	return nil
+}
+
+func (d *statementDetails) period(value string) error {
+	for _, separator := range periodSeparators {
+		first, last, ok := strings.Cut(value, separator)
+		if !ok {
+			continue
+		}
+		start, err := d.timestamps.parse(first)
+		if err != nil {
+			continue
+		}
+		end, err := d.timestamps.parse(last)
+		if err != nil {
+			continue
+		}
+		if end.Before(start) {
+			return fmt.Errorf("statement period '%s' ends before it starts", value)
+		}
+		d.metadata.Details.PeriodStart, d.metadata.Details.PeriodEnd = start, end
+		return nil
+	}
+	return fmt.Errorf("invalid statement period '%s': expected two dates such as 2024-01-01 - 2024-01-31", value)
+}
+
+func (d *statementDetails) amount(value string) (int64, error) {
+	return parseAmount(value, d.format, d.statementCurrency().Scale())
+}
+
+// statementCurrency is the currency the statement states, or the one assumed when it states none.
+func (d *statementDetails) statementCurrency() money.Currency {
+	if d.metadata.Details.Currency != "" {
+		return d.metadata.Details.Currency
+	}
+	return d.currency
+}
+
+// rowCurrency is the currency of rows that do not state their own.
+func (d *statementDetails) rowCurrency() money.Currency {
+	if d.adoptCurrency {
+		return d.statementCurrency()
+	}
+	return d.currency
+}
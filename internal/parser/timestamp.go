@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimestampUnix is the timestamp format of Unix seconds.
+const TimestampUnix = "unix"
+
+// DefaultTimestampFormats are tried in order when a delimited file has no formats configured.
+// Day-first and month-first dates cannot be told apart, so formats like DD/MM/YYYY must be configured.
+var DefaultTimestampFormats = []string{
+	TimestampUnix,
+	"YYYY-MM-DDTHH:mm:ssZ",
+	"YYYY-MM-DDTHH:mm:ss",
+	"YYYY-MM-DD HH:mm:ssZ",
+	"YYYY-MM-DD HH:mm:ss",
+	"YYYY-MM-DD HH:mm",
+	"YYYY-MM-DD",
+}
+
+// timestampTokens translates the placeholders of a timestamp format to the Go reference time,
+// longer tokens first so YYYY is not read as two YY.
+var timestampTokens = []struct {
+	token  string
+	layout string
+}{
+	{"YYYY", "2006"},
+	{"YY", "06"},
+	{"MMM", "Jan"},
+	{"MM", "01"},
+	{"DD", "02"},
+	{"HH", "15"},
+	{"hh", "03"},
+	{"mm", "04"},
+	{"ss", "05"},
+	{"A", "PM"},
+	{"Z", "Z07:00"},
+}
+
+// TimestampLayout converts a timestamp format such as "DD/MM/YYYY HH:mm" to a Go time layout.
+// Z matches either "Z" or a numeric offset like "+07:00", fractional seconds are always accepted.
+func TimestampLayout(format string) (string, error) {
+	if format == "" {
+		return "", fmt.Errorf("timestamp format cannot be empty")
+	}
+
+	var layout strings.Builder
+	for rest := format; rest != ""; {
+		matched := false
+		for _, t := range timestampTokens {
+			if strings.HasPrefix(rest, t.token) {
+				layout.WriteString(t.layout)
+				rest = rest[len(t.token):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		c := rest[0]
+		// other digits and letters could be read as part of the reference time, T separates ISO 8601 dates and times
+		if c != 'T' && ((c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')) {
+			return "", fmt.Errorf("unexpected '%c' in timestamp format '%s'", c, format)
+		}
+		layout.WriteByte(c)
+		rest = rest[1:]
+	}
+
+	return layout.String(), nil
+}
+
+// timestampParser reads timestamps in any of its formats. Values without an offset are in location.
+type timestampParser struct {
+	formats  []string
+	layouts  []string
+	location *time.Location
+}
+
+func newTimestampParser(formats []string, location *time.Location) (*timestampParser, error) {
+	if len(formats) == 0 {
+		formats = DefaultTimestampFormats
+	}
+
+	p := &timestampParser{formats: formats, location: locationOrUTC(location)}
+	for _, format := range formats {
+		if format == TimestampUnix {
+			p.layouts = append(p.layouts, "")
+			continue
+		}
+
+		layout, err := TimestampLayout(format)
+		if err != nil {
+			return nil, err
+		}
+		p.layouts = append(p.layouts, layout)
+	}
+
+	return p, nil
+}
+
+func (p *timestampParser) parse(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range p.layouts {
+		if layout == "" {
+			if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return time.Unix(seconds, 0).UTC(), nil
+			}
+			continue
+		}
+
+		if t, err := time.ParseInLocation(layout, value, p.location); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("timestamp '%s' does not match any of the formats %s", value, strings.Join(p.formats, ", "))
+}
@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimestampLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{name: "it should translate day-first date and time", format: "DD/MM/YYYY HH:mm", want: "02/01/2006 15:04"},
+		{name: "it should translate ISO 8601 with offset", format: "YYYY-MM-DDTHH:mm:ssZ", want: "2006-01-02T15:04:05Z07:00"},
+		{name: "it should translate month names and 12-hour clock", format: "DD MMM YY hh:mm A", want: "02 Jan 06 03:04 PM"},
+		{name: "it should return error when given unknown letters", format: "YYYY-MM-DD at HH", wantErr: true},
+		{name: "it should return error when given digits", format: "YYYY-01-DD", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TimestampLayout(tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("TimestampLayout() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("TimestampLayout() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_timestampParser_parse(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*3600)
+
+	tests := []struct {
+		name     string
+		formats  []string
+		location *time.Location
+		value    string
+		want     int64
+		wantErr  bool
+	}{
+		{
+			name:  "it should read Unix seconds with default formats",
+			value: "1674507883",
+			want:  1674507883,
+		},
+		{
+			name:  "it should read ISO 8601 with offset and fractional seconds",
+			value: "2023-01-24T04:04:43.250+07:00",
+			want:  1674507883,
+		},
+		{
+			name:     "it should read date only in the source time zone",
+			location: jakarta,
+			value:    "2024-01-23",
+			want:     1705942800,
+		},
+		{
+			name:     "it should read configured day-first format in the source time zone",
+			formats:  []string{"DD/MM/YYYY HH:mm"},
+			location: jakarta,
+			value:    "23/01/2024 14:05",
+			want:     1705993500,
+		},
+		{
+			name:    "it should return error when value matches no format",
+			formats: []string{"DD/MM/YYYY HH:mm"},
+			value:   "2024-01-23",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newTimestampParser(tt.formats, tt.location)
+			if err != nil {
+				t.Fatalf("newTimestampParser() error = %v", err)
+			}
+
+			got, err := p.parse(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.Unix() != tt.want {
+				t.Errorf("parse() got = %v, want %v", got.Unix(), tt.want)
+			}
+		})
+	}
+}
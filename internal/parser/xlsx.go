@@ -0,0 +1,589 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// date cells are written in this format before they are parsed as timestamps
+const (
+	xlsxTimestampFormat = "YYYY-MM-DD HH:mm:ss"
+	xlsxTimestampLayout = "2006-01-02 15:04:05"
+)
+
+var (
+	// excelEpoch is day zero of the 1900 date system, shifted back a day for the leap day 1900 never had
+	excelEpoch     = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+	excelEpoch1904 = time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+type xlsxParser struct{}
+
+// NewXLSXParser reads Office Open XML workbooks. A worksheet is read like a delimited file with
+// a header row, cells formatted as dates become timestamps and numbers keep their full precision.
+func NewXLSXParser() StatementParser {
+	return &xlsxParser{}
+}
+
+func (p *xlsxParser) Format() Format {
+	return FormatXLSX
+}
+
+func (p *xlsxParser) Extensions() []string {
+	return []string{".xlsx"}
+}
+
+// Sniff looks for a zip file whose first entries are the parts of a workbook.
+func (p *xlsxParser) Sniff(head []byte) bool {
+	return IsWorkbook(head)
+}
+
+// IsWorkbook reports whether the first bytes of a zip file belong to an Office Open XML workbook.
+func IsWorkbook(head []byte) bool {
+	return bytes.HasPrefix(head, magicZip) &&
+		(bytes.Contains(head, []byte("[Content_Types].xml")) || bytes.Contains(head, []byte("xl/")))
+}
+
+// NewReader spools the workbook to a temporary file, since a zip file keeps its index at the end.
+// The returned reader removes the file when closed. The workbook and the parts read from it count
+// against opts.Limit.
+func (p *xlsxParser) NewReader(r io.Reader, opts Options) (Reader, error) {
+	spool, err := os.CreateTemp("", "statement-*.xlsx")
+	if err != nil {
+		return nil, fmt.Errorf("failed to spool workbook: %w", err)
+	}
+	cleanup := func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}
+
+	size, err := io.Copy(spool, limitReader(opts.Limit, r))
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to read workbook: %w", err)
+	}
+
+	workbook, err := zip.NewReader(spool, size)
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("invalid workbook: %w", err)
+	}
+
+	reader, err := newWorkbookReader(workbook, opts)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	reader.cleanup = cleanup
+
+	return reader, nil
+}
+
+// xlsxReader reads the rows of one worksheet of a workbook as records.
+type xlsxReader struct {
+	*csvRecordReader
+	sheet   *worksheetReader
+	cleanup func()
+}
+
+func newWorkbookReader(workbook *zip.Reader, opts Options) (*xlsxReader, error) {
+	files := make(map[string]*zip.File, len(workbook.File))
+	for _, f := range workbook.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, date1904, err := findWorksheet(files, opts.Sheet, opts.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedStrings, err := readSharedStrings(files["xl/sharedStrings.xml"], opts.Limit)
+	if err != nil {
+		return nil, err
+	}
+	dateStyles, err := readDateStyles(files["xl/styles.xml"], opts.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	sheetFile, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("worksheet part %s is missing from the workbook", sheetPath)
+	}
+	content, err := openPart(sheetFile, opts.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open worksheet: %w", err)
+	}
+
+	headerRow := opts.HeaderRow
+	if headerRow == 0 && opts.Profile != nil && opts.Profile.SkipRows > 0 {
+		headerRow = opts.Profile.SkipRows + 1
+	}
+
+	sheet := &worksheetReader{
+		content:       content,
+		decoder:       xml.NewDecoder(content),
+		sharedStrings: sharedStrings,
+		dateStyles:    dateStyles,
+		epoch:         excelEpoch,
+		firstRow:      headerRow,
+	}
+	if date1904 {
+		sheet.epoch = excelEpoch1904
+	}
+	if opts.Profile != nil {
+		sheet.decimalSeparator = opts.Profile.AmountFormat.DecimalSeparator
+	}
+
+	// date cells are written in a format of their own, which the timestamp parser has to know
+	if len(opts.TimestampFormats) > 0 && !slices.Contains(opts.TimestampFormats, xlsxTimestampFormat) {
+		opts.TimestampFormats = append(slices.Clone(opts.TimestampFormats), xlsxTimestampFormat)
+	}
+	details, err := newStatementDetails(opts)
+	if err != nil {
+		content.Close()
+		return nil, err
+	}
+	sheet.preamble = details.addRow
+
+	// the header row is found by the worksheet reader, a delimited profile's skipped rows do not apply
+	records, err := newTabularReader(sheet, opts, details)
+	if err != nil {
+		content.Close()
+		return nil, err
+	}
+
+	return &xlsxReader{csvRecordReader: records, sheet: sheet}, nil
+}
+
+func (r *xlsxReader) Close() error {
+	err := r.sheet.content.Close()
+	if r.cleanup != nil {
+		r.cleanup()
+	}
+	return err
+}
+
+type xlsxWorkbook struct {
+	Properties struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		// RelID is the r:id attribute linking the sheet to its part
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// findWorksheet returns the part of the named worksheet, the first one when name is empty, and
+// whether the workbook counts dates from 1904.
+func findWorksheet(files map[string]*zip.File, name string, limit UnpackLimit) (string, bool, error) {
+	var workbook xlsxWorkbook
+	if err := decodePart(files["xl/workbook.xml"], limit, &workbook); err != nil {
+		return "", false, err
+	}
+	var rels xlsxRelationships
+	if err := decodePart(files["xl/_rels/workbook.xml.rels"], limit, &rels); err != nil {
+		return "", false, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", false, errors.New("workbook has no worksheets")
+	}
+
+	sheet := workbook.Sheets[0]
+	if name != "" {
+		found := false
+		for _, s := range workbook.Sheets {
+			if strings.EqualFold(s.Name, name) {
+				sheet, found = s, true
+				break
+			}
+		}
+		if !found {
+			return "", false, fmt.Errorf("worksheet '%s' not found in workbook", name)
+		}
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID != sheet.RelID {
+			continue
+		}
+		// targets are relative to xl/ unless they start at the package root
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), workbook.Properties.Date1904, nil
+		}
+		return path.Join("xl", rel.Target), workbook.Properties.Date1904, nil
+	}
+	return "", false, fmt.Errorf("worksheet '%s' has no part in the workbook", sheet.Name)
+}
+
+// readSharedStrings returns the string table cells of type s point into. Rich text runs are joined.
+func readSharedStrings(f *zip.File, limit UnpackLimit) ([]string, error) {
+	if f == nil {
+		return nil, nil
+	}
+	var table struct {
+		Items []xlsxText `xml:"si"`
+	}
+	if err := decodePart(f, limit, &table); err != nil {
+		return nil, err
+	}
+
+	strs := make([]string, len(table.Items))
+	for i, item := range table.Items {
+		strs[i] = item.String()
+	}
+	return strs, nil
+}
+
+// xlsxText is a plain or rich text string of a shared string or an inline string cell.
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var text strings.Builder
+	text.WriteString(t.Text)
+	for _, run := range t.Runs {
+		text.WriteString(run.Text)
+	}
+	return text.String()
+}
+
+// builtinDateFormats are the predefined number formats that show dates or times
+var builtinDateFormats = map[int]bool{
+	14: true, 15: true, 16: true, 17: true, 18: true, 19: true, 20: true, 21: true, 22: true,
+	45: true, 46: true, 47: true,
+}
+
+// readDateStyles returns for each cell style whether it formats its number as a date or time.
+func readDateStyles(f *zip.File, limit UnpackLimit) ([]bool, error) {
+	if f == nil {
+		return nil, nil
+	}
+	var styles struct {
+		NumberFormats []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellFormats []struct {
+			NumberFormat int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := decodePart(f, limit, &styles); err != nil {
+		return nil, err
+	}
+
+	dateFormats := make(map[int]bool, len(builtinDateFormats))
+	for id := range builtinDateFormats {
+		dateFormats[id] = true
+	}
+	for _, format := range styles.NumberFormats {
+		dateFormats[format.ID] = isDateFormatCode(format.Code)
+	}
+
+	dates := make([]bool, len(styles.CellFormats))
+	for i, xf := range styles.CellFormats {
+		dates[i] = dateFormats[xf.NumberFormat]
+	}
+	return dates, nil
+}
+
+// isDateFormatCode reports whether a custom number format shows a date or time, looking for date
+// and time placeholders outside of quoted text, escaped characters and colours like [Red].
+func isDateFormatCode(code string) bool {
+	inQuotes, inBrackets, escaped := false, false, false
+	for _, c := range strings.ToLower(code) {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+		case c == '[':
+			inBrackets = true
+		case c == ']':
+			inBrackets = false
+		case inBrackets:
+		case strings.ContainsRune("ymdhs", c):
+			return true
+		}
+	}
+	return false
+}
+
+func decodePart(f *zip.File, limit UnpackLimit, v any) error {
+	if f == nil {
+		return errors.New("invalid workbook: missing workbook part")
+	}
+	content, err := openPart(f, limit)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer content.Close()
+
+	if err := xml.NewDecoder(content).Decode(v); err != nil {
+		return fmt.Errorf("invalid workbook part %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// openPart opens a part of a workbook whose size the limit allows, counting what it unpacks to
+// against the limit, since the size a zip file states for a part may not be true.
+func openPart(f *zip.File, limit UnpackLimit) (io.ReadCloser, error) {
+	if limit != nil {
+		if err := limit.Check(f.UncompressedSize64); err != nil {
+			return nil, err
+		}
+	}
+	content, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &limitedPart{Reader: limitReader(limit, content), Closer: content}, nil
+}
+
+// limitedPart reads a part of a workbook through its limit and closes the part itself.
+type limitedPart struct {
+	io.Reader
+	io.Closer
+}
+
+// limitReader counts what is read from r against limit, r is read as it is without one.
+func limitReader(limit UnpackLimit, r io.Reader) io.Reader {
+	if limit == nil {
+		return r
+	}
+	return limit.Reader(r)
+}
+
+// worksheetReader streams the rows of a worksheet, returning each row's cells as text.
+type worksheetReader struct {
+	content       io.ReadCloser
+	decoder       *xml.Decoder
+	sharedStrings []string
+	dateStyles    []bool
+	epoch         time.Time
+	// firstRow is the number of the header row, rows before it are skipped. When zero the first row with a value is the header.
+	firstRow int
+	// preamble is given the rows skipped before firstRow, so labelled ones are not lost
+	preamble func(cells []string) (bool, error)
+	// decimalSeparator is written into numbers, so they read like the amounts of the profile
+	decimalSeparator rune
+	// width is the number of header cells, rows are padded to it since empty trailing cells are left out
+	width int
+}
+
+type xlsxCell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Style  int      `xml:"s,attr"`
+	Value  string   `xml:"v"`
+	Inline xlsxText `xml:"is"`
+}
+
+// readRecord returns the cells of the next row that has a value, its row number and its cells
+// joined by commas as its source text.
+func (w *worksheetReader) readRecord() ([]string, int, string, error) {
+	for {
+		cells, number, err := w.readRow()
+		if err != nil {
+			return nil, 0, "", err
+		}
+		if isBlankRow(cells) {
+			continue
+		}
+		if number < w.firstRow {
+			if _, err := w.preamble(cells); err != nil {
+				return nil, 0, "", fmt.Errorf("row %d: %w", number, err)
+			}
+			continue
+		}
+
+		raw := strings.Join(cells, ",")
+		// the width is taken from the header, labelled rows above it are narrower
+		if _, _, labelled := detailRow(cells); w.width == 0 && !labelled {
+			w.width = len(cells)
+		}
+		for len(cells) < w.width {
+			cells = append(cells, "")
+		}
+		return cells, number, raw, nil
+	}
+}
+
+func (w *worksheetReader) readRow() ([]string, int, error) {
+	var (
+		cells  []string
+		number int
+		inRow  bool
+		// refErr is a cell of the row placed beyond the last column, the rest of the row is still consumed
+		refErr error
+	)
+	for {
+		token, err := w.decoder.Token()
+		if err == io.EOF {
+			if inRow {
+				return nil, 0, io.ErrUnexpectedEOF
+			}
+			return nil, 0, io.EOF
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid worksheet: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				inRow, number = true, number+1
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "r" {
+						if n, err := strconv.Atoi(attr.Value); err == nil {
+							number = n
+						}
+					}
+				}
+			case "c":
+				if !inRow {
+					continue
+				}
+				var cell xlsxCell
+				if err := w.decoder.DecodeElement(&cell, &t); err != nil {
+					return nil, 0, fmt.Errorf("invalid worksheet: %w", err)
+				}
+				if refErr != nil {
+					continue
+				}
+				column := len(cells)
+				if cell.Ref != "" {
+					c, err := cellColumn(cell.Ref)
+					if err != nil {
+						refErr = err
+						continue
+					}
+					if c >= 0 {
+						column = c
+					}
+				}
+				if column >= xlsxMaxColumns {
+					refErr = fmt.Errorf("row has more than %d cells", xlsxMaxColumns)
+					continue
+				}
+				for len(cells) <= column {
+					cells = append(cells, "")
+				}
+				cells[column] = w.cellText(cell)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "row" && inRow {
+				if refErr != nil {
+					return nil, number, &RowError{Line: number, Err: refErr}
+				}
+				return cells, number, nil
+			}
+		}
+	}
+}
+
+// cellText converts the value of a cell to text. Dates become xlsxTimestampFormat and numbers keep
+// the digits Excel shows, dropping the floating point noise beyond 15 significant digits.
+func (w *worksheetReader) cellText(cell xlsxCell) string {
+	switch cell.Type {
+	case "s":
+		index, err := strconv.Atoi(cell.Value)
+		if err != nil || index < 0 || index >= len(w.sharedStrings) {
+			return ""
+		}
+		return w.sharedStrings[index]
+	case "inlineStr":
+		return cell.Inline.String()
+	case "b":
+		if cell.Value == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case "str", "e", "d":
+		// formula results, errors like #N/A and ISO 8601 dates are kept as written
+		return cell.Value
+	}
+
+	number, err := strconv.ParseFloat(cell.Value, 64)
+	if err != nil {
+		return cell.Value
+	}
+	if cell.Style >= 0 && cell.Style < len(w.dateStyles) && w.dateStyles[cell.Style] {
+		return excelSerialTime(number, w.epoch).Format(xlsxTimestampLayout)
+	}
+
+	text := strconv.FormatFloat(number, 'g', 15, 64)
+	if number, err = strconv.ParseFloat(text, 64); err == nil {
+		text = strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	if w.decimalSeparator != 0 && w.decimalSeparator != '.' {
+		text = strings.Replace(text, ".", string(w.decimalSeparator), 1)
+	}
+	return text
+}
+
+// excelSerialTime converts a serial date, the days since epoch with the time of day as fraction,
+// rounding to the second.
+func excelSerialTime(serial float64, epoch time.Time) time.Time {
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 24 * 60 * 60)
+	return epoch.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+}
+
+// xlsxMaxColumns is the number of columns of a worksheet, XFD is the last one.
+const xlsxMaxColumns = 16384
+
+// cellColumn returns the zero based column of a cell reference such as "AB12", or -1 when the
+// reference has no column. References beyond column XFD are an error.
+func cellColumn(ref string) (int, error) {
+	column := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		column = column*26 + int(c-'A'+1)
+		if column > xlsxMaxColumns {
+			return 0, fmt.Errorf("cell reference '%s' is beyond column XFD", ref)
+		}
+	}
+	return column - 1, nil
+}
+
+func isBlankRow(cells []string) bool {
+	for _, cell := range cells {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,358 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/profile"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+)
+
+type testSheet struct {
+	name string
+	// rows is the content of sheetData
+	rows string
+}
+
+// buildWorkbook writes a minimal workbook. Style 1 formats cells as date and time, style 2 with
+// the custom format dd/mm/yyyy. Cells of type s point into the shared strings.
+func buildWorkbook(t *testing.T, date1904 bool, sharedStrings []string, sheets ...testSheet) []byte {
+	t.Helper()
+
+	var sheetList, rels strings.Builder
+	for i, sheet := range sheets {
+		fmt.Fprintf(&sheetList, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, sheet.name, i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	var strs strings.Builder
+	for _, s := range sharedStrings {
+		fmt.Fprintf(&strs, "<si><t>%s</t></si>", s)
+	}
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"></Types>`},
+		{"xl/workbook.xml", fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<workbookPr date1904="%t"/><sheets>%s</sheets></workbook>`, date1904, sheetList.String())},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + rels.String() + `</Relationships>`},
+		{"xl/sharedStrings.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` + strs.String() + `</sst>`},
+		{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="dd/mm/yyyy"/></numFmts>
+<cellXfs count="3"><xf numFmtId="0"/><xf numFmtId="22"/><xf numFmtId="164"/></cellXfs></styleSheet>`},
+	}
+	for i, sheet := range sheets {
+		parts = append(parts, struct{ name, content string }{
+			fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1),
+			`<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + sheet.rows + `</sheetData></worksheet>`,
+		})
+	}
+
+	var workbook bytes.Buffer
+	writer := zip.NewWriter(&workbook)
+	for _, part := range parts {
+		w, err := writer.Create(part.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, part.content)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return workbook.Bytes()
+}
+
+func Test_xlsxReader_Read(t *testing.T) {
+	header := `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c>` +
+		`<c r="D1" t="s"><v>3</v></c><c r="E1" t="s"><v>4</v></c><c r="F1" t="s"><v>5</v></c></row>`
+	canonicalStrings := []string{"timestamp", "counterparty", "type", "amount", "status", "description", "DEBIT", "SUCCESS"}
+
+	tests := []struct {
+		name          string
+		workbook      []byte
+		opts          Options
+		want          [][]string
+		wantLines     []int
+		wantRowErrors []int
+		wantErr       bool
+	}{
+		{
+			name: "it should read the first worksheet with date, number and text cells",
+			workbook: buildWorkbook(t, false, canonicalStrings, testSheet{name: "Sheet1", rows: header +
+				`<row r="2"><c r="A2" s="1"><v>44949.878275462965</v></c><c r="B2" t="inlineStr"><is><t>JOHN DOE</t></is></c>` +
+				`<c r="C2" t="s"><v>6</v></c><c r="D2"><v>250000</v></c><c r="E2" t="s"><v>7</v></c><c r="F2" t="str"><v>restaurant</v></c></row>` +
+				`<row r="3"/>` +
+				`<row r="4"><c r="A4" s="2"><v>44949</v></c><c r="B4" t="inlineStr"><is><r><t>ACME </t></r><r><t>CORP</t></r></is></c>` +
+				`<c r="C4" t="s"><v>6</v></c><c r="D4"><v>75000</v></c><c r="E4" t="s"><v>7</v></c></row>`,
+			}),
+			want: [][]string{
+				{"1674507883", "JOHN DOE", "DEBIT", "250000", "SUCCESS", "restaurant"},
+				{"1674432000", "ACME CORP", "DEBIT", "75000", "SUCCESS", ""},
+			},
+			wantLines: []int{2, 4},
+		},
+		{
+			name: "it should read the worksheet and header row given by options",
+			workbook: buildWorkbook(t, false, []string{"Date", "Payee", "Amount", "State", "Memo"},
+				testSheet{name: "Summary", rows: `<row r="1"><c r="A1" t="inlineStr"><is><t>nothing here</t></is></c></row>`},
+				testSheet{name: "Transactions", rows: `<row r="1"><c r="A1" t="inlineStr"><is><t>Account 123</t></is></c></row>` +
+					`<row r="3"><c r="A3" t="s"><v>0</v></c><c r="B3" t="s"><v>1</v></c><c r="C3" t="s"><v>2</v></c>` +
+					`<c r="D3" t="s"><v>3</v></c><c r="E3" t="s"><v>4</v></c></row>` +
+					`<row r="4"><c r="A4" s="2"><v>44949</v></c><c r="B4" t="inlineStr"><is><t>JOHN DOE</t></is></c>` +
+					`<c r="C4"><v>-1234.5</v></c><c r="D4" t="inlineStr"><is><t>SUCCESS</t></is></c><c r="E4" t="inlineStr"><is><t>rent</t></is></c></row>` +
+					`<row r="5"><c r="A5" s="2"><v>44949</v></c><c r="B5" t="inlineStr"><is><t>ACME CORP</t></is></c>` +
+					`<c r="C5"><v>0.30000000000000004</v></c><c r="D5" t="inlineStr"><is><t>SUCCESS</t></is></c><c r="E5" t="inlineStr"><is><t>refund</t></is></c></row>`,
+				}),
+			opts: Options{
+				Sheet:     "transactions",
+				HeaderRow: 3,
+				Currency:  money.Currency("USD"),
+				Profile: &profile.Profile{
+					Name:         "excel",
+					AmountLayout: profile.AmountLayoutSigned,
+					AmountFormat: profile.AmountFormat{DecimalSeparator: ','},
+					Columns: map[profile.Field]string{
+						profile.FieldTimestamp:    "Date",
+						profile.FieldCounterparty: "Payee",
+						profile.FieldAmount:       "Amount",
+						profile.FieldStatus:       "State",
+						profile.FieldDescription:  "Memo",
+					},
+				},
+			},
+			want: [][]string{
+				{"1674432000", "JOHN DOE", "DEBIT", "123450", "SUCCESS", "rent"},
+				{"1674432000", "ACME CORP", "CREDIT", "30", "SUCCESS", "refund"},
+			},
+			wantLines: []int{4, 5},
+		},
+		{
+			name: "it should count dates from 1904 when the workbook says so",
+			workbook: buildWorkbook(t, true, canonicalStrings, testSheet{name: "Sheet1", rows: header +
+				`<row r="2"><c r="A2" s="2"><v>43487</v></c><c r="B2" t="inlineStr"><is><t>JOHN DOE</t></is></c>` +
+				`<c r="C2" t="s"><v>6</v></c><c r="D2"><v>250000</v></c><c r="E2" t="s"><v>7</v></c><c r="F2" t="str"><v>restaurant</v></c></row>`,
+			}),
+			want: [][]string{
+				{"1674432000", "JOHN DOE", "DEBIT", "250000", "SUCCESS", "restaurant"},
+			},
+			wantLines: []int{2},
+		},
+		{
+			name: "it should return row error when a cell reference is beyond column XFD or overflows",
+			workbook: buildWorkbook(t, false, canonicalStrings, testSheet{name: "Sheet1", rows: header +
+				`<row r="2"><c r="A2" s="2"><v>44949</v></c><c r="XFE2"><v>1</v></c></row>` +
+				`<row r="3"><c r="A3" s="2"><v>44949</v></c><c r="ZZZZZZZZZZZZZZZZZZZZ3"><v>1</v></c></row>` +
+				`<row r="4"><c r="A4" s="2"><v>44949</v></c><c r="B4" t="inlineStr"><is><t>JOHN DOE</t></is></c>` +
+				`<c r="C4" t="s"><v>6</v></c><c r="D4"><v>250000</v></c><c r="E4" t="s"><v>7</v></c></row>`,
+			}),
+			want: [][]string{
+				{"1674432000", "JOHN DOE", "DEBIT", "250000", "SUCCESS", ""},
+			},
+			wantLines:     []int{4},
+			wantRowErrors: []int{2, 3},
+		},
+		{
+			name:     "it should return error when the worksheet does not exist",
+			workbook: buildWorkbook(t, false, canonicalStrings, testSheet{name: "Sheet1", rows: header}),
+			opts:     Options{Sheet: "Transactions"},
+			wantErr:  true,
+		},
+		{
+			name:     "it should return error when given a zip file that is not a workbook",
+			workbook: []byte("PK\x03\x04 not really a zip file"),
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewXLSXParser().NewReader(bytes.NewReader(tt.workbook), tt.opts)
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("NewReader() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			defer reader.(io.Closer).Close()
+
+			var (
+				got          [][]string
+				gotLines     []int
+				gotRowErrors []int
+			)
+			for {
+				record, err := reader.Read()
+				if err == io.EOF {
+					break
+				}
+				var rowErr *RowError
+				if errors.As(err, &rowErr) {
+					gotRowErrors = append(gotRowErrors, rowErr.Line)
+					continue
+				}
+				if err != nil {
+					if !tt.wantErr {
+						t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+					}
+					return
+				}
+				got = append(got, record.Fields)
+				gotLines = append(gotLines, record.Line)
+			}
+
+			if tt.wantErr {
+				t.Errorf("Read() error = nil, wantErr %v", tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read() got = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(gotLines, tt.wantLines) {
+				t.Errorf("Read() lines = %v, want %v", gotLines, tt.wantLines)
+			}
+			if !reflect.DeepEqual(gotRowErrors, tt.wantRowErrors) {
+				t.Errorf("Read() row errors at = %v, want %v", gotRowErrors, tt.wantRowErrors)
+			}
+		})
+	}
+}
+
+func Test_xlsxReader_Metadata(t *testing.T) {
+	cell := func(ref, value string) string {
+		return fmt.Sprintf(`<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, value)
+	}
+	workbook := buildWorkbook(t, false, nil, testSheet{name: "Sheet1", rows: `<row r="1">` + cell("A1", "Account Number") + cell("B1", "ACC-1") + `</row>` +
+		`<row r="2">` + cell("A2", "Period: 2024-01-01 - 2024-01-31") + `</row>` +
+		`<row r="3">` + cell("A3", "timestamp") + cell("B3", "counterparty") + cell("C3", "type") + cell("D3", "amount") + cell("E3", "status") + cell("F3", "description") + `</row>` +
+		`<row r="4"><c r="A4"><v>1674507883</v></c>` + cell("B4", "JOHN DOE") + cell("C4", "DEBIT") + `<c r="D4"><v>250000</v></c>` + cell("E4", "SUCCESS") + `</row>` +
+		`<row r="5">` + cell("A5", "Total Rows") + `<c r="B5"><v>1</v></c></row>`,
+	})
+
+	reader, err := NewXLSXParser().NewReader(bytes.NewReader(workbook), Options{})
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	var got [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		got = append(got, record.Fields)
+	}
+
+	// the row missing its description is padded to the width of the header, not of the preamble
+	want := [][]string{{"1674507883", "JOHN DOE", "DEBIT", "250000", "SUCCESS", ""}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() = %v, want %v", got, want)
+	}
+	rowCount := int64(1)
+	wantDetails := upload.StatementDetails{
+		AccountNumber: "ACC-1",
+		PeriodStart:   time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:     time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC),
+		RowCount:      &rowCount,
+	}
+	if details := reader.(MetadataReader).Metadata().Details; !reflect.DeepEqual(details, wantDetails) {
+		t.Errorf("Metadata() details = %+v, want %+v", details, wantDetails)
+	}
+}
+
+var errTestLimit = errors.New("unpacks to too much")
+
+// testLimit allows remaining bytes to be unpacked.
+type testLimit struct {
+	remaining int64
+}
+
+func (l *testLimit) Reader(r io.Reader) io.Reader {
+	return &testLimitReader{Reader: r, limit: l}
+}
+
+func (l *testLimit) Check(size uint64) error {
+	if size > uint64(l.remaining) {
+		return errTestLimit
+	}
+	return nil
+}
+
+type testLimitReader struct {
+	io.Reader
+	limit *testLimit
+}
+
+func (r *testLimitReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if r.limit.remaining -= int64(n); r.limit.remaining < 0 {
+		return n, errTestLimit
+	}
+	return n, err
+}
+
+func Test_xlsxParser_NewReader_Limit(t *testing.T) {
+	header := `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c>` +
+		`<c r="D1" t="s"><v>3</v></c><c r="E1" t="s"><v>4</v></c><c r="F1" t="s"><v>5</v></c></row>`
+	sharedStrings := []string{"timestamp", "counterparty", "type", "amount", "status", "description"}
+	// the shared strings are padded to unpack to far more than the workbook takes
+	for i := 0; i < 10000; i++ {
+		sharedStrings = append(sharedStrings, "padding")
+	}
+	workbook := buildWorkbook(t, false, sharedStrings, testSheet{name: "Sheet1", rows: header})
+
+	tests := []struct {
+		name    string
+		limit   int64
+		wantErr bool
+	}{
+		{name: "it should read a workbook unpacking to less than the limit", limit: 1 << 20},
+		{name: "it should turn down a workbook whose shared strings unpack to more than the limit", limit: int64(len(workbook)) + 10000, wantErr: true},
+		{name: "it should turn down a workbook larger than the limit", limit: int64(len(workbook)) - 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewXLSXParser().NewReader(bytes.NewReader(workbook), Options{Limit: &testLimit{remaining: tt.limit}})
+			if err == nil {
+				reader.(io.Closer).Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewReader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, errTestLimit) {
+				t.Errorf("NewReader() error = %v, want %v", err, errTestLimit)
+			}
+		})
+	}
+}
+
+func Test_excelSerialTime(t *testing.T) {
+	tests := []struct {
+		name   string
+		serial float64
+		epoch  time.Time
+		want   time.Time
+	}{
+		{name: "it should convert a date", serial: 45292, epoch: excelEpoch, want: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "it should round the time of day to the second", serial: 45292.5000001, epoch: excelEpoch, want: time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)},
+		{name: "it should convert a date of the 1904 system", serial: 43830, epoch: excelEpoch1904, want: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := excelSerialTime(tt.serial, tt.epoch); !got.Equal(tt.want) {
+				t.Errorf("excelSerialTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,7 +2,12 @@ package repository
 
 import (
 	"context"
+	"time"
 
+	"github.com/mj3smile/bank-statement-processor/internal/model/account"
+	"github.com/mj3smile/bank-statement-processor/internal/model/fx"
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/profile"
 	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
 	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
 )
@@ -11,12 +16,65 @@ type UploadRepository interface {
 	Save(ctx context.Context, uploadTask *upload.Task) error
 	Update(ctx context.Context, updateValue *upload.Task) error
 	GetByID(ctx context.Context, uploadID upload.ID) (*upload.Task, error)
+	UpdateProgress(ctx context.Context, uploadID upload.ID, progress upload.Progress) error
+	// ClaimContentHash records uploadID as the upload of the content with the given hash and returns it,
+	// unless another upload claimed the hash first and has not released it, whose ID is returned instead.
+	ClaimContentHash(ctx context.Context, contentHash string, uploadID upload.ID) (upload.ID, error)
+	// ReleaseContentHash gives up the claim of uploadID on the hash, so that the content can be uploaded again.
+	ReleaseContentHash(ctx context.Context, contentHash string, uploadID upload.ID) error
+	// GetByContentHash returns the upload holding the claim on the hash, an error when it is not claimed.
+	GetByContentHash(ctx context.Context, contentHash string) (*upload.Task, error)
+	AddRejection(ctx context.Context, uploadID upload.ID, rejection *upload.Rejection) error
+	GetRejections(ctx context.Context, uploadID upload.ID, page, pageSize int) ([]*upload.Rejection, int, error)
+	// AddDedupDecisions records the dedup decisions of an upload once its transactions are committed.
+	AddDedupDecisions(ctx context.Context, uploadID upload.ID, decisions []*upload.DedupDecision) error
+	GetDedupDecisions(ctx context.Context, uploadID upload.ID, page, pageSize int) ([]*upload.DedupDecision, int, error)
 }
 
 type TransactionRepository interface {
 	Save(ctx context.Context, t *transaction.Transaction) error
-	GetBalanceByUploadID(ctx context.Context, uploadID upload.ID) int64
+	// Stage keeps a transaction out of every query until Commit is called for its upload.
+	// Rollback discards whatever is staged for the upload.
+	Stage(ctx context.Context, t *transaction.Transaction) error
+	Commit(ctx context.Context, uploadID upload.ID) error
+	Rollback(ctx context.Context, uploadID upload.ID) error
+	GetByUploadID(ctx context.Context, uploadID upload.ID) []*transaction.Transaction
+	// GetByFingerprint returns the first committed transaction with the given fingerprint, nil when there is none.
+	// Staged transactions are not matched, their upload may still be rolled back.
+	GetByFingerprint(ctx context.Context, fingerprint string) *transaction.Transaction
+	GetBalancesByUploadID(ctx context.Context, uploadID upload.ID) []money.Money
 	GetIssuesWithFilters(ctx context.Context, filters *transaction.IssuesFilters) ([]*transaction.Transaction, int, error)
 	//PrepareDataForFilters(ctx context.Context, uploadID upload.ID)
 	//CalculateBalance(uploadID upload.ID) int64
 }
+
+type FXRateRepository interface {
+	SaveAll(ctx context.Context, rates []*fx.Rate) error
+	// GetRate returns the latest rate of the pair dated on or before at
+	GetRate(ctx context.Context, base, quote money.Currency, at time.Time) (*fx.Rate, error)
+}
+
+type BatchRepository interface {
+	Save(ctx context.Context, batch *upload.Batch) error
+	Update(ctx context.Context, batch *upload.Batch) error
+	AddUpload(ctx context.Context, batchID upload.BatchID, uploadID upload.ID) error
+	GetByID(ctx context.Context, batchID upload.BatchID) (*upload.Batch, error)
+}
+
+type AccountRepository interface {
+	// AddUpload links an upload to an account, creating the account with its first upload.
+	AddUpload(ctx context.Context, accountID account.ID, uploadID upload.ID) error
+	// AddCompletedUpload counts the committed transactions of an upload towards its account, keeping the
+	// balances of the account up to date. Flagged duplicates are left out, the account has them already.
+	AddCompletedUpload(ctx context.Context, accountID account.ID, uploadID upload.ID, transactions []*transaction.Transaction) error
+	GetByID(ctx context.Context, accountID account.ID) (*account.Account, error)
+	// GetTransactions returns the transactions of the completed uploads of an account in chronological order,
+	// those of the same time in the order they were uploaded.
+	GetTransactions(ctx context.Context, accountID account.ID) ([]*transaction.Transaction, error)
+}
+
+type ProfileRepository interface {
+	Save(ctx context.Context, p *profile.Profile) error
+	GetByName(ctx context.Context, name string) (*profile.Profile, error)
+	List(ctx context.Context) ([]*profile.Profile, error)
+}
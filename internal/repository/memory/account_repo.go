@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/account"
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+	"github.com/mj3smile/bank-statement-processor/internal/repository"
+)
+
+type accountRepository struct {
+	mu       sync.RWMutex
+	accounts map[account.ID]*account.Account
+	// ledgers holds the transactions of the completed uploads of each account in chronological order
+	ledgers map[account.ID][]*transaction.Transaction
+}
+
+func NewAccountRepository() repository.AccountRepository {
+	return &accountRepository{
+		accounts: make(map[account.ID]*account.Account),
+		ledgers:  make(map[account.ID][]*transaction.Transaction),
+	}
+}
+
+func (a *accountRepository) AddUpload(ctx context.Context, accountID account.ID, uploadID upload.ID) error {
+	if accountID == "" {
+		return errors.New("account id is empty")
+	}
+	if uploadID == "" {
+		return errors.New("upload id is empty")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	acc, ok := a.accounts[accountID]
+	if !ok {
+		acc = &account.Account{ID: accountID, Balances: make(map[money.Currency]money.Money), CreatedAt: time.Now()}
+		a.accounts[accountID] = acc
+	}
+	acc.UploadIDs = append(acc.UploadIDs, uploadID)
+	return nil
+}
+
+// AddCompletedUpload adds either all of the transactions or, when a balance would overflow, none.
+func (a *accountRepository) AddCompletedUpload(ctx context.Context, accountID account.ID, uploadID upload.ID, transactions []*transaction.Transaction) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	acc, ok := a.accounts[accountID]
+	if !ok {
+		return errors.New("account not found")
+	}
+
+	balances := make(map[money.Currency]money.Money, len(acc.Balances))
+	for currency, balance := range acc.Balances {
+		balances[currency] = balance
+	}
+	added := make([]*transaction.Transaction, 0, len(transactions))
+	for _, t := range transactions {
+		if t.DuplicateOf != "" {
+			continue
+		}
+		added = append(added, t)
+		if !t.CountsTowardsBalance() {
+			continue
+		}
+
+		balance, ok := balances[t.Currency]
+		if !ok {
+			balance = money.New(0, t.Currency)
+		}
+
+		var err error
+		if t.Type == transaction.TypeCredit {
+			balance, err = balance.Add(t.Money())
+		} else if t.Type == transaction.TypeDebit {
+			balance, err = balance.Sub(t.Money())
+		}
+		if err != nil {
+			return fmt.Errorf("balance of transaction %s: %w", t.ID, err)
+		}
+		balances[t.Currency] = balance
+	}
+
+	acc.Balances = balances
+	acc.CompletedUploads++
+	a.ledgers[accountID] = mergeChronologically(a.ledgers[accountID], added)
+	return nil
+}
+
+// mergeChronologically merges the transactions of an upload into a ledger, after those of the same time
+// uploaded before.
+func mergeChronologically(ledger, transactions []*transaction.Transaction) []*transaction.Transaction {
+	sort.SliceStable(transactions, func(i, j int) bool { return transactions[i].Timestamp < transactions[j].Timestamp })
+
+	merged := make([]*transaction.Transaction, 0, len(ledger)+len(transactions))
+	i, j := 0, 0
+	for i < len(ledger) && j < len(transactions) {
+		if transactions[j].Timestamp < ledger[i].Timestamp {
+			merged = append(merged, transactions[j])
+			j++
+		} else {
+			merged = append(merged, ledger[i])
+			i++
+		}
+	}
+	merged = append(merged, ledger[i:]...)
+	return append(merged, transactions[j:]...)
+}
+
+// GetByID returns a copy of the account, uploads keep being added to it.
+func (a *accountRepository) GetByID(ctx context.Context, accountID account.ID) (*account.Account, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	acc, ok := a.accounts[accountID]
+	if !ok {
+		return nil, errors.New("account not found")
+	}
+
+	found := *acc
+	found.UploadIDs = append([]upload.ID(nil), acc.UploadIDs...)
+	found.Balances = make(map[money.Currency]money.Money, len(acc.Balances))
+	for currency, balance := range acc.Balances {
+		found.Balances[currency] = balance
+	}
+	return &found, nil
+}
+
+func (a *accountRepository) GetTransactions(ctx context.Context, accountID account.ID) ([]*transaction.Transaction, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if _, ok := a.accounts[accountID]; !ok {
+		return nil, errors.New("account not found")
+	}
+	// the ledger is replaced rather than changed as uploads complete, it can be handed out as it is
+	return a.ledgers[accountID], nil
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+)
+
+func Test_accountRepository_AddUpload(t *testing.T) {
+	ctx := context.Background()
+	repo := NewAccountRepository()
+
+	if _, err := repo.GetByID(ctx, "acc-1"); err == nil {
+		t.Fatalf("GetByID() of an account without uploads: got no error")
+	}
+
+	for _, uploadID := range []upload.ID{"first", "second"} {
+		if err := repo.AddUpload(ctx, "acc-1", uploadID); err != nil {
+			t.Fatalf("AddUpload() error = %v", err)
+		}
+	}
+	repo.AddUpload(ctx, "acc-2", "other")
+
+	got, err := repo.GetByID(ctx, "acc-1")
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if want := []upload.ID{"first", "second"}; !reflect.DeepEqual(got.UploadIDs, want) {
+		t.Errorf("GetByID() uploads = %v, want %v", got.UploadIDs, want)
+	}
+
+	// the account returned is a copy
+	got.UploadIDs[0] = "changed"
+	if again, _ := repo.GetByID(ctx, "acc-1"); again.UploadIDs[0] != "first" {
+		t.Errorf("GetByID() uploads changed through a returned account: %v", again.UploadIDs)
+	}
+}
+
+func Test_accountRepository_AddCompletedUpload(t *testing.T) {
+	ctx := context.Background()
+	newTransaction := func(id string, timestamp int64, txType transaction.Type, amount int64, currency money.Currency) *transaction.Transaction {
+		return &transaction.Transaction{ID: transaction.ID(id), Timestamp: timestamp, Type: txType, Amount: amount, Currency: currency, Status: transaction.StatusSuccess}
+	}
+	failed := newTransaction("failed", 150, transaction.TypeDebit, 700, "IDR")
+	failed.Status = transaction.StatusFailed
+	flagged := newTransaction("flagged", 100, transaction.TypeCredit, 1000, "IDR")
+	flagged.DuplicateOf = "first-credit"
+
+	tests := []struct {
+		name             string
+		uploads          map[upload.ID][]*transaction.Transaction
+		order            []upload.ID
+		wantErr          bool
+		wantBalances     map[money.Currency]money.Money
+		wantTransactions []transaction.ID
+		wantCompleted    int
+	}{
+		{
+			name: "it should keep the balances and the transactions of completed uploads in chronological order",
+			uploads: map[upload.ID][]*transaction.Transaction{
+				"first":  {newTransaction("first-credit", 100, transaction.TypeCredit, 1000, "IDR"), newTransaction("first-usd", 300, transaction.TypeCredit, 50, "USD")},
+				"second": {newTransaction("second-late", 400, transaction.TypeDebit, 200, "IDR"), newTransaction("second-tie", 100, transaction.TypeDebit, 100, "IDR"), failed, flagged},
+			},
+			order:            []upload.ID{"first", "second"},
+			wantBalances:     map[money.Currency]money.Money{"IDR": money.New(700, "IDR"), "USD": money.New(50, "USD")},
+			wantTransactions: []transaction.ID{"first-credit", "second-tie", "failed", "first-usd", "second-late"},
+			wantCompleted:    2,
+		},
+		{
+			name: "it should leave an upload out entirely when a balance would overflow",
+			uploads: map[upload.ID][]*transaction.Transaction{
+				"first":  {newTransaction("first-credit", 100, transaction.TypeCredit, math.MaxInt64, "IDR")},
+				"second": {newTransaction("second-usd", 50, transaction.TypeCredit, 50, "USD"), newTransaction("second-credit", 200, transaction.TypeCredit, 1, "IDR")},
+			},
+			order:            []upload.ID{"first", "second"},
+			wantErr:          true,
+			wantBalances:     map[money.Currency]money.Money{"IDR": money.New(math.MaxInt64, "IDR")},
+			wantTransactions: []transaction.ID{"first-credit"},
+			wantCompleted:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewAccountRepository()
+			var err error
+			for _, uploadID := range tt.order {
+				repo.AddUpload(ctx, "acc-1", uploadID)
+				if addErr := repo.AddCompletedUpload(ctx, "acc-1", uploadID, tt.uploads[uploadID]); addErr != nil {
+					err = addErr
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AddCompletedUpload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			acc, _ := repo.GetByID(ctx, "acc-1")
+			if !reflect.DeepEqual(acc.Balances, tt.wantBalances) || acc.CompletedUploads != tt.wantCompleted {
+				t.Errorf("GetByID() balances = %v, completed uploads = %v, want %v, %v", acc.Balances, acc.CompletedUploads, tt.wantBalances, tt.wantCompleted)
+			}
+
+			transactions, _ := repo.GetTransactions(ctx, "acc-1")
+			got := make([]transaction.ID, 0, len(transactions))
+			for _, tx := range transactions {
+				got = append(got, tx.ID)
+			}
+			if !reflect.DeepEqual(got, tt.wantTransactions) {
+				t.Errorf("GetTransactions() = %v, want %v", got, tt.wantTransactions)
+			}
+		})
+	}
+}
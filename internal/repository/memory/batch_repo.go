@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+	"github.com/mj3smile/bank-statement-processor/internal/repository"
+)
+
+type batchRepository struct {
+	mu      sync.RWMutex
+	batches map[upload.BatchID]*upload.Batch
+}
+
+func NewBatchRepository() repository.BatchRepository {
+	return &batchRepository{
+		batches: make(map[upload.BatchID]*upload.Batch),
+	}
+}
+
+func (b *batchRepository) Save(ctx context.Context, batch *upload.Batch) error {
+	if batch == nil {
+		return errors.New("batch is nil")
+	}
+
+	if batch.ID == "" {
+		return errors.New("batch id is empty")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.batches[batch.ID]; ok {
+		return errors.New("batch already exists")
+	}
+
+	stored := *batch
+	stored.UploadIDs = append([]upload.ID(nil), batch.UploadIDs...)
+	b.batches[batch.ID] = &stored
+	return nil
+}
+
+func (b *batchRepository) Update(ctx context.Context, updateValue *upload.Batch) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	batch, ok := b.batches[updateValue.ID]
+	if !ok {
+		return errors.New("batch not found")
+	}
+
+	batch.Status = updateValue.Status
+	batch.Message = updateValue.Message
+	batch.CompletedAt = updateValue.CompletedAt
+	batch.QueuePosition = updateValue.QueuePosition
+	return nil
+}
+
+func (b *batchRepository) AddUpload(ctx context.Context, batchID upload.BatchID, uploadID upload.ID) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	batch, ok := b.batches[batchID]
+	if !ok {
+		return errors.New("batch not found")
+	}
+
+	batch.UploadIDs = append(batch.UploadIDs, uploadID)
+	return nil
+}
+
+// GetByID returns a copy of the batch, uploads keep being added while the archive is read.
+func (b *batchRepository) GetByID(ctx context.Context, batchID upload.BatchID) (*upload.Batch, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	batch, ok := b.batches[batchID]
+	if !ok {
+		return nil, errors.New("batch not found")
+	}
+
+	found := *batch
+	found.UploadIDs = append([]upload.ID(nil), batch.UploadIDs...)
+	return &found, nil
+}
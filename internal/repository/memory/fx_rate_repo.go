@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/fx"
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/repository"
+)
+
+type currencyPair struct {
+	base  money.Currency
+	quote money.Currency
+}
+
+type fxRateRepository struct {
+	mu sync.RWMutex
+	// rates of each pair sorted by date
+	rates map[currencyPair][]*fx.Rate
+}
+
+func NewFXRateRepository() repository.FXRateRepository {
+	return &fxRateRepository{
+		rates: make(map[currencyPair][]*fx.Rate),
+	}
+}
+
+func (rr *fxRateRepository) SaveAll(ctx context.Context, rates []*fx.Rate) error {
+	for _, r := range rates {
+		if r == nil || r.Value == nil {
+			return errors.New("rate cannot be nil")
+		}
+	}
+
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	for _, r := range rates {
+		pair := currencyPair{base: r.Base, quote: r.Quote}
+		pairRates := rr.rates[pair]
+		i := sort.Search(len(pairRates), func(i int) bool { return !pairRates[i].Date.Before(r.Date) })
+		if i < len(pairRates) && pairRates[i].Date.Equal(r.Date) {
+			// a reloaded day replaces the rate that was there
+			pairRates[i] = r
+			continue
+		}
+		pairRates = append(pairRates, nil)
+		copy(pairRates[i+1:], pairRates[i:])
+		pairRates[i] = r
+		rr.rates[pair] = pairRates
+	}
+
+	return nil
+}
+
+func (rr *fxRateRepository) GetRate(ctx context.Context, base, quote money.Currency, at time.Time) (*fx.Rate, error) {
+	rr.mu.RLock()
+	defer rr.mu.RUnlock()
+
+	pairRates := rr.rates[currencyPair{base: base, quote: quote}]
+	i := sort.Search(len(pairRates), func(i int) bool { return pairRates[i].Date.After(at) })
+	if i == 0 {
+		return nil, errors.New("rate not found")
+	}
+
+	return pairRates[i-1], nil
+}
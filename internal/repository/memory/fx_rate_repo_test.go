@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/fx"
+)
+
+func Test_fxRateRepository_GetRate(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2023, time.January, d, 0, 0, 0, 0, time.UTC)
+	}
+	rate := func(d int, value string) *fx.Rate {
+		v, _ := new(big.Rat).SetString(value)
+		return &fx.Rate{Date: day(d), Base: "USD", Quote: "IDR", Value: v}
+	}
+
+	rr := NewFXRateRepository()
+	_ = rr.SaveAll(context.Background(), []*fx.Rate{rate(20, "15100"), rate(16, "15000"), rate(23, "15200")})
+	// reloading a day replaces its rate
+	_ = rr.SaveAll(context.Background(), []*fx.Rate{rate(20, "15150")})
+
+	tests := []struct {
+		name    string
+		at      time.Time
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "it should return the rate of the same day",
+			at:   day(16).Add(15 * time.Hour),
+			want: "15000",
+		},
+		{
+			name: "it should return the latest earlier rate when the day has none",
+			at:   day(22),
+			want: "15150",
+		},
+		{
+			name: "it should return the latest rate after the last day",
+			at:   day(31),
+			want: "15200",
+		},
+		{
+			name:    "it should return error when there is no rate before the given time",
+			at:      day(15),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rr.GetRate(context.Background(), "USD", "IDR", tt.at)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetRate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != nil && got.Value.RatString() != tt.want {
+				t.Errorf("GetRate() got = %v, want %v", got.Value.RatString(), tt.want)
+			}
+		})
+	}
+}
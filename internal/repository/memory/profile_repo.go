@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/profile"
+	"github.com/mj3smile/bank-statement-processor/internal/repository"
+)
+
+type profileRepository struct {
+	mu       sync.RWMutex
+	profiles map[string]*profile.Profile
+}
+
+func NewProfileRepository() repository.ProfileRepository {
+	return &profileRepository{
+		profiles: make(map[string]*profile.Profile),
+	}
+}
+
+func (pr *profileRepository) Save(ctx context.Context, p *profile.Profile) error {
+	if p == nil {
+		return errors.New("profile cannot be nil")
+	}
+
+	if p.Name == "" {
+		return errors.New("profile name cannot be empty")
+	}
+
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	if _, exists := pr.profiles[p.Name]; exists {
+		return errors.New("profile already exists")
+	}
+
+	pr.profiles[p.Name] = p
+	return nil
+}
+
+func (pr *profileRepository) GetByName(ctx context.Context, name string) (*profile.Profile, error) {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+	p, ok := pr.profiles[name]
+	if !ok {
+		return nil, errors.New("profile not found")
+	}
+
+	return p, nil
+}
+
+func (pr *profileRepository) List(ctx context.Context) ([]*profile.Profile, error) {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+
+	profiles := make([]*profile.Profile, 0, len(pr.profiles))
+	for _, p := range pr.profiles {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+
+	return profiles, nil
+}
@@ -3,8 +3,11 @@ package memory
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"sync"
 
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
 	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
 	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
 	"github.com/mj3smile/bank-statement-processor/internal/repository"
@@ -18,21 +21,93 @@ import (
 //}
 
 type transactionRepository struct {
-	mu                sync.RWMutex
-	transactions      map[transaction.ID]*transaction.Transaction
-	uploadIdToIssues  map[upload.ID][]*transaction.Transaction
-	uploadIdToBalance map[upload.ID]int64
+	mu                     sync.RWMutex
+	transactions           map[transaction.ID]*transaction.Transaction
+	uploadIdToTransactions map[upload.ID][]*transaction.Transaction
+	uploadIdToIssues       map[upload.ID][]*transaction.Transaction
+	uploadIdToBalance      map[upload.ID]map[money.Currency]money.Money
+	// fingerprints holds the first committed transaction of each fingerprint
+	fingerprints map[string]*transaction.Transaction
+
+	// staged transactions are invisible until their upload is committed
+	staged    map[upload.ID][]*transaction.Transaction
+	stagedIDs map[transaction.ID]struct{}
 }
 
 func NewTransactionRepository() repository.TransactionRepository {
 	return &transactionRepository{
-		transactions:      make(map[transaction.ID]*transaction.Transaction),
-		uploadIdToIssues:  make(map[upload.ID][]*transaction.Transaction),
-		uploadIdToBalance: make(map[upload.ID]int64),
+		transactions:           make(map[transaction.ID]*transaction.Transaction),
+		uploadIdToTransactions: make(map[upload.ID][]*transaction.Transaction),
+		uploadIdToIssues:       make(map[upload.ID][]*transaction.Transaction),
+		uploadIdToBalance:      make(map[upload.ID]map[money.Currency]money.Money),
+		fingerprints:           make(map[string]*transaction.Transaction),
+		staged:                 make(map[upload.ID][]*transaction.Transaction),
+		stagedIDs:              make(map[transaction.ID]struct{}),
 	}
 }
 
 func (tr *transactionRepository) Save(ctx context.Context, t *transaction.Transaction) error {
+	if err := validateTransaction(t); err != nil {
+		return err
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if tr.exists(t.ID) {
+		return errors.New("transaction already exists")
+	}
+
+	return tr.apply(t.UploadID, []*transaction.Transaction{t})
+}
+
+func (tr *transactionRepository) Stage(ctx context.Context, t *transaction.Transaction) error {
+	if err := validateTransaction(t); err != nil {
+		return err
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if tr.exists(t.ID) {
+		return errors.New("transaction already exists")
+	}
+
+	tr.staged[t.UploadID] = append(tr.staged[t.UploadID], t)
+	tr.stagedIDs[t.ID] = struct{}{}
+	return nil
+}
+
+func (tr *transactionRepository) Commit(ctx context.Context, uploadID upload.ID) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	staged := tr.staged[uploadID]
+	if err := tr.apply(uploadID, staged); err != nil {
+		return err
+	}
+	tr.unstage(uploadID)
+
+	return nil
+}
+
+func (tr *transactionRepository) Rollback(ctx context.Context, uploadID upload.ID) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	tr.unstage(uploadID)
+	return nil
+}
+
+// unstage forgets the staged transactions of an upload. It must be called with tr.mu held.
+func (tr *transactionRepository) unstage(uploadID upload.ID) {
+	for _, t := range tr.staged[uploadID] {
+		delete(tr.stagedIDs, t.ID)
+	}
+	delete(tr.staged, uploadID)
+}
+
+func validateTransaction(t *transaction.Transaction) error {
 	if t == nil {
 		return errors.New("transaction cannot be nil")
 	}
@@ -45,35 +120,93 @@ func (tr *transactionRepository) Save(ctx context.Context, t *transaction.Transa
 		return errors.New("upload ID cannot be empty")
 	}
 
-	tr.mu.Lock()
-	defer tr.mu.Unlock()
+	return nil
+}
 
-	if _, exists := tr.transactions[t.ID]; exists {
-		return errors.New("transaction already exists")
+// exists must be called with tr.mu held
+func (tr *transactionRepository) exists(id transaction.ID) bool {
+	if _, exists := tr.transactions[id]; exists {
+		return true
+	}
+	_, staged := tr.stagedIDs[id]
+	return staged
+}
+
+// apply makes the transactions of an upload visible. Either all of them are applied or, when a
+// balance would overflow, none. It must be called with tr.mu held.
+func (tr *transactionRepository) apply(uploadID upload.ID, transactions []*transaction.Transaction) error {
+	balances := make(map[money.Currency]money.Money, len(tr.uploadIdToBalance[uploadID]))
+	for currency, balance := range tr.uploadIdToBalance[uploadID] {
+		balances[currency] = balance
 	}
 
-	tr.transactions[t.ID] = t
-	if t.Status == transaction.StatusSuccess {
-		balance := tr.uploadIdToBalance[t.UploadID]
+	for _, t := range transactions {
+		if !t.CountsTowardsBalance() {
+			continue
+		}
+
+		balance, ok := balances[t.Currency]
+		if !ok {
+			balance = money.New(0, t.Currency)
+		}
+
+		var err error
 		if t.Type == transaction.TypeCredit {
-			balance += t.Amount
+			balance, err = balance.Add(t.Money())
 		} else if t.Type == transaction.TypeDebit {
-			balance -= t.Amount
+			balance, err = balance.Sub(t.Money())
 		}
-		tr.uploadIdToBalance[t.UploadID] = balance
+		if err != nil {
+			return fmt.Errorf("balance of transaction %s: %w", t.ID, err)
+		}
+		balances[t.Currency] = balance
+	}
 
-	} else {
-		tr.uploadIdToIssues[t.UploadID] = append(tr.uploadIdToIssues[t.UploadID], t)
+	for _, t := range transactions {
+		tr.transactions[t.ID] = t
+		tr.uploadIdToTransactions[uploadID] = append(tr.uploadIdToTransactions[uploadID], t)
+		// flagged duplicates and transactions that broke a business rule are listed with the issues for review
+		if t.Status != transaction.StatusSuccess || t.DuplicateOf != "" || len(t.RuleResults) > 0 {
+			tr.uploadIdToIssues[uploadID] = append(tr.uploadIdToIssues[uploadID], t)
+		}
+		if _, seen := tr.fingerprints[t.Fingerprint]; t.Fingerprint != "" && !seen {
+			tr.fingerprints[t.Fingerprint] = t
+		}
 	}
+	tr.uploadIdToBalance[uploadID] = balances
 
 	return nil
 }
 
-func (tr *transactionRepository) GetBalanceByUploadID(ctx context.Context, uploadID upload.ID) int64 {
+// GetByUploadID returns every committed transaction of an upload in the order it was read.
+func (tr *transactionRepository) GetByUploadID(ctx context.Context, uploadID upload.ID) []*transaction.Transaction {
 	tr.mu.RLock()
 	defer tr.mu.RUnlock()
-	balance := tr.uploadIdToBalance[uploadID]
-	return balance
+
+	transactions := make([]*transaction.Transaction, len(tr.uploadIdToTransactions[uploadID]))
+	copy(transactions, tr.uploadIdToTransactions[uploadID])
+	return transactions
+}
+
+func (tr *transactionRepository) GetByFingerprint(ctx context.Context, fingerprint string) *transaction.Transaction {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	return tr.fingerprints[fingerprint]
+}
+
+// GetBalancesByUploadID returns one balance per currency, ordered by currency code.
+func (tr *transactionRepository) GetBalancesByUploadID(ctx context.Context, uploadID upload.ID) []money.Money {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	balances := make([]money.Money, 0, len(tr.uploadIdToBalance[uploadID]))
+	for _, balance := range tr.uploadIdToBalance[uploadID] {
+		balances = append(balances, balance)
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Currency < balances[j].Currency })
+
+	return balances
 }
 
 func (tr *transactionRepository) GetIssuesWithFilters(ctx context.Context, filters *transaction.IssuesFilters) ([]*transaction.Transaction, int, error) {
@@ -91,6 +224,9 @@ func (tr *transactionRepository) GetIssuesWithFilters(ctx context.Context, filte
 			continue
 		}
 
+		if filters.Currency != "" && t.Currency != filters.Currency {
+			continue
+		}
 		if filters.MinAmount != nil && t.Amount < *filters.MinAmount {
 			continue
 		}
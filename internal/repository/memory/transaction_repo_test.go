@@ -2,14 +2,16 @@ package memory
 
 import (
 	"context"
+	"math"
 	"reflect"
 	"testing"
 
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
 	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
 	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
 )
 
-func Test_transactionRepository_GetBalanceByUploadID(t *testing.T) {
+func Test_transactionRepository_GetBalancesByUploadID(t *testing.T) {
 	type args struct {
 		ctx      context.Context
 		uploadID upload.ID
@@ -18,19 +20,20 @@ func Test_transactionRepository_GetBalanceByUploadID(t *testing.T) {
 	tests := []struct {
 		name         string
 		args         args
-		want         int64
+		want         []money.Money
 		transactions []*transaction.Transaction
 	}{
 		{
 			name: "it should return balance as expected when given transactions with status SUCCESS",
 			args: args{context.Background(), upload.ID("ABCDEFG")},
-			want: 75,
+			want: []money.Money{money.New(75, money.DefaultCurrency)},
 			transactions: []*transaction.Transaction{
 				{
 					ID:       transaction.ID("1234"),
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusSuccess,
 					Amount:   100,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeCredit,
 				},
 				{
@@ -38,20 +41,46 @@ func Test_transactionRepository_GetBalanceByUploadID(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusSuccess,
 					Amount:   25,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeDebit,
 				},
 			},
 		},
+		{
+			name: "it should leave transactions flagged as duplicates out of the balance",
+			args: args{context.Background(), upload.ID("ABCDEFG")},
+			want: []money.Money{money.New(100, money.DefaultCurrency)},
+			transactions: []*transaction.Transaction{
+				{
+					ID:       transaction.ID("1234"),
+					UploadID: upload.ID("ABCDEFG"),
+					Status:   transaction.StatusSuccess,
+					Amount:   100,
+					Currency: money.DefaultCurrency,
+					Type:     transaction.TypeCredit,
+				},
+				{
+					ID:          transaction.ID("5678"),
+					UploadID:    upload.ID("ABCDEFG"),
+					Status:      transaction.StatusSuccess,
+					Amount:      25,
+					Currency:    money.DefaultCurrency,
+					Type:        transaction.TypeDebit,
+					DuplicateOf: transaction.ID("0001"),
+				},
+			},
+		},
 		{
 			name: "it should return balance as expected when given transactions with status SUCCESS, FAILED, and PENDING",
 			args: args{context.Background(), upload.ID("ABCDEFG")},
-			want: 86,
+			want: []money.Money{money.New(86, money.DefaultCurrency)},
 			transactions: []*transaction.Transaction{
 				{
 					ID:       transaction.ID("1234"),
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusSuccess,
 					Amount:   125,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeCredit,
 				},
 				{
@@ -59,6 +88,7 @@ func Test_transactionRepository_GetBalanceByUploadID(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusFailed,
 					Amount:   25,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeDebit,
 				},
 				{
@@ -66,6 +96,7 @@ func Test_transactionRepository_GetBalanceByUploadID(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusSuccess,
 					Amount:   50,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeDebit,
 				},
 				{
@@ -73,6 +104,7 @@ func Test_transactionRepository_GetBalanceByUploadID(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusPending,
 					Amount:   25,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeDebit,
 				},
 				{
@@ -80,6 +112,61 @@ func Test_transactionRepository_GetBalanceByUploadID(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusSuccess,
 					Amount:   11,
+					Currency: money.DefaultCurrency,
+					Type:     transaction.TypeCredit,
+				},
+			},
+		},
+		{
+			name: "it should return one balance per currency when given transactions in several currencies",
+			args: args{context.Background(), upload.ID("ABCDEFG")},
+			want: []money.Money{money.New(-25, "EUR"), money.New(1000, "JPY"), money.New(100, "USD")},
+			transactions: []*transaction.Transaction{
+				{
+					ID:       transaction.ID("1234"),
+					UploadID: upload.ID("ABCDEFG"),
+					Status:   transaction.StatusSuccess,
+					Amount:   100,
+					Currency: "USD",
+					Type:     transaction.TypeCredit,
+				},
+				{
+					ID:       transaction.ID("5678"),
+					UploadID: upload.ID("ABCDEFG"),
+					Status:   transaction.StatusSuccess,
+					Amount:   25,
+					Currency: "EUR",
+					Type:     transaction.TypeDebit,
+				},
+				{
+					ID:       transaction.ID("9102"),
+					UploadID: upload.ID("ABCDEFG"),
+					Status:   transaction.StatusSuccess,
+					Amount:   1000,
+					Currency: "JPY",
+					Type:     transaction.TypeCredit,
+				},
+			},
+		},
+		{
+			name: "it should reject transaction that overflows the balance",
+			args: args{context.Background(), upload.ID("ABCDEFG")},
+			want: []money.Money{money.New(math.MaxInt64, "USD")},
+			transactions: []*transaction.Transaction{
+				{
+					ID:       transaction.ID("1234"),
+					UploadID: upload.ID("ABCDEFG"),
+					Status:   transaction.StatusSuccess,
+					Amount:   math.MaxInt64,
+					Currency: "USD",
+					Type:     transaction.TypeCredit,
+				},
+				{
+					ID:       transaction.ID("5678"),
+					UploadID: upload.ID("ABCDEFG"),
+					Status:   transaction.StatusSuccess,
+					Amount:   1,
+					Currency: "USD",
 					Type:     transaction.TypeCredit,
 				},
 			},
@@ -94,8 +181,8 @@ func Test_transactionRepository_GetBalanceByUploadID(t *testing.T) {
 				}
 			}
 
-			if got := tr.GetBalanceByUploadID(tt.args.ctx, tt.args.uploadID); got != tt.want {
-				t.Errorf("GetBalanceByUploadID() = %v, want %v", got, tt.want)
+			if got := tr.GetBalancesByUploadID(tt.args.ctx, tt.args.uploadID); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetBalancesByUploadID() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -131,6 +218,7 @@ func Test_transactionRepository_GetIssuesWithFilters(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusSuccess,
 					Amount:   100,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeCredit,
 				},
 				{
@@ -138,6 +226,7 @@ func Test_transactionRepository_GetIssuesWithFilters(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusSuccess,
 					Amount:   25,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeDebit,
 				},
 			},
@@ -159,6 +248,7 @@ func Test_transactionRepository_GetIssuesWithFilters(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusSuccess,
 					Amount:   125,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeCredit,
 				},
 				{
@@ -166,6 +256,7 @@ func Test_transactionRepository_GetIssuesWithFilters(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusFailed,
 					Amount:   25,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeDebit,
 				},
 				{
@@ -173,6 +264,7 @@ func Test_transactionRepository_GetIssuesWithFilters(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusSuccess,
 					Amount:   50,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeDebit,
 				},
 				{
@@ -180,6 +272,7 @@ func Test_transactionRepository_GetIssuesWithFilters(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusPending,
 					Amount:   25,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeDebit,
 				},
 				{
@@ -187,6 +280,7 @@ func Test_transactionRepository_GetIssuesWithFilters(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusSuccess,
 					Amount:   11,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeCredit,
 				},
 			},
@@ -196,6 +290,7 @@ func Test_transactionRepository_GetIssuesWithFilters(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusFailed,
 					Amount:   25,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeDebit,
 				},
 				{
@@ -203,6 +298,7 @@ func Test_transactionRepository_GetIssuesWithFilters(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusPending,
 					Amount:   25,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeDebit,
 				},
 			},
@@ -216,6 +312,7 @@ func Test_transactionRepository_GetIssuesWithFilters(t *testing.T) {
 					UploadID:  "ABCDEFG",
 					Page:      2,
 					PageSize:  1,
+					Currency:  money.DefaultCurrency,
 					MinAmount: int64Ptr(20),
 					MaxAmount: int64Ptr(50),
 					Status:    transactionStatusPtr(transaction.StatusFailed),
@@ -227,6 +324,7 @@ func Test_transactionRepository_GetIssuesWithFilters(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusFailed,
 					Amount:   125,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeCredit,
 				},
 				{
@@ -234,6 +332,7 @@ func Test_transactionRepository_GetIssuesWithFilters(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusFailed,
 					Amount:   25,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeDebit,
 				},
 				{
@@ -241,6 +340,7 @@ func Test_transactionRepository_GetIssuesWithFilters(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusFailed,
 					Amount:   19,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeCredit,
 				},
 				{
@@ -248,6 +348,15 @@ func Test_transactionRepository_GetIssuesWithFilters(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusPending,
 					Amount:   50,
+					Currency: money.DefaultCurrency,
+					Type:     transaction.TypeDebit,
+				},
+				{
+					ID:       transaction.ID("3141"),
+					UploadID: upload.ID("ABCDEFG"),
+					Status:   transaction.StatusFailed,
+					Amount:   30,
+					Currency: money.Currency("USD"),
 					Type:     transaction.TypeDebit,
 				},
 				{
@@ -255,6 +364,7 @@ func Test_transactionRepository_GetIssuesWithFilters(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusFailed,
 					Amount:   25,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeDebit,
 				},
 			},
@@ -264,6 +374,7 @@ func Test_transactionRepository_GetIssuesWithFilters(t *testing.T) {
 					UploadID: upload.ID("ABCDEFG"),
 					Status:   transaction.StatusFailed,
 					Amount:   25,
+					Currency: money.DefaultCurrency,
 					Type:     transaction.TypeDebit,
 				},
 			},
@@ -301,3 +412,155 @@ func int64Ptr(i int64) *int64 {
 func transactionStatusPtr(status transaction.Status) *transaction.Status {
 	return &status
 }
+
+func Test_transactionRepository_Stage(t *testing.T) {
+	uploadID := upload.ID("ABCDEFG")
+	filters := &transaction.IssuesFilters{UploadID: uploadID, Page: 1, PageSize: 20}
+	staged := []*transaction.Transaction{
+		{
+			ID:       transaction.ID("1234"),
+			UploadID: uploadID,
+			Status:   transaction.StatusSuccess,
+			Amount:   100,
+			Currency: money.DefaultCurrency,
+			Type:     transaction.TypeCredit,
+		},
+		{
+			ID:       transaction.ID("5678"),
+			UploadID: uploadID,
+			Status:   transaction.StatusFailed,
+			Amount:   25,
+			Currency: money.DefaultCurrency,
+			Type:     transaction.TypeDebit,
+		},
+		{
+			ID:          transaction.ID("9012"),
+			UploadID:    uploadID,
+			Status:      transaction.StatusSuccess,
+			Amount:      50,
+			Currency:    money.DefaultCurrency,
+			Type:        transaction.TypeCredit,
+			RuleResults: []transaction.RuleResult{{Rule: "known", Severity: transaction.SeverityWarn, Message: "counterparty is not on the allow-list"}},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		commit          bool
+		wantBalances    []money.Money
+		wantIssuesCount int
+	}{
+		{
+			name:            "it should make staged transactions visible when upload is committed",
+			commit:          true,
+			wantBalances:    []money.Money{money.New(150, money.DefaultCurrency)},
+			wantIssuesCount: 2,
+		},
+		{
+			name:            "it should discard staged transactions when upload is rolled back",
+			commit:          false,
+			wantBalances:    []money.Money{},
+			wantIssuesCount: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tr := NewTransactionRepository()
+			for _, tx := range staged {
+				if err := tr.Stage(ctx, tx); err != nil {
+					t.Fatalf("Stage() error = %v", err)
+				}
+			}
+
+			if got := tr.GetBalancesByUploadID(ctx, uploadID); len(got) != 0 {
+				t.Errorf("GetBalancesByUploadID() before commit = %v, want none", got)
+			}
+			if _, got, _ := tr.GetIssuesWithFilters(ctx, filters); got != 0 {
+				t.Errorf("GetIssuesWithFilters() total count before commit = %v, want %v", got, 0)
+			}
+			if err := tr.Stage(ctx, staged[0]); err == nil {
+				t.Errorf("Stage() of duplicate transaction error = nil, want error")
+			}
+
+			if tt.commit {
+				_ = tr.Commit(ctx, uploadID)
+			} else {
+				_ = tr.Rollback(ctx, uploadID)
+			}
+
+			if got := tr.GetBalancesByUploadID(ctx, uploadID); !reflect.DeepEqual(got, tt.wantBalances) {
+				t.Errorf("GetBalancesByUploadID() = %v, want %v", got, tt.wantBalances)
+			}
+			if _, got, _ := tr.GetIssuesWithFilters(ctx, filters); got != tt.wantIssuesCount {
+				t.Errorf("GetIssuesWithFilters() total count = %v, want %v", got, tt.wantIssuesCount)
+			}
+		})
+	}
+}
+
+func Test_transactionRepository_GetByFingerprint(t *testing.T) {
+	tests := []struct {
+		name string
+		// stage stages a transaction with the fingerprint for every upload given, in order
+		stage    []upload.ID
+		commit   upload.ID
+		rollback upload.ID
+		want     transaction.ID
+	}{
+		{
+			name: "it should return nothing when no transaction has the fingerprint",
+		},
+		{
+			name:  "it should not return staged transactions",
+			stage: []upload.ID{"first", "second"},
+		},
+		{
+			name:     "it should not return the transactions of an upload rolled back",
+			stage:    []upload.ID{"first", "second"},
+			commit:   upload.ID("second"),
+			rollback: upload.ID("first"),
+			want:     transaction.ID("second-tx"),
+		},
+		{
+			name:   "it should return the committed transaction",
+			stage:  []upload.ID{"first", "second"},
+			commit: upload.ID("second"),
+			want:   transaction.ID("second-tx"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tr := NewTransactionRepository()
+			for _, uploadID := range tt.stage {
+				tx := &transaction.Transaction{
+					ID:          transaction.ID(string(uploadID) + "-tx"),
+					UploadID:    uploadID,
+					Status:      transaction.StatusSuccess,
+					Amount:      100,
+					Currency:    money.DefaultCurrency,
+					Type:        transaction.TypeCredit,
+					Fingerprint: "abc",
+				}
+				if err := tr.Stage(ctx, tx); err != nil {
+					t.Fatalf("Stage() error = %v", err)
+				}
+			}
+			if tt.commit != "" {
+				_ = tr.Commit(ctx, tt.commit)
+			}
+			if tt.rollback != "" {
+				_ = tr.Rollback(ctx, tt.rollback)
+			}
+
+			var got transaction.ID
+			if tx := tr.GetByFingerprint(ctx, "abc"); tx != nil {
+				got = tx.ID
+			}
+			if got != tt.want {
+				t.Errorf("GetByFingerprint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -10,13 +10,20 @@ import (
 )
 
 type uploadRepository struct {
-	mu   sync.RWMutex
-	task map[upload.ID]*upload.Task
+	mu         sync.RWMutex
+	task       map[upload.ID]*upload.Task
+	rejections map[upload.ID][]*upload.Rejection
+	decisions  map[upload.ID][]*upload.DedupDecision
+	// contentHashes holds the upload that claimed each content hash
+	contentHashes map[string]upload.ID
 }
 
 func NewUploadRepository() repository.UploadRepository {
 	return &uploadRepository{
-		task: make(map[upload.ID]*upload.Task),
+		task:          make(map[upload.ID]*upload.Task),
+		rejections:    make(map[upload.ID][]*upload.Rejection),
+		decisions:     make(map[upload.ID][]*upload.DedupDecision),
+		contentHashes: make(map[string]upload.ID),
 	}
 }
 
@@ -35,7 +42,8 @@ func (u *uploadRepository) Save(ctx context.Context, uploadTask *upload.Task) er
 		return errors.New("upload task already exists")
 	}
 
-	u.task[uploadTask.ID] = uploadTask
+	stored := *uploadTask
+	u.task[uploadTask.ID] = &stored
 	return nil
 }
 
@@ -52,10 +60,42 @@ func (u *uploadRepository) Update(ctx context.Context, updateValue *upload.Task)
 	u.task[id].Message = updateValue.Message
 	u.task[id].Status = updateValue.Status
 	u.task[id].CompletedAt = updateValue.CompletedAt
+	u.task[id].QueuePosition = updateValue.QueuePosition
+	if updateValue.RejectedRows > 0 {
+		u.task[id].RejectedRows = updateValue.RejectedRows
+	}
+	if updateValue.OpeningBalance != nil {
+		u.task[id].OpeningBalance = updateValue.OpeningBalance
+	}
+	if updateValue.ClosingBalance != nil {
+		u.task[id].ClosingBalance = updateValue.ClosingBalance
+	}
+	if updateValue.BalanceCheck != nil {
+		u.task[id].BalanceCheck = updateValue.BalanceCheck
+	}
+	if updateValue.Details != nil {
+		u.task[id].Details = updateValue.Details
+	}
+	if len(updateValue.TrailerMismatches) > 0 {
+		u.task[id].TrailerMismatches = updateValue.TrailerMismatches
+	}
+	if updateValue.Currency != "" {
+		u.task[id].Currency = updateValue.Currency
+	}
+	if updateValue.ContentHash != "" {
+		u.task[id].ContentHash = updateValue.ContentHash
+	}
+	if updateValue.DuplicateOf != "" {
+		u.task[id].DuplicateOf = updateValue.DuplicateOf
+	}
+	if updateValue.DuplicateRows > 0 {
+		u.task[id].DuplicateRows = updateValue.DuplicateRows
+	}
 
 	return nil
 }
 
+// GetByID returns a copy of the task, which keeps being updated while the upload is processed.
 func (u *uploadRepository) GetByID(ctx context.Context, uploadID upload.ID) (*upload.Task, error) {
 	u.mu.RLock()
 	defer u.mu.RUnlock()
@@ -64,5 +104,134 @@ func (u *uploadRepository) GetByID(ctx context.Context, uploadID upload.ID) (*up
 		return nil, errors.New("upload ID not found")
 	}
 
-	return task, nil
+	copied := *task
+	return &copied, nil
+}
+
+func (u *uploadRepository) UpdateProgress(ctx context.Context, uploadID upload.ID, progress upload.Progress) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	task, ok := u.task[uploadID]
+	if !ok {
+		return errors.New("upload task not found")
+	}
+
+	task.Progress = progress
+	return nil
+}
+
+func (u *uploadRepository) ClaimContentHash(ctx context.Context, contentHash string, uploadID upload.ID) (upload.ID, error) {
+	if contentHash == "" {
+		return "", errors.New("content hash is empty")
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if _, ok := u.task[uploadID]; !ok {
+		return "", errors.New("upload task not found")
+	}
+
+	if owner, ok := u.contentHashes[contentHash]; ok {
+		return owner, nil
+	}
+
+	u.contentHashes[contentHash] = uploadID
+	return uploadID, nil
+}
+
+// GetByContentHash returns a copy of the task of the upload holding the claim on the hash.
+func (u *uploadRepository) GetByContentHash(ctx context.Context, contentHash string) (*upload.Task, error) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	owner, ok := u.contentHashes[contentHash]
+	if !ok {
+		return nil, errors.New("content hash not claimed")
+	}
+	task, ok := u.task[owner]
+	if !ok {
+		return nil, errors.New("upload ID not found")
+	}
+
+	copied := *task
+	return &copied, nil
+}
+
+func (u *uploadRepository) ReleaseContentHash(ctx context.Context, contentHash string, uploadID upload.ID) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if owner, ok := u.contentHashes[contentHash]; ok && owner == uploadID {
+		delete(u.contentHashes, contentHash)
+	}
+	return nil
+}
+
+func (u *uploadRepository) AddRejection(ctx context.Context, uploadID upload.ID, rejection *upload.Rejection) error {
+	if rejection == nil {
+		return errors.New("rejection is nil")
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if _, ok := u.task[uploadID]; !ok {
+		return errors.New("upload task not found")
+	}
+
+	u.rejections[uploadID] = append(u.rejections[uploadID], rejection)
+	return nil
+}
+
+func (u *uploadRepository) GetRejections(ctx context.Context, uploadID upload.ID, page, pageSize int) ([]*upload.Rejection, int, error) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	rejections := u.rejections[uploadID]
+	totalCount := len(rejections)
+	offset := (page - 1) * pageSize
+	if offset >= totalCount {
+		return []*upload.Rejection{}, totalCount, nil
+	}
+
+	end := offset + pageSize
+	if end > totalCount {
+		end = totalCount
+	}
+
+	return rejections[offset:end], totalCount, nil
+}
+
+func (u *uploadRepository) AddDedupDecisions(ctx context.Context, uploadID upload.ID, decisions []*upload.DedupDecision) error {
+	for _, decision := range decisions {
+		if decision == nil {
+			return errors.New("dedup decision is nil")
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if _, ok := u.task[uploadID]; !ok {
+		return errors.New("upload task not found")
+	}
+
+	u.decisions[uploadID] = append(u.decisions[uploadID], decisions...)
+	return nil
+}
+
+func (u *uploadRepository) GetDedupDecisions(ctx context.Context, uploadID upload.ID, page, pageSize int) ([]*upload.DedupDecision, int, error) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	decisions := u.decisions[uploadID]
+	totalCount := len(decisions)
+	offset := (page - 1) * pageSize
+	if offset >= totalCount {
+		return []*upload.DedupDecision{}, totalCount, nil
+	}
+
+	end := offset + pageSize
+	if end > totalCount {
+		end = totalCount
+	}
+
+	return decisions[offset:end], totalCount, nil
 }
@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+)
+
+func Test_uploadRepository_ClaimContentHash(t *testing.T) {
+	tests := []struct {
+		name        string
+		firstStatus upload.Status
+		want        upload.ID
+	}{
+		{
+			name:        "it should return the first upload when it is still processing",
+			firstStatus: upload.StatusProcessing,
+			want:        upload.ID("first"),
+		},
+		{
+			name:        "it should return the first upload when it has completed",
+			firstStatus: upload.StatusCompleted,
+			want:        upload.ID("first"),
+		},
+		{
+			name:        "it should return the first upload until it releases the hash, even when it has failed",
+			firstStatus: upload.StatusFailed,
+			want:        upload.ID("first"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewUploadRepository()
+			repo.Save(ctx, &upload.Task{ID: "first", Status: upload.StatusProcessing})
+			repo.Save(ctx, &upload.Task{ID: "second", Status: upload.StatusProcessing})
+
+			owner, err := repo.ClaimContentHash(ctx, "abc", "first")
+			if err != nil || owner != "first" {
+				t.Fatalf("ClaimContentHash() = %v, %v, want first", owner, err)
+			}
+			repo.Update(ctx, &upload.Task{ID: "first", Status: tt.firstStatus})
+
+			got, err := repo.ClaimContentHash(ctx, "abc", "second")
+			if err != nil {
+				t.Fatalf("ClaimContentHash() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ClaimContentHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_uploadRepository_ReleaseContentHash(t *testing.T) {
+	tests := []struct {
+		name     string
+		releaser upload.ID
+		want     upload.ID
+	}{
+		{
+			name:     "it should hand the hash over once the first upload releases it",
+			releaser: upload.ID("first"),
+			want:     upload.ID("second"),
+		},
+		{
+			name:     "it should keep the claim when another upload releases the hash",
+			releaser: upload.ID("second"),
+			want:     upload.ID("first"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewUploadRepository()
+			repo.Save(ctx, &upload.Task{ID: "first", Status: upload.StatusProcessing})
+			repo.Save(ctx, &upload.Task{ID: "second", Status: upload.StatusProcessing})
+
+			if _, err := repo.ClaimContentHash(ctx, "abc", "first"); err != nil {
+				t.Fatalf("ClaimContentHash() error = %v", err)
+			}
+			if err := repo.ReleaseContentHash(ctx, "abc", tt.releaser); err != nil {
+				t.Fatalf("ReleaseContentHash() error = %v", err)
+			}
+
+			got, err := repo.ClaimContentHash(ctx, "abc", "second")
+			if err != nil {
+				t.Fatalf("ClaimContentHash() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ClaimContentHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_uploadRepository_GetByContentHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		claim   bool
+		release bool
+		want    upload.ID
+		wantErr bool
+	}{
+		{
+			name:    "it should fail when no upload claimed the hash",
+			wantErr: true,
+		},
+		{
+			name:  "it should return the upload holding the claim",
+			claim: true,
+			want:  upload.ID("first"),
+		},
+		{
+			name:    "it should fail once the claim is released",
+			claim:   true,
+			release: true,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewUploadRepository()
+			repo.Save(ctx, &upload.Task{ID: "first", Status: upload.StatusProcessing})
+			if tt.claim {
+				if _, err := repo.ClaimContentHash(ctx, "abc", "first"); err != nil {
+					t.Fatalf("ClaimContentHash() error = %v", err)
+				}
+			}
+			if tt.release {
+				if err := repo.ReleaseContentHash(ctx, "abc", "first"); err != nil {
+					t.Fatalf("ReleaseContentHash() error = %v", err)
+				}
+			}
+
+			got, err := repo.GetByContentHash(ctx, "abc")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetByContentHash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.ID != tt.want {
+				t.Errorf("GetByContentHash() = %v, want %v", got.ID, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/account"
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+	"github.com/mj3smile/bank-statement-processor/internal/repository"
+)
+
+var ErrAccountNotFound = errors.New("account not found")
+
+type Account interface {
+	// GetBalance returns the balances of an account over all of its completed uploads, consolidated into
+	// reportingCurrency as well unless it is empty
+	GetBalance(ctx context.Context, accountID string, reportingCurrency money.Currency) (*AccountBalanceResult, error)
+	// GetTransactions returns a page of the transactions of all completed uploads of an account, oldest first.
+	GetTransactions(ctx context.Context, accountID string, page, pageSize int) (*AccountTransactionsResult, error)
+}
+
+type accounts struct {
+	accountRepo repository.AccountRepository
+	rateRepo    repository.FXRateRepository
+}
+
+// AccountBalanceResult holds one balance per currency, like GetBalanceResult does for a single upload.
+type AccountBalanceResult struct {
+	AccountID string
+	Balance   *int64
+	Balances  []money.Money
+	Reporting *ReportingBalance
+	// Uploads counts every upload of the account, CompletedUploads those whose transactions count
+	Uploads          int
+	CompletedUploads int
+}
+
+type AccountTransactionsResult struct {
+	AccountID    string
+	Transactions []*transaction.Transaction
+	TotalCount   int
+}
+
+func NewAccount(accountRepo repository.AccountRepository, rateRepo repository.FXRateRepository) Account {
+	return &accounts{
+		accountRepo: accountRepo,
+		rateRepo:    rateRepo,
+	}
+}
+
+// GetBalance reads the balances the account keeps up to date as its uploads complete, only a reporting
+// currency needs its transactions, each converted at the rate of its own day.
+func (a *accounts) GetBalance(ctx context.Context, accountID string, reportingCurrency money.Currency) (*AccountBalanceResult, error) {
+	acc, err := a.accountRepo.GetByID(ctx, account.ID(accountID))
+	if err != nil {
+		return nil, ErrAccountNotFound
+	}
+
+	result := &AccountBalanceResult{
+		AccountID:        accountID,
+		Uploads:          len(acc.UploadIDs),
+		CompletedUploads: acc.CompletedUploads,
+	}
+	for _, balance := range acc.Balances {
+		result.Balances = append(result.Balances, balance)
+	}
+	sort.Slice(result.Balances, func(i, j int) bool { return result.Balances[i].Currency < result.Balances[j].Currency })
+	switch len(result.Balances) {
+	case 0:
+		var b int64
+		result.Balance = &b
+	case 1:
+		b := result.Balances[0].Amount
+		result.Balance = &b
+	}
+
+	if reportingCurrency != "" {
+		transactions, err := a.accountRepo.GetTransactions(ctx, acc.ID)
+		if err != nil {
+			return nil, err
+		}
+		result.Reporting, err = consolidate(ctx, a.rateRepo, transactions, reportingCurrency)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (a *accounts) GetTransactions(ctx context.Context, accountID string, page, pageSize int) (*AccountTransactionsResult, error) {
+	if _, err := a.accountRepo.GetByID(ctx, account.ID(accountID)); err != nil {
+		return nil, ErrAccountNotFound
+	}
+	transactions, err := a.accountRepo.GetTransactions(ctx, account.ID(accountID))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AccountTransactionsResult{
+		AccountID:    accountID,
+		Transactions: []*transaction.Transaction{},
+		TotalCount:   len(transactions),
+	}
+
+	start := (page - 1) * pageSize
+	if start >= len(transactions) {
+		return result, nil
+	}
+	end := min(start+pageSize, len(transactions))
+	result.Transactions = transactions[start:end]
+	return result, nil
+}
@@ -0,0 +1,365 @@
+package usecase
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mj3smile/bank-statement-processor/internal/infra/log"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+	"github.com/mj3smile/bank-statement-processor/internal/parser"
+)
+
+const (
+	// DefaultArchiveMaxEntries is the number of statements an archive may hold when none is configured
+	DefaultArchiveMaxEntries = 1000
+	// DefaultArchiveMaxSize is the number of bytes an upload may unpack to when none is configured
+	DefaultArchiveMaxSize int64 = 10 << 30 // 10 GB
+)
+
+var ErrArchiveTooLarge = errors.New("archive is too large")
+
+// ArchiveConfig bounds what a compressed upload may unpack to, so that a small upload cannot expand
+// into more statements or bytes than the service is prepared to process.
+type ArchiveConfig struct {
+	// MaxEntries is DefaultArchiveMaxEntries when zero or less
+	MaxEntries int
+	// MaxSize counts every byte decompressed from an upload, including gzip compressed statements of an
+	// archive and entries that are skipped. It is DefaultArchiveMaxSize when zero or less
+	MaxSize int64
+}
+
+func (c ArchiveConfig) withDefaults() ArchiveConfig {
+	if c.MaxEntries <= 0 {
+		c.MaxEntries = DefaultArchiveMaxEntries
+	}
+	if c.MaxSize <= 0 {
+		c.MaxSize = DefaultArchiveMaxSize
+	}
+	return c
+}
+
+// unpackLimit is what is left of the bytes an upload may unpack to, shared by every statement of an archive.
+type unpackLimit struct {
+	max       int64
+	remaining int64
+}
+
+func newUnpackLimit(max int64) *unpackLimit {
+	return &unpackLimit{max: max, remaining: max}
+}
+
+// Reader counts what is read from r against the limit and fails every read once it is exceeded.
+func (l *unpackLimit) Reader(r io.Reader) io.Reader {
+	return &limitedReader{Reader: r, limit: l}
+}
+
+// Check fails when size more bytes would exceed what is left of the limit.
+func (l *unpackLimit) Check(size uint64) error {
+	if l.remaining < 0 || size > uint64(l.remaining) {
+		return unpacksTooMuch(l.max)
+	}
+	return nil
+}
+
+// err returns ErrArchiveTooLarge once the limit has been exceeded.
+func (l *unpackLimit) err() error {
+	if l.remaining >= 0 {
+		return nil
+	}
+	return unpacksTooMuch(l.max)
+}
+
+type limitedReader struct {
+	io.Reader
+	limit *unpackLimit
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if err := r.limit.err(); err != nil {
+		return 0, err
+	}
+	// one byte more than is left tells a stream that ends right at the limit from one that goes past it
+	if int64(len(p)) > r.limit.remaining+1 {
+		p = p[:r.limit.remaining+1]
+	}
+	n, err := r.Reader.Read(p)
+	r.limit.remaining -= int64(n)
+	if limitErr := r.limit.err(); limitErr != nil {
+		return n, limitErr
+	}
+	return n, err
+}
+
+func unpacksTooMuch(max int64) error {
+	return fmt.Errorf("%w: it unpacks to more than %d bytes", ErrArchiveTooLarge, max)
+}
+
+func tooManyEntries(max int) error {
+	return fmt.Errorf("%w: it holds more than %d statements", ErrArchiveTooLarge, max)
+}
+
+// queueBatch saves the batch of an archive, as queued while its admission waits for a worker, and keeps
+// its position in the queue up to date.
+func (uc *statement) queueBatch(ctx context.Context, batch *upload.Batch, admission *admission) error {
+	if position := uc.ingestion.position(admission); position > 0 {
+		batch.Status, batch.Message, batch.QueuePosition = upload.StatusQueued, upload.MessageQueued, position
+	}
+	if err := uc.batchRepo.Save(ctx, batch); err != nil {
+		log.Info(ctx, fmt.Sprint("save batch error:", err.Error()))
+		return err
+	}
+
+	uc.ingestion.follow(admission, func(position int) {
+		update := &upload.Batch{ID: batch.ID, Status: upload.StatusQueued, Message: upload.MessageQueued, QueuePosition: position}
+		if err := uc.batchRepo.Update(uc.appCtx, update); err != nil {
+			log.Info(uc.appCtx, fmt.Sprint("failed to update queue position:", err.Error()))
+		}
+	})
+	return nil
+}
+
+// processArchive processes the statements of an archive one after another, each as an upload of the batch,
+// once the archive has its turn in the ingestion queue. Tar archives are read as they arrive, zip archives
+// keep their index at the end and are spooled to a temporary file first.
+func (uc *statement) processArchive(ctx context.Context, batch *upload.Batch, admission *admission, file io.Closer, source io.Reader, archive parser.Archive, opts UploadOptions, parserOpts parser.Options) {
+	closeFile := sync.OnceFunc(func() { file.Close() })
+	defer closeFile()
+
+	if !uc.ingestion.wait(ctx, admission) {
+		uc.completeBatch(ctx, batch.ID, errors.New("processing cancelled"))
+		return
+	}
+	defer uc.ingestion.done(admission)
+
+	if err := uc.batchRepo.Update(ctx, &upload.Batch{ID: batch.ID, Status: upload.StatusProcessing, Message: upload.MessageProcessing}); err != nil {
+		log.Info(ctx, fmt.Sprint("failed to mark batch as processing:", err.Error()))
+	}
+
+	limit := newUnpackLimit(uc.archiveLimits.MaxSize)
+	var err error
+	switch archive {
+	case parser.ArchiveZip:
+		err = uc.processZip(ctx, batch.ID, source, closeFile, limit, opts, parserOpts)
+	case parser.ArchiveTarGzip:
+		var decompressed *gzip.Reader
+		decompressed, err = gzip.NewReader(source)
+		if err == nil {
+			err = uc.processTar(ctx, batch.ID, limit.Reader(decompressed), limit, opts, parserOpts)
+		}
+	default:
+		err = uc.processTar(ctx, batch.ID, source, limit, opts, parserOpts)
+	}
+
+	uc.completeBatch(ctx, batch.ID, err)
+}
+
+// processZip spools the archive, no larger than what it may unpack to, and checks the statements
+// its index declares against the limits before processing any of them.
+func (uc *statement) processZip(ctx context.Context, batchID upload.BatchID, source io.Reader, closeFile func(), limit *unpackLimit, opts UploadOptions, parserOpts parser.Options) error {
+	spool, err := os.CreateTemp("", "statement-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to spool archive: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	// even stored entries take up no less than they unpack to
+	size, err := io.Copy(spool, io.LimitReader(source, limit.max+1))
+	// the upload request can end once the archive has been received
+	closeFile()
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	if size > limit.max {
+		return fmt.Errorf("%w: it is larger than %d bytes", ErrArchiveTooLarge, limit.max)
+	}
+
+	archive, err := zip.NewReader(spool, size)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	var members []*zip.File
+	var declared uint64
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() || !parser.IsArchiveMember(f.Name) {
+			continue
+		}
+		if len(members) == uc.archiveLimits.MaxEntries {
+			return tooManyEntries(uc.archiveLimits.MaxEntries)
+		}
+		// compared to what is left so that sizes crafted to overflow the sum are caught
+		if f.UncompressedSize64 > uint64(limit.max)-declared {
+			return unpacksTooMuch(limit.max)
+		}
+		members = append(members, f)
+		declared += f.UncompressedSize64
+	}
+
+	for _, f := range members {
+		if ctx.Err() != nil {
+			return errors.New("processing cancelled")
+		}
+
+		entry, err := f.Open()
+		if err != nil {
+			uc.recordFailedEntry(ctx, batchID, f.Name, opts, err)
+			continue
+		}
+		// the index may understate what an entry unpacks to
+		uc.processEntry(ctx, batchID, limit.Reader(entry), f.Name, int64(f.UncompressedSize64), limit, opts, parserOpts)
+		entry.Close()
+		if err := limit.err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (uc *statement) processTar(ctx context.Context, batchID upload.BatchID, source io.Reader, limit *unpackLimit, opts UploadOptions, parserOpts parser.Options) error {
+	archive := tar.NewReader(source)
+	entries := 0
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err := limit.err(); err != nil {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		if ctx.Err() != nil {
+			return errors.New("processing cancelled")
+		}
+		if header.Typeflag != tar.TypeReg || !parser.IsArchiveMember(header.Name) {
+			continue
+		}
+		if entries++; entries > uc.archiveLimits.MaxEntries {
+			return tooManyEntries(uc.archiveLimits.MaxEntries)
+		}
+
+		// the next entry skips whatever processing leaves unread of this one
+		uc.processEntry(ctx, batchID, archive, header.Name, header.Size, limit, opts, parserOpts)
+		if err := limit.err(); err != nil {
+			return err
+		}
+	}
+}
+
+// processEntry processes one statement of an archive and waits for it to finish. Entries may be gzip compressed.
+func (uc *statement) processEntry(ctx context.Context, batchID upload.BatchID, entry io.Reader, name string, size int64, limit *unpackLimit, opts UploadOptions, parserOpts parser.Options) {
+	opts.Size = size
+
+	source := bufio.NewReaderSize(entry, sniffSize)
+	head, err := source.Peek(sniffSize)
+	if err != nil && err != io.EOF {
+		uc.recordFailedEntry(ctx, batchID, name, opts, fmt.Errorf("failed to read file: %w", err))
+		return
+	}
+
+	archive := parser.DetectArchive(name, head)
+	if archive.IsMultiFile() {
+		uc.recordFailedEntry(ctx, batchID, name, opts, fmt.Errorf("%w: nested archives are not supported", ErrInvalidArchive))
+		return
+	}
+
+	prepared, err := uc.prepare(ctx, source, name, archive, batchID, limit, opts, parserOpts)
+	if err != nil {
+		uc.recordFailedEntry(ctx, batchID, name, opts, err)
+		return
+	}
+	// the upload can be cancelled as soon as its task can be found
+	uploadCtx := uc.cancellations.register(ctx, prepared.task.ID)
+	if err := uc.saveTask(ctx, prepared.task); err != nil {
+		uc.cancellations.release(prepared.task.ID)
+		uc.recordFailedEntry(ctx, batchID, name, opts, err)
+		return
+	}
+
+	uc.processStatement(uploadCtx, prepared, io.NopCloser(nil))
+}
+
+// recordFailedEntry keeps a failed upload for an archive entry that could not be read as a statement,
+// so that it shows up in the batch.
+func (uc *statement) recordFailedEntry(ctx context.Context, batchID upload.BatchID, name string, opts UploadOptions, reason error) {
+	now := time.Now()
+	task := &upload.Task{
+		ID:          upload.ID(uuid.NewString()),
+		BatchID:     batchID,
+		Status:      upload.StatusFailed,
+		Message:     reason.Error(),
+		Filename:    name,
+		Profile:     opts.Profile,
+		Lenient:     opts.Lenient,
+		Account:     opts.Account,
+		StartedAt:   now,
+		CompletedAt: now,
+	}
+
+	if err := uc.saveTask(ctx, task); err != nil {
+		log.Info(ctx, fmt.Sprint("failed to record archive entry ", name, ": ", err.Error()))
+	}
+}
+
+// completeBatch sums up the uploads of a batch once its archive has been read.
+func (uc *statement) completeBatch(ctx context.Context, batchID upload.BatchID, archiveErr error) {
+	batch, err := uc.batchRepo.GetByID(ctx, batchID)
+	if err != nil {
+		log.Info(ctx, fmt.Sprint("failed to complete batch:", err.Error()))
+		return
+	}
+
+	failed, withErrors := 0, 0
+	for _, uploadID := range batch.UploadIDs {
+		task, err := uc.uploadRepo.GetByID(ctx, uploadID)
+		if err != nil {
+			failed++
+			continue
+		}
+		switch task.Status {
+		case upload.StatusCompleted, upload.StatusDuplicate:
+		case upload.StatusCompletedWithErrors:
+			withErrors++
+		default:
+			failed++
+		}
+	}
+
+	total := len(batch.UploadIDs)
+	update := &upload.Batch{ID: batchID, CompletedAt: time.Now()}
+	switch {
+	case errors.Is(archiveErr, ErrArchiveTooLarge):
+		update.Status, update.Message = upload.StatusFailed, archiveErr.Error()
+	case archiveErr != nil && total == 0:
+		update.Status, update.Message = upload.StatusFailed, archiveErr.Error()
+	case archiveErr != nil:
+		update.Status, update.Message = upload.StatusCompletedWithErrors, archiveErr.Error()
+	case total == 0:
+		update.Status, update.Message = upload.StatusFailed, "archive holds no statements"
+	case failed == total:
+		update.Status, update.Message = upload.StatusFailed, "all statements failed"
+	case failed > 0 || withErrors > 0:
+		update.Status = upload.StatusCompletedWithErrors
+		update.Message = fmt.Sprintf("%d of %d statements failed, %d completed with rejected rows", failed, total, withErrors)
+	default:
+		update.Status = upload.StatusCompleted
+	}
+
+	if err := uc.batchRepo.Update(ctx, update); err != nil {
+		log.Info(ctx, fmt.Sprint("failed to complete batch:", err.Error()))
+	}
+}
@@ -0,0 +1,223 @@
+package usecase
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/event"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+	"github.com/mj3smile/bank-statement-processor/internal/parser"
+	"github.com/mj3smile/bank-statement-processor/internal/repository"
+	"github.com/mj3smile/bank-statement-processor/internal/repository/memory"
+)
+
+func Test_statement_Upload_ArchiveLimits(t *testing.T) {
+	small := []string{statementRows(0, 1), statementRows(1, 1), statementRows(2, 1)}
+	large := statementRows(10, 120)
+	// every entry of a tar archive takes a header of 512 bytes and is padded to a multiple of it
+	limits := ArchiveConfig{MaxEntries: 2, MaxSize: 5000}
+
+	tests := []struct {
+		name        string
+		filename    string
+		content     []byte
+		wantMessage string
+		// wantUploads are the statuses of the statements processed before the limit was reached
+		wantUploads []upload.Status
+	}{
+		{
+			name:        "it should fail a zip archive holding too many statements before processing any",
+			filename:    "statements.zip",
+			content:     zipContent(t, zip.Deflate, small[0], small[1], small[2]),
+			wantMessage: "archive is too large: it holds more than 2 statements",
+		},
+		{
+			name:        "it should fail a zip archive unpacking to too much before processing any",
+			filename:    "statements.zip",
+			content:     zipContent(t, zip.Deflate, large),
+			wantMessage: "archive is too large: it unpacks to more than 5000 bytes",
+		},
+		{
+			name:        "it should fail a zip archive larger than it may unpack to without spooling it",
+			filename:    "statements.zip",
+			content:     zipContent(t, zip.Store, large),
+			wantMessage: "archive is too large: it is larger than 5000 bytes",
+		},
+		{
+			name:        "it should fail a tar archive holding too many statements at the first one too many",
+			filename:    "statements.tar.gz",
+			content:     gzipBytes(t, tarContent(t, small[0], small[1], small[2])),
+			wantMessage: "archive is too large: it holds more than 2 statements",
+			wantUploads: []upload.Status{upload.StatusCompleted, upload.StatusCompleted},
+		},
+		{
+			name:        "it should fail a tar archive unpacking to too much at the statement going past the limit",
+			filename:    "statements.tar.gz",
+			content:     gzipBytes(t, tarContent(t, small[0], large, small[1])),
+			wantMessage: "archive is too large: it unpacks to more than 5000 bytes",
+			wantUploads: []upload.Status{upload.StatusCompleted, upload.StatusFailed},
+		},
+		{
+			name:        "it should count gzip compressed statements of a tar archive as they unpack",
+			filename:    "statements.tar",
+			content:     tarContent(t, string(gzipBytes(t, []byte(large)))),
+			wantMessage: "archive is too large: it unpacks to more than 5000 bytes",
+			wantUploads: []upload.Status{upload.StatusFailed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			uploadRepo, batchRepo := memory.NewUploadRepository(), memory.NewBatchRepository()
+			uc := newTestStatementWithArchive(ctx, uploadRepo, batchRepo, limits)
+
+			result, err := uc.Upload(ctx, io.NopCloser(bytes.NewReader(tt.content)), tt.filename, UploadOptions{})
+			if err != nil {
+				t.Fatalf("Upload() error = %v", err)
+			}
+
+			batch := waitForBatch(t, batchRepo, result.BatchID)
+			if batch.Status != upload.StatusFailed || batch.Message != tt.wantMessage {
+				t.Errorf("Upload() batch = %v (%s), want %v (%s)", batch.Status, batch.Message, upload.StatusFailed, tt.wantMessage)
+			}
+			if len(batch.UploadIDs) != len(tt.wantUploads) {
+				t.Fatalf("Upload() batch uploads = %v, want %v", len(batch.UploadIDs), len(tt.wantUploads))
+			}
+			for i, uploadID := range batch.UploadIDs {
+				if task := waitForUpload(t, uploadRepo, uploadID); task.Status != tt.wantUploads[i] {
+					t.Errorf("Upload() statement %d = %v (%s), want %v", i, task.Status, task.Message, tt.wantUploads[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_statement_Upload_GzipLimit(t *testing.T) {
+	content := statementRows(0, 700)
+
+	tests := []struct {
+		name       string
+		maxSize    int64
+		wantErr    error
+		wantStatus upload.Status
+	}{
+		{name: "it should process a gzip compressed statement within the limit", maxSize: int64(len(content)), wantStatus: upload.StatusCompleted},
+		{name: "it should turn down a gzip compressed statement whose head unpacks to more than the limit", maxSize: 1000, wantErr: ErrArchiveTooLarge},
+		{name: "it should fail a gzip compressed statement unpacking to more than the limit", maxSize: int64(len(content)) - 1, wantStatus: upload.StatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			uploadRepo := memory.NewUploadRepository()
+			uc := newTestStatementWithArchive(ctx, uploadRepo, memory.NewBatchRepository(), ArchiveConfig{MaxSize: tt.maxSize})
+
+			result, err := uc.Upload(ctx, io.NopCloser(bytes.NewReader(gzipBytes(t, []byte(content)))), "statement.csv.gz", UploadOptions{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Upload() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			task := waitForUpload(t, uploadRepo, result.UploadID)
+			if task.Status != tt.wantStatus {
+				t.Errorf("Upload() status = %v (%s), want %v", task.Status, task.Message, tt.wantStatus)
+			}
+			if tt.wantStatus == upload.StatusFailed && !strings.Contains(task.Message, ErrArchiveTooLarge.Error()) {
+				t.Errorf("Upload() message = %s, want it to mention %v", task.Message, ErrArchiveTooLarge)
+			}
+		})
+	}
+}
+
+func newTestStatementWithArchive(ctx context.Context, uploadRepo repository.UploadRepository, batchRepo repository.BatchRepository, archive ArchiveConfig) Statement {
+	return NewStatement(ctx, StatementConfig{
+		TransactionRepo: memory.NewTransactionRepository(),
+		UploadRepo:      uploadRepo,
+		BatchRepo:       batchRepo,
+		AccountRepo:     memory.NewAccountRepository(),
+		ProfileRepo:     memory.NewProfileRepository(),
+		EventBus:        event.NewBus(ctx),
+		Parsers:         parser.NewDefaultRegistry(),
+		Rules:           NewRules(""),
+		Archive:         archive,
+	})
+}
+
+// waitForBatch waits until every statement of a batch has been processed and returns it.
+func waitForBatch(t *testing.T, batchRepo repository.BatchRepository, batchID upload.BatchID) *upload.Batch {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		batch, err := batchRepo.GetByID(context.Background(), batchID)
+		if err == nil && !batch.CompletedAt.IsZero() {
+			return batch
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("batch %s is still processing", batchID)
+	return nil
+}
+
+// statementRows returns rows of a statement, each a second after the other from the given second.
+func statementRows(from, rows int) string {
+	var content strings.Builder
+	for i := from; i < from+rows; i++ {
+		fmt.Fprintf(&content, "%d, JOHN DOE, DEBIT, 250000, SUCCESS, restaurant\n", 1674507883+i)
+	}
+	return content.String()
+}
+
+// zipContent writes every statement as an entry of a zip archive compressed with method.
+func zipContent(t *testing.T, method uint16, statements ...string) []byte {
+	var archive bytes.Buffer
+	w := zip.NewWriter(&archive)
+	for i, statement := range statements {
+		entry, err := w.CreateHeader(&zip.FileHeader{Name: fmt.Sprintf("statement-%d.csv", i+1), Method: method})
+		if err != nil {
+			t.Fatalf("error while creating zip entry: %v", err)
+		}
+		io.WriteString(entry, statement)
+	}
+	w.Close()
+	return archive.Bytes()
+}
+
+// tarContent writes every statement as an entry of a tar archive, gzip compressed statements as .csv.gz.
+func tarContent(t *testing.T, statements ...string) []byte {
+	var archive bytes.Buffer
+	w := tar.NewWriter(&archive)
+	for i, statement := range statements {
+		name := fmt.Sprintf("statement-%d.csv", i+1)
+		if strings.HasPrefix(statement, "\x1f\x8b") {
+			name += ".gz"
+		}
+		if err := w.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(statement)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("error while writing tar header: %v", err)
+		}
+		io.WriteString(w, statement)
+	}
+	w.Close()
+	return archive.Bytes()
+}
+
+func gzipBytes(t *testing.T, content []byte) []byte {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	if _, err := w.Write(content); err != nil {
+		t.Fatalf("error while compressing: %v", err)
+	}
+	w.Close()
+	return compressed.Bytes()
+}
@@ -5,34 +5,60 @@ import (
 	"fmt"
 
 	"github.com/mj3smile/bank-statement-processor/internal/infra/log"
+	"github.com/mj3smile/bank-statement-processor/internal/model/fx"
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
 	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
 	"github.com/mj3smile/bank-statement-processor/internal/repository"
 )
 
 type Balance interface {
-	Get(ctx context.Context, uploadID string) (*GetBalanceResult, error)
+	// Get returns the balances of an upload, consolidated into reportingCurrency as well unless it is empty
+	Get(ctx context.Context, uploadID string, reportingCurrency money.Currency) (*GetBalanceResult, error)
 }
 
 type balance struct {
 	transactionRepo repository.TransactionRepository
 	uploadRepo      repository.UploadRepository
+	rateRepo        repository.FXRateRepository
 }
 
+// GetBalanceResult holds one balance per currency. Balance is only set when the upload
+// holds at most one currency, adding amounts of different currencies means nothing.
 type GetBalanceResult struct {
 	UploadID          string
 	Balance           *int64
+	Balances          []money.Money
 	UploadTaskStatus  string
 	UploadTaskMessage string
+	// QueuePosition is the place of a queued upload in the ingestion queue
+	QueuePosition  int
+	OpeningBalance *upload.Balance
+	ClosingBalance *upload.Balance
+	// BalanceCheck is set once processed when both statement balances are known
+	BalanceCheck *upload.BalanceCheck
+	Reporting    *ReportingBalance
+	// Account, DedupPolicy and DuplicateRows tell how transactions imported before were handled
+	Account       string
+	DedupPolicy   upload.DedupPolicy
+	DuplicateRows int
 }
 
-func NewBalance(transactionRepo repository.TransactionRepository, uploadRepo repository.UploadRepository) Balance {
+// ReportingBalance is the balance of every currency converted into a single one, with the rates that were used.
+type ReportingBalance struct {
+	Balance money.Money
+	Rates   []*fx.Rate
+}
+
+func NewBalance(transactionRepo repository.TransactionRepository, uploadRepo repository.UploadRepository, rateRepo repository.FXRateRepository) Balance {
 	return &balance{
 		transactionRepo: transactionRepo,
 		uploadRepo:      uploadRepo,
+		rateRepo:        rateRepo,
 	}
 }
 
-func (g *balance) Get(ctx context.Context, uploadID string) (*GetBalanceResult, error) {
+func (g *balance) Get(ctx context.Context, uploadID string, reportingCurrency money.Currency) (*GetBalanceResult, error) {
 	task, err := g.uploadRepo.GetByID(ctx, upload.ID(uploadID))
 	if err != nil {
 		log.Info(ctx, fmt.Sprint("get upload task error: ", err.Error()))
@@ -43,13 +69,70 @@ func (g *balance) Get(ctx context.Context, uploadID string) (*GetBalanceResult,
 		UploadID:          uploadID,
 		UploadTaskStatus:  string(task.Status),
 		UploadTaskMessage: task.Message,
+		QueuePosition:     task.QueuePosition,
+		OpeningBalance:    task.OpeningBalance,
+		ClosingBalance:    task.ClosingBalance,
+		BalanceCheck:      task.BalanceCheck,
+		Account:           task.Account,
+		DedupPolicy:       task.DedupPolicy,
+		DuplicateRows:     task.DuplicateRows,
 	}
 
-	if task.Status != upload.StatusCompleted {
+	if !task.Status.IsCompleted() {
 		return response, nil
 	}
 
-	b := g.transactionRepo.GetBalanceByUploadID(ctx, upload.ID(uploadID))
-	response.Balance = &b
+	response.Balances = g.transactionRepo.GetBalancesByUploadID(ctx, upload.ID(uploadID))
+	switch len(response.Balances) {
+	case 0:
+		var b int64
+		response.Balance = &b
+	case 1:
+		b := response.Balances[0].Amount
+		response.Balance = &b
+	}
+
+	if reportingCurrency != "" {
+		response.Reporting, err = g.consolidate(ctx, upload.ID(uploadID), reportingCurrency)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return response, nil
 }
+
+// consolidate converts the transactions of an upload that count towards its balance at the rate of their own
+// day and adds them up.
+func (g *balance) consolidate(ctx context.Context, uploadID upload.ID, reportingCurrency money.Currency) (*ReportingBalance, error) {
+	return consolidate(ctx, g.rateRepo, g.transactionRepo.GetByUploadID(ctx, uploadID), reportingCurrency)
+}
+
+// consolidate converts the transactions that count towards the balance at the rate of their own day and adds them up.
+func consolidate(ctx context.Context, rateRepo repository.FXRateRepository, transactions []*transaction.Transaction, reportingCurrency money.Currency) (*ReportingBalance, error) {
+	c := newConverter(rateRepo, reportingCurrency)
+	total := money.New(0, reportingCurrency)
+	for _, t := range transactions {
+		if !t.CountsTowardsBalance() {
+			continue
+		}
+
+		converted, err := c.convert(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+
+		if t.Type == transaction.TypeCredit {
+			total, err = total.Add(converted)
+		} else if t.Type == transaction.TypeDebit {
+			total, err = total.Sub(converted)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &ReportingBalance{
+		Balance: total,
+		Rates:   c.rates(),
+	}, nil
+}
@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"fmt"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+)
+
+// statementNet adds up the successful transactions of a statement per currency. Duplicates left out by
+// deduplication count as well, they are still part of the statement.
+type statementNet map[money.Currency]money.Money
+
+func (n statementNet) add(t *transaction.Transaction) error {
+	if t.Status != transaction.StatusSuccess {
+		return nil
+	}
+
+	net, ok := n[t.Currency]
+	if !ok {
+		net = money.New(0, t.Currency)
+	}
+
+	var err error
+	if t.Type == transaction.TypeCredit {
+		net, err = net.Add(t.Money())
+	} else if t.Type == transaction.TypeDebit {
+		net, err = net.Sub(t.Money())
+	}
+	if err != nil {
+		return err
+	}
+	n[t.Currency] = net
+	return nil
+}
+
+// checkBalance compares the closing balance of a statement with its opening balance plus the net of its
+// transactions in the currency of the opening balance. It returns nil when either balance is not known.
+func checkBalance(opening, closing *upload.Balance, net statementNet) (*upload.BalanceCheck, error) {
+	if opening == nil || closing == nil {
+		return nil, nil
+	}
+	if opening.Currency != closing.Currency {
+		return nil, fmt.Errorf("%w: opening balance in %s, closing balance in %s", money.ErrCurrencyMismatch, opening.Currency, closing.Currency)
+	}
+
+	currencyNet, ok := net[opening.Currency]
+	if !ok {
+		currencyNet = money.New(0, opening.Currency)
+	}
+	computed, err := money.New(opening.Amount, opening.Currency).Add(currencyNet)
+	if err != nil {
+		return nil, err
+	}
+	discrepancy, err := computed.Sub(money.New(closing.Amount, closing.Currency))
+	if err != nil {
+		return nil, err
+	}
+
+	return &upload.BalanceCheck{
+		Currency:        opening.Currency,
+		Opening:         opening.Amount,
+		Net:             currencyNet.Amount,
+		ExpectedClosing: closing.Amount,
+		Discrepancy:     discrepancy.Amount,
+	}, nil
+}
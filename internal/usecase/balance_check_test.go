@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+)
+
+func Test_checkBalance(t *testing.T) {
+	transactions := []*transaction.Transaction{
+		{Type: transaction.TypeCredit, Amount: 500000, Currency: "IDR", Status: transaction.StatusSuccess},
+		{Type: transaction.TypeDebit, Amount: 250000, Currency: "IDR", Status: transaction.StatusSuccess},
+		{Type: transaction.TypeDebit, Amount: 75000, Currency: "IDR", Status: transaction.StatusFailed},
+		{Type: transaction.TypeCredit, Amount: 1000, Currency: "USD", Status: transaction.StatusSuccess},
+	}
+	net := statementNet{}
+	for _, tx := range transactions {
+		if err := net.add(tx); err != nil {
+			t.Fatalf("add() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		opening *upload.Balance
+		closing *upload.Balance
+		want    *upload.BalanceCheck
+		wantErr error
+	}{
+		{
+			name:    "it should balance when opening plus the net of successful transactions is the closing balance",
+			opening: &upload.Balance{Amount: 1000000, Currency: "IDR"},
+			closing: &upload.Balance{Amount: 1250000, Currency: "IDR"},
+			want:    &upload.BalanceCheck{Currency: "IDR", Opening: 1000000, Net: 250000, ExpectedClosing: 1250000},
+		},
+		{
+			name:    "it should report the discrepancy when transactions are missing",
+			opening: &upload.Balance{Amount: 1000000, Currency: "IDR"},
+			closing: &upload.Balance{Amount: 1400000, Currency: "IDR"},
+			want:    &upload.BalanceCheck{Currency: "IDR", Opening: 1000000, Net: 250000, ExpectedClosing: 1400000, Discrepancy: -150000},
+		},
+		{
+			name:    "it should check a currency without transactions against a net of zero",
+			opening: &upload.Balance{Amount: 300, Currency: "EUR"},
+			closing: &upload.Balance{Amount: 300, Currency: "EUR"},
+			want:    &upload.BalanceCheck{Currency: "EUR", Opening: 300, ExpectedClosing: 300},
+		},
+		{
+			name:    "it should not check without a closing balance",
+			opening: &upload.Balance{Amount: 1000000, Currency: "IDR"},
+		},
+		{
+			name:    "it should return error when the balances are in different currencies",
+			opening: &upload.Balance{Amount: 1000000, Currency: "IDR"},
+			closing: &upload.Balance{Amount: 1000, Currency: "USD"},
+			wantErr: money.ErrCurrencyMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checkBalance(tt.opening, tt.closing, net)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("checkBalance() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkBalance() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+)
+
+// cancellations holds the context of every upload being processed, so that a single upload can be
+// cancelled until its transactions are about to be committed.
+type cancellations struct {
+	mu      sync.Mutex
+	uploads map[upload.ID]*cancellation
+}
+
+type cancellation struct {
+	cancel context.CancelCauseFunc
+	// settled is set once the upload is committed or discarded, cancelling it is too late then
+	settled bool
+}
+
+func newCancellations() *cancellations {
+	return &cancellations{
+		uploads: make(map[upload.ID]*cancellation),
+	}
+}
+
+// register returns the context an upload is processed with. It is cancelled with ErrUploadCancelled
+// when the upload is cancelled, and has to be released once processing ends.
+func (c *cancellations) register(parent context.Context, uploadID upload.ID) context.Context {
+	ctx, cancel := context.WithCancelCause(parent)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.uploads[uploadID] = &cancellation{cancel: cancel}
+	return ctx
+}
+
+// cancel cancels an upload that has not been settled and reports whether it did.
+func (c *cancellations) cancel(uploadID upload.ID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.uploads[uploadID]
+	if !ok || entry.settled {
+		return false
+	}
+	delete(c.uploads, uploadID)
+	entry.cancel(ErrUploadCancelled)
+	return true
+}
+
+// settle ends the time an upload can be cancelled. It returns false when the upload was cancelled first.
+func (c *cancellations) settle(uploadID upload.ID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.uploads[uploadID]
+	if !ok {
+		return false
+	}
+	entry.settled = true
+	return true
+}
+
+// release frees the context of an upload once processing has ended.
+func (c *cancellations) release(uploadID upload.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.uploads[uploadID]; ok {
+		delete(c.uploads, uploadID)
+		entry.cancel(nil)
+	}
+}
+
+func (uc *statement) Cancel(ctx context.Context, uploadID string) error {
+	task, err := uc.uploadRepo.GetByID(ctx, upload.ID(uploadID))
+	if err != nil {
+		return ErrUploadNotFound
+	}
+
+	if !uc.cancellations.cancel(task.ID) {
+		return ErrUploadFinished
+	}
+	return nil
+}
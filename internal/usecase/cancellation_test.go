@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+	"github.com/mj3smile/bank-statement-processor/internal/repository"
+	"github.com/mj3smile/bank-statement-processor/internal/repository/memory"
+)
+
+func Test_cancellations(t *testing.T) {
+	tests := []struct {
+		name string
+		// before runs on the registered upload before it is cancelled
+		before      func(c *cancellations, id upload.ID)
+		wantCancel  bool
+		wantSettled bool
+	}{
+		{
+			name:        "it should cancel an upload being processed",
+			before:      func(c *cancellations, id upload.ID) {},
+			wantCancel:  true,
+			wantSettled: false,
+		},
+		{
+			name:        "it should not cancel an upload about to be committed",
+			before:      func(c *cancellations, id upload.ID) { c.settle(id) },
+			wantCancel:  false,
+			wantSettled: true,
+		},
+		{
+			name:        "it should not cancel an upload that has ended",
+			before:      func(c *cancellations, id upload.ID) { c.release(id) },
+			wantCancel:  false,
+			wantSettled: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCancellations()
+			ctx := c.register(context.Background(), "upload-1")
+			tt.before(c, "upload-1")
+
+			if got := c.cancel("upload-1"); got != tt.wantCancel {
+				t.Errorf("cancel() = %v, want %v", got, tt.wantCancel)
+			}
+			if got := errors.Is(context.Cause(ctx), ErrUploadCancelled); got != tt.wantCancel {
+				t.Errorf("context cancelled with ErrUploadCancelled = %v, want %v", got, tt.wantCancel)
+			}
+			if got := c.settle("upload-1"); got != tt.wantSettled {
+				t.Errorf("settle() after cancel() = %v, want %v", got, tt.wantSettled)
+			}
+		})
+	}
+}
+
+// cancellingUploadRepository cancels every upload as soon as it has been saved.
+type cancellingUploadRepository struct {
+	repository.UploadRepository
+	uc        Statement
+	cancelled chan cancelled
+}
+
+type cancelled struct {
+	uploadID upload.ID
+	err      error
+}
+
+func (r *cancellingUploadRepository) Save(ctx context.Context, task *upload.Task) error {
+	if err := r.UploadRepository.Save(ctx, task); err != nil {
+		return err
+	}
+	r.cancelled <- cancelled{uploadID: task.ID, err: r.uc.Cancel(ctx, string(task.ID))}
+	return nil
+}
+
+func Test_statement_Cancel_AsSoonAsSaved(t *testing.T) {
+	content := "1674507883, JOHN DOE, DEBIT, 250000, SUCCESS, restaurant\n"
+
+	var archive bytes.Buffer
+	w := zip.NewWriter(&archive)
+	entry, _ := w.Create("statement.csv")
+	entry.Write([]byte(content))
+	w.Close()
+
+	tests := []struct {
+		name     string
+		filename string
+		content  []byte
+	}{
+		{name: "it should cancel an upload the moment it can be found", filename: "statement.csv", content: []byte(content)},
+		{name: "it should cancel a statement of an archive the moment it can be found", filename: "statements.zip", content: archive.Bytes()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			uploadRepo := &cancellingUploadRepository{UploadRepository: memory.NewUploadRepository(), cancelled: make(chan cancelled, 1)}
+			uploadRepo.uc = newTestStatement(ctx, memory.NewTransactionRepository(), uploadRepo)
+
+			if _, err := uploadRepo.uc.Upload(ctx, io.NopCloser(bytes.NewReader(tt.content)), tt.filename, UploadOptions{}); err != nil {
+				t.Fatalf("Upload() error = %v", err)
+			}
+
+			var got cancelled
+			select {
+			case got = <-uploadRepo.cancelled:
+			case <-time.After(5 * time.Second):
+				t.Fatal("upload was never saved")
+			}
+			if got.err != nil {
+				t.Errorf("Cancel() error = %v, want nil", got.err)
+			}
+			if task := waitForUpload(t, uploadRepo, got.uploadID); task.Status != upload.StatusCancelled {
+				t.Errorf("Upload() status = %v (%s), want %v", task.Status, task.Message, upload.StatusCancelled)
+			}
+		})
+	}
+}
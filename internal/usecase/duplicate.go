@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/infra/log"
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+	"github.com/mj3smile/bank-statement-processor/internal/parser"
+)
+
+// DuplicatePolicy decides what an upload of already processed content answers and how it ends.
+type DuplicatePolicy string
+
+const (
+	// DuplicateRecord is the default, the upload is answered right away and its content is only known to
+	// be a duplicate once the whole file has been read. It then ends up with StatusDuplicate naming the
+	// upload that processed the content first
+	DuplicateRecord DuplicatePolicy = ""
+	// DuplicateReturnExisting hashes the file before answering and returns the upload that processed the
+	// content first instead of starting another
+	DuplicateReturnExisting DuplicatePolicy = "existing"
+	// DuplicateReject hashes the file before answering and fails with a DuplicateUploadError
+	DuplicateReject DuplicatePolicy = "reject"
+)
+
+// DuplicateUploadError is returned for uploads of already processed content under DuplicateReject.
+type DuplicateUploadError struct {
+	UploadID upload.ID
+}
+
+func (e *DuplicateUploadError) Error() string {
+	return fmt.Sprintf("%v as upload %s", ErrDuplicateUpload, e.UploadID)
+}
+
+func (e *DuplicateUploadError) Unwrap() error {
+	return ErrDuplicateUpload
+}
+
+// answerDuplicate hashes the spooled file of a single statement before the upload is answered and returns
+// the answer when an earlier upload claimed the content: that upload under DuplicateReturnExisting, a
+// DuplicateUploadError under DuplicateReject. Both are nil when the content is new. An upload of the same
+// content that is answered at the same time is still found once the file has been processed.
+func (uc *statement) answerDuplicate(ctx context.Context, file *spooledFile, archive parser.Archive, policy DuplicatePolicy) (*UploadResult, error) {
+	contentHash, err := hashStatement(file.Name(), archive, newUnpackLimit(uc.archiveLimits.MaxSize))
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := uc.uploadRepo.GetByContentHash(ctx, contentHash)
+	if err != nil {
+		return nil, nil
+	}
+	if policy == DuplicateReject {
+		return nil, &DuplicateUploadError{UploadID: original.ID}
+	}
+	return &UploadResult{UploadID: original.ID, Duplicate: true}, nil
+}
+
+// hashStatement computes the content hash of the statement in a file the way processing does, after
+// decompressing a gzip compressed one. The file is read on its own, leaving whatever else reads it alone.
+func hashStatement(name string, archive parser.Archive, limit *unpackLimit) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if archive == parser.ArchiveGzip {
+		decompressed, err := gzip.NewReader(r)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		r = limit.Reader(decompressed)
+	}
+
+	contentHash := sha256.New()
+	if _, err := io.Copy(contentHash, r); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return hex.EncodeToString(contentHash.Sum(nil)), nil
+}
+
+// claimContent claims the hash of a statement read to the end for its upload. An upload whose content
+// another upload claimed first is marked as its duplicate, and false is returned when processing stops.
+func (uc *statement) claimContent(ctx context.Context, prepared *preparedUpload) (string, bool) {
+	uploadID := prepared.task.ID
+	contentHash := hex.EncodeToString(prepared.contentHash.Sum(nil))
+	owner, err := uc.uploadRepo.ClaimContentHash(ctx, contentHash, uploadID)
+	if err != nil {
+		uc.markUploadAsFailed(ctx, uploadID, fmt.Sprintf("failed to check for duplicates: %v", err))
+		return "", false
+	}
+	if owner != uploadID {
+		uc.markUploadAsDuplicate(ctx, uploadID, contentHash, owner, prepared.onDuplicate)
+		return "", false
+	}
+	return contentHash, true
+}
+
+// releaseContent gives up the claim of an upload that fails after claiming its content, so that the
+// content is not taken for a duplicate of an upload that kept nothing.
+func (uc *statement) releaseContent(ctx context.Context, contentHash string, uploadID upload.ID) {
+	if err := uc.uploadRepo.ReleaseContentHash(ctx, contentHash, uploadID); err != nil {
+		log.Info(ctx, fmt.Sprint("failed to release content hash:", err.Error()))
+	}
+}
+
+// markUploadAsDuplicate discards the staged transactions of an upload whose content was processed before.
+// Under DuplicateReject the upload fails instead of ending as a duplicate.
+func (uc *statement) markUploadAsDuplicate(ctx context.Context, uploadID upload.ID, contentHash string, original upload.ID, policy DuplicatePolicy) {
+	if err := uc.transactionRepo.Rollback(ctx, uploadID); err != nil {
+		log.Info(ctx, fmt.Sprint("failed to roll back upload transactions:", err.Error()))
+	}
+
+	info := &upload.Task{
+		ID:          uploadID,
+		Status:      upload.StatusDuplicate,
+		Message:     fmt.Sprintf("statement was already uploaded as %s", original),
+		ContentHash: contentHash,
+		DuplicateOf: original,
+		CompletedAt: time.Now(),
+	}
+	if policy == DuplicateReject {
+		info.Status, info.Message = upload.StatusFailed, fmt.Sprintf("%v as %s", ErrDuplicateUpload, original)
+	}
+	if err := uc.uploadRepo.Update(ctx, info); err != nil {
+		log.Info(ctx, fmt.Sprint("failed to mark upload as duplicate:", err.Error()))
+	}
+}
+
+// dedupOutcomes describes what each policy did to the duplicates of an upload
+var dedupOutcomes = map[upload.DedupPolicy]string{
+	upload.DedupSkip: "skipped",
+	upload.DedupFlag: "flagged",
+	upload.DedupKeep: "kept",
+}
+
+// deduplicate fingerprints a transaction of an account and returns the decision taken when an earlier
+// upload of the account committed it already, nil otherwise. Rows repeated within the statement and rows of
+// uploads still being processed are not duplicates. Flagged duplicates are marked with the transaction they
+// repeat. The decision is only recorded once the upload is committed.
+func (uc *statement) deduplicate(ctx context.Context, task *upload.Task, line int, t *transaction.Transaction) *upload.DedupDecision {
+	t.Fingerprint = transaction.Fingerprint(task.Account, t)
+	original := uc.transactionRepo.GetByFingerprint(ctx, t.Fingerprint)
+	if original == nil {
+		return nil
+	}
+
+	decision := &upload.DedupDecision{
+		Line:              line,
+		Action:            task.DedupPolicy,
+		DuplicateOf:       string(original.ID),
+		DuplicateOfUpload: original.UploadID,
+		Fingerprint:       t.Fingerprint,
+	}
+	if task.DedupPolicy != upload.DedupSkip {
+		decision.TransactionID = string(t.ID)
+	}
+	if task.DedupPolicy == upload.DedupFlag {
+		t.DuplicateOf = original.ID
+	}
+	return decision
+}
@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/event"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+	"github.com/mj3smile/bank-statement-processor/internal/parser"
+	"github.com/mj3smile/bank-statement-processor/internal/repository"
+	"github.com/mj3smile/bank-statement-processor/internal/repository/memory"
+)
+
+// failingCommitRepository fails the first commit it is asked for.
+type failingCommitRepository struct {
+	repository.TransactionRepository
+	failed bool
+}
+
+func (r *failingCommitRepository) Commit(ctx context.Context, uploadID upload.ID) error {
+	if !r.failed {
+		r.failed = true
+		return errors.New("storage unavailable")
+	}
+	return r.TransactionRepository.Commit(ctx, uploadID)
+}
+
+func Test_statement_Upload_ReleasesContentOnFailedCommit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	uploadRepo := memory.NewUploadRepository()
+	uc := newTestStatement(ctx, &failingCommitRepository{TransactionRepository: memory.NewTransactionRepository()}, uploadRepo)
+	content := "1674507883, JOHN DOE, DEBIT, 250000, SUCCESS, restaurant\n"
+
+	tests := []struct {
+		name       string
+		wantStatus upload.Status
+	}{
+		{
+			name:       "it should fail the upload whose transactions could not be committed",
+			wantStatus: upload.StatusFailed,
+		},
+		{
+			name:       "it should process the same content again instead of taking it for a duplicate",
+			wantStatus: upload.StatusCompleted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := uc.Upload(ctx, io.NopCloser(strings.NewReader(content)), "statement.csv", UploadOptions{})
+			if err != nil {
+				t.Fatalf("Upload() error = %v", err)
+			}
+
+			task := waitForUpload(t, uploadRepo, result.UploadID)
+			if task.Status != tt.wantStatus {
+				t.Errorf("Upload() status = %v (%s), want %v", task.Status, task.Message, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func Test_statement_Upload_KeepsRepeatedRowsWithinStatement(t *testing.T) {
+	content := `timestamp,counterparty,type,amount,status,description
+1674507883,JOHN DOE,DEBIT,250000,SUCCESS,restaurant
+1674508123,ACME CORP,CREDIT,1500000,SUCCESS,salary
+1674507883,John Doe,DEBIT,250000,SUCCESS,restaurant
+`
+	tests := []struct {
+		name  string
+		dedup upload.DedupPolicy
+	}{
+		{name: "it should keep a row repeated further down the statement under skip", dedup: upload.DedupSkip},
+		{name: "it should not flag a row repeated further down the statement", dedup: upload.DedupFlag},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			transactionRepo := memory.NewTransactionRepository()
+			uploadRepo := memory.NewUploadRepository()
+			uc := newTestStatement(ctx, transactionRepo, uploadRepo)
+
+			result, err := uc.Upload(ctx, io.NopCloser(strings.NewReader(content)), "statement.csv", UploadOptions{Account: "acc-1", Dedup: tt.dedup})
+			if err != nil {
+				t.Fatalf("Upload() error = %v", err)
+			}
+
+			task := waitForUpload(t, uploadRepo, result.UploadID)
+			if task.Status != upload.StatusCompleted || task.DuplicateRows != 0 {
+				t.Errorf("Upload() status = %v, duplicate rows = %v (%s), want %v, 0", task.Status, task.DuplicateRows, task.Message, upload.StatusCompleted)
+			}
+			transactions := transactionRepo.GetByUploadID(ctx, result.UploadID)
+			if len(transactions) != 3 {
+				t.Fatalf("GetByUploadID() = %v transactions, want 3", len(transactions))
+			}
+			for _, tx := range transactions {
+				if tx.DuplicateOf != "" {
+					t.Errorf("GetByUploadID() transaction %v duplicate of %v, want none", tx.ID, tx.DuplicateOf)
+				}
+			}
+
+			if decisions, total, _ := uploadRepo.GetDedupDecisions(ctx, result.UploadID, 1, 10); total != 0 {
+				t.Errorf("GetDedupDecisions() = %+v, want none", decisions)
+			}
+		})
+	}
+}
+
+func newTestStatement(ctx context.Context, transactionRepo repository.TransactionRepository, uploadRepo repository.UploadRepository) Statement {
+	return NewStatement(ctx, StatementConfig{
+		TransactionRepo: transactionRepo,
+		UploadRepo:      uploadRepo,
+		BatchRepo:       memory.NewBatchRepository(),
+		AccountRepo:     memory.NewAccountRepository(),
+		ProfileRepo:     memory.NewProfileRepository(),
+		EventBus:        event.NewBus(ctx),
+		Parsers:         parser.NewDefaultRegistry(),
+		Rules:           NewRules(""),
+	})
+}
+
+// waitForUpload waits until an upload has finished processing and returns it.
+func waitForUpload(t *testing.T, uploadRepo repository.UploadRepository, uploadID upload.ID) *upload.Task {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		task, err := uploadRepo.GetByID(context.Background(), uploadID)
+		if err == nil && task.Status != upload.StatusProcessing && task.Status != upload.StatusQueued {
+			return task
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("upload %s is still processing", uploadID)
+	return nil
+}
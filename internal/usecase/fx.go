@@ -0,0 +1,185 @@
+package usecase
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/fx"
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+	"github.com/mj3smile/bank-statement-processor/internal/repository"
+)
+
+var (
+	ErrInvalidRates = errors.New("invalid rates")
+	ErrRateNotFound = errors.New("rate not found")
+)
+
+// rateColumns are the columns of a rates file, in any order
+var rateColumns = []string{"date", "base", "quote", "rate"}
+
+type FX interface {
+	// LoadRates reads a CSV of daily rates with the columns date (YYYY-MM-DD), base, quote and rate.
+	// Nothing is stored when a row is invalid.
+	LoadRates(ctx context.Context, r io.Reader) (int, error)
+}
+
+type fxUseCase struct {
+	rateRepo repository.FXRateRepository
+}
+
+func NewFX(rateRepo repository.FXRateRepository) FX {
+	return &fxUseCase{
+		rateRepo: rateRepo,
+	}
+}
+
+func (uc *fxUseCase) LoadRates(ctx context.Context, r io.Reader) (int, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("%w: failed to read header: %v", ErrInvalidRates, err)
+	}
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, column := range rateColumns {
+		if _, ok := positions[column]; !ok {
+			return 0, fmt.Errorf("%w: missing column %s", ErrInvalidRates, column)
+		}
+	}
+
+	var rates []*fx.Rate
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidRates, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		rate, err := parseRate(row, positions)
+		if err != nil {
+			return 0, fmt.Errorf("%w: line %d: %v", ErrInvalidRates, line, err)
+		}
+		rates = append(rates, rate)
+	}
+
+	if err := uc.rateRepo.SaveAll(ctx, rates); err != nil {
+		return 0, err
+	}
+
+	return len(rates), nil
+}
+
+func parseRate(row []string, positions map[string]int) (*fx.Rate, error) {
+	value := func(column string) string {
+		return strings.TrimSpace(row[positions[column]])
+	}
+
+	date, err := time.Parse(time.DateOnly, value("date"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid date '%s'", value("date"))
+	}
+
+	base, err := money.ParseCurrency(value("base"))
+	if err != nil {
+		return nil, err
+	}
+	quote, err := money.ParseCurrency(value("quote"))
+	if err != nil {
+		return nil, err
+	}
+	if base == quote {
+		return nil, fmt.Errorf("base and quote are both %s", base)
+	}
+
+	rate, ok := new(big.Rat).SetString(value("rate"))
+	if !ok || rate.Sign() <= 0 {
+		return nil, fmt.Errorf("rate must be a positive number, got '%s'", value("rate"))
+	}
+
+	return &fx.Rate{
+		Date:  date,
+		Base:  base,
+		Quote: quote,
+		Value: rate,
+	}, nil
+}
+
+// converter translates transaction amounts into a reporting currency at the rate in effect on the
+// day of each transaction, and keeps track of the rates it used.
+type converter struct {
+	rateRepo repository.FXRateRepository
+	to       money.Currency
+	used     map[rateKey]*fx.Rate
+}
+
+type rateKey struct {
+	date  time.Time
+	base  money.Currency
+	quote money.Currency
+}
+
+func newConverter(rateRepo repository.FXRateRepository, to money.Currency) *converter {
+	return &converter{
+		rateRepo: rateRepo,
+		to:       to,
+		used:     make(map[rateKey]*fx.Rate),
+	}
+}
+
+func (c *converter) convert(ctx context.Context, t *transaction.Transaction) (money.Money, error) {
+	if t.Currency == c.to {
+		return t.Money(), nil
+	}
+
+	at := time.Unix(t.Timestamp, 0).UTC()
+	rate, err := c.rateRepo.GetRate(ctx, t.Currency, c.to, at)
+	if err != nil {
+		// a rate of the opposite direction works as well
+		inverse, inverseErr := c.rateRepo.GetRate(ctx, c.to, t.Currency, at)
+		if inverseErr != nil {
+			return money.Money{}, fmt.Errorf("%w: %s to %s on %s", ErrRateNotFound, t.Currency, c.to, at.Format(time.DateOnly))
+		}
+		rate = inverse.Inverse()
+	}
+
+	converted, err := t.Money().Convert(c.to, rate.Value)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	c.used[rateKey{date: rate.Date, base: rate.Base, quote: rate.Quote}] = rate
+	return converted, nil
+}
+
+// rates returns the rates used so far ordered by date and currency pair.
+func (c *converter) rates() []*fx.Rate {
+	rates := make([]*fx.Rate, 0, len(c.used))
+	for _, rate := range c.used {
+		rates = append(rates, rate)
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		if !rates[i].Date.Equal(rates[j].Date) {
+			return rates[i].Date.Before(rates[j].Date)
+		}
+		if rates[i].Base != rates[j].Base {
+			return rates[i].Base < rates[j].Base
+		}
+		return rates[i].Quote < rates[j].Quote
+	})
+	return rates
+}
@@ -0,0 +1,228 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultIngestionWorkers is the number of statements processed at once when none is configured
+	DefaultIngestionWorkers = 4
+	// DefaultIngestionQueueSize is the number of uploads that may wait for a worker when none is configured
+	DefaultIngestionQueueSize = 100
+	// defaultRetryAfter is suggested to uploads turned down before any statement has been processed
+	defaultRetryAfter = 10 * time.Second
+)
+
+var ErrIngestionQueueFull = errors.New("too many uploads are waiting to be processed")
+
+// IngestionConfig bounds how many statements are processed at the same time and how many uploads
+// may wait for their turn.
+type IngestionConfig struct {
+	// Workers is DefaultIngestionWorkers when zero or less
+	Workers int
+	// QueueSize is DefaultIngestionQueueSize when zero or less
+	QueueSize int
+}
+
+// QueueFullError is returned for uploads turned down because the ingestion queue is full.
+type QueueFullError struct {
+	// RetryAfter estimates when a place in the queue frees up
+	RetryAfter time.Duration
+}
+
+func (e *QueueFullError) Error() string {
+	return fmt.Sprintf("%v, retry after %v", ErrIngestionQueueFull, e.RetryAfter)
+}
+
+func (e *QueueFullError) Unwrap() error {
+	return ErrIngestionQueueFull
+}
+
+// ingestionQueue hands out a fixed number of workers to uploads in the order they arrived. Uploads
+// that find every worker busy wait in a bounded queue, uploads that find the queue full are turned down.
+type ingestionQueue struct {
+	mu        sync.Mutex
+	workers   int
+	queueSize int
+	running   int
+	waiting   []*admission
+	// averageRun is a moving average of how long processing takes, it estimates when to retry
+	averageRun time.Duration
+}
+
+// admission is the place of an upload, either holding a worker or waiting for one.
+type admission struct {
+	// ready is closed once the admission holds a worker
+	ready   chan struct{}
+	started time.Time
+	// moved is told the position in the queue each time it changes
+	moved func(position int)
+}
+
+func newIngestionQueue(config IngestionConfig) *ingestionQueue {
+	if config.Workers <= 0 {
+		config.Workers = DefaultIngestionWorkers
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultIngestionQueueSize
+	}
+	return &ingestionQueue{
+		workers:   config.Workers,
+		queueSize: config.QueueSize,
+	}
+}
+
+// admit takes a free worker, or a place at the end of the queue when every worker is busy.
+func (q *ingestionQueue) admit() (*admission, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	a := &admission{ready: make(chan struct{})}
+	if q.running < q.workers {
+		q.running++
+		close(a.ready)
+		return a, nil
+	}
+	if len(q.waiting) >= q.queueSize {
+		return nil, &QueueFullError{RetryAfter: q.retryAfter()}
+	}
+
+	q.waiting = append(q.waiting, a)
+	return a, nil
+}
+
+// position returns the 1-based place of an admission in the queue, 0 once it holds a worker.
+func (q *ingestionQueue) position(a *admission) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.indexOf(a) + 1
+}
+
+// follow calls moved with the current position of a waiting admission and again whenever it changes.
+// moved runs with the queue locked, so positions are reported in order.
+func (q *ingestionQueue) follow(a *admission, moved func(position int)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	a.moved = moved
+	if i := q.indexOf(a); i >= 0 {
+		moved(i + 1)
+	}
+}
+
+// wait blocks until the admission holds a worker. It gives up the place in the queue and returns
+// false when ctx is done first.
+func (q *ingestionQueue) wait(ctx context.Context, a *admission) bool {
+	select {
+	case <-a.ready:
+		a.started = time.Now()
+		return true
+	case <-ctx.Done():
+		q.withdraw(a)
+		return false
+	}
+}
+
+// done hands the worker of a processed admission on to the first one waiting.
+func (q *ingestionQueue) done(a *admission) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	took := time.Since(a.started)
+	if q.averageRun == 0 {
+		q.averageRun = took
+	} else {
+		q.averageRun = (4*q.averageRun + took) / 5
+	}
+	q.release()
+}
+
+// withdraw gives up an admission that is not going to be processed, whether it waits or holds a worker.
+func (q *ingestionQueue) withdraw(a *admission) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := q.indexOf(a)
+	if i < 0 {
+		q.release()
+		return
+	}
+	q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+	q.moved(i)
+}
+
+// release passes a worker to the first admission waiting, or frees it when nobody waits.
+func (q *ingestionQueue) release() {
+	if len(q.waiting) == 0 {
+		q.running--
+		return
+	}
+
+	next := q.waiting[0]
+	q.waiting = q.waiting[1:]
+	close(next.ready)
+	q.moved(0)
+}
+
+// moved reports the new positions of the admissions from index from onwards.
+func (q *ingestionQueue) moved(from int) {
+	for i := from; i < len(q.waiting); i++ {
+		if q.waiting[i].moved != nil {
+			q.waiting[i].moved(i + 1)
+		}
+	}
+}
+
+func (q *ingestionQueue) indexOf(a *admission) int {
+	for i, waiting := range q.waiting {
+		if waiting == a {
+			return i
+		}
+	}
+	return -1
+}
+
+// retryAfter estimates how long until a worker takes the first upload off the queue.
+func (q *ingestionQueue) retryAfter() time.Duration {
+	if q.averageRun == 0 {
+		return defaultRetryAfter
+	}
+	return max((q.averageRun / time.Duration(q.workers)).Round(time.Second), time.Second)
+}
+
+// spooledFile is the file of a queued upload kept on disk, it is removed once closed.
+type spooledFile struct {
+	*os.File
+}
+
+func (f *spooledFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
+
+// spool reads the rest of file into a temporary file and closes it, so that the request it arrives with can end.
+func spool(file io.ReadCloser) (io.ReadCloser, error) {
+	defer file.Close()
+
+	spooled, err := os.CreateTemp("", "statement-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to spool file: %w", err)
+	}
+	f := &spooledFile{File: spooled}
+	if _, err := io.Copy(spooled, file); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if _, err := spooled.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to spool file: %w", err)
+	}
+	return f, nil
+}
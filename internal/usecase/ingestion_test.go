@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_ingestionQueue(t *testing.T) {
+	tests := []struct {
+		name string
+		// run admits uploads and returns the positions they report, in the order they moved
+		run        func(t *testing.T, q *ingestionQueue) []int
+		want       []int
+		wantQueued int
+	}{
+		{
+			name: "it should hand out free workers and queue the uploads after them",
+			run: func(t *testing.T, q *ingestionQueue) []int {
+				var positions []int
+				for range 4 {
+					a, err := q.admit()
+					if err != nil {
+						t.Fatalf("admit() error = %v", err)
+					}
+					positions = append(positions, q.position(a))
+				}
+				return positions
+			},
+			want:       []int{0, 0, 1, 2},
+			wantQueued: 2,
+		},
+		{
+			name: "it should move the queue up when a worker is done",
+			run: func(t *testing.T, q *ingestionQueue) []int {
+				first, _ := q.admit()
+				q.admit()
+				var moves []int
+				for range 2 {
+					a, _ := q.admit()
+					q.follow(a, func(position int) { moves = append(moves, position) })
+				}
+
+				q.wait(context.Background(), first)
+				q.done(first)
+				return moves
+			},
+			want:       []int{1, 2, 1},
+			wantQueued: 1,
+		},
+		{
+			name: "it should move the queue up when an upload gives up its place",
+			run: func(t *testing.T, q *ingestionQueue) []int {
+				q.admit()
+				q.admit()
+				waiting, _ := q.admit()
+				last, _ := q.admit()
+
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				if q.wait(ctx, waiting) {
+					t.Errorf("wait() = true, want false for a cancelled context")
+				}
+				return []int{q.position(last)}
+			},
+			want:       []int{1},
+			wantQueued: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newIngestionQueue(IngestionConfig{Workers: 2, QueueSize: 2})
+			if got := tt.run(t, q); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("positions = %v, want %v", got, tt.want)
+			}
+			if len(q.waiting) != tt.wantQueued {
+				t.Errorf("queued = %d, want %d", len(q.waiting), tt.wantQueued)
+			}
+		})
+	}
+}
+
+func Test_ingestionQueue_admit_QueueFull(t *testing.T) {
+	q := newIngestionQueue(IngestionConfig{Workers: 1, QueueSize: 1})
+	first, _ := q.admit()
+	q.admit()
+
+	_, err := q.admit()
+	var queueFullErr *QueueFullError
+	if !errors.As(err, &queueFullErr) || !errors.Is(err, ErrIngestionQueueFull) {
+		t.Fatalf("admit() error = %v, want a QueueFullError", err)
+	}
+	if queueFullErr.RetryAfter != defaultRetryAfter {
+		t.Errorf("RetryAfter = %v, want %v before anything was processed", queueFullErr.RetryAfter, defaultRetryAfter)
+	}
+
+	q.wait(context.Background(), first)
+	first.started = time.Now().Add(-3 * time.Second)
+	q.done(first)
+	if _, err := q.admit(); err != nil {
+		t.Fatalf("admit() error = %v after the queue moved up", err)
+	}
+	_, err = q.admit()
+	if !errors.As(err, &queueFullErr) || queueFullErr.RetryAfter != 3*time.Second {
+		t.Errorf("admit() error = %v, want a QueueFullError to retry after %v", err, 3*time.Second)
+	}
+}
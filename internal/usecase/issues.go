@@ -4,33 +4,43 @@ import (
 	"context"
 	"errors"
 
+	"github.com/mj3smile/bank-statement-processor/internal/model/fx"
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
 	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
 	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
 	"github.com/mj3smile/bank-statement-processor/internal/repository"
 )
 
 type Issues interface {
-	GetIssues(ctx context.Context, filters *transaction.IssuesFilters) (*IssuesResult, error)
+	// GetIssues lists the problematic transactions of an upload, with their amounts converted
+	// into reportingCurrency as well unless it is empty
+	GetIssues(ctx context.Context, filters *transaction.IssuesFilters, reportingCurrency money.Currency) (*IssuesResult, error)
 }
 
 type issues struct {
 	transactionRepo repository.TransactionRepository
 	uploadRepo      repository.UploadRepository
+	rateRepo        repository.FXRateRepository
 }
 
+// IssuesResult holds a page of transactions. When a reporting currency was requested,
+// ReportingAmounts has the converted amount of each transaction at the same index.
 type IssuesResult struct {
-	Transactions []*transaction.Transaction
-	TotalCount   int
+	Transactions     []*transaction.Transaction
+	TotalCount       int
+	ReportingAmounts []money.Money
+	Rates            []*fx.Rate
 }
 
-func NewIssues(transactionRepo repository.TransactionRepository, uploadRepo repository.UploadRepository) Issues {
+func NewIssues(transactionRepo repository.TransactionRepository, uploadRepo repository.UploadRepository, rateRepo repository.FXRateRepository) Issues {
 	return &issues{
 		transactionRepo: transactionRepo,
 		uploadRepo:      uploadRepo,
+		rateRepo:        rateRepo,
 	}
 }
 
-func (i *issues) GetIssues(ctx context.Context, filters *transaction.IssuesFilters) (*IssuesResult, error) {
+func (i *issues) GetIssues(ctx context.Context, filters *transaction.IssuesFilters, reportingCurrency money.Currency) (*IssuesResult, error) {
 	_, err := i.uploadRepo.GetByID(ctx, upload.ID(filters.UploadID))
 	if err != nil {
 		return nil, errors.New("upload not found")
@@ -41,8 +51,24 @@ func (i *issues) GetIssues(ctx context.Context, filters *transaction.IssuesFilte
 		return nil, err
 	}
 
-	return &IssuesResult{
+	result := &IssuesResult{
 		Transactions: transactions,
 		TotalCount:   totalCount,
-	}, nil
+	}
+	if reportingCurrency == "" {
+		return result, nil
+	}
+
+	c := newConverter(i.rateRepo, reportingCurrency)
+	result.ReportingAmounts = make([]money.Money, 0, len(transactions))
+	for _, t := range transactions {
+		converted, err := c.convert(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		result.ReportingAmounts = append(result.ReportingAmounts, converted)
+	}
+	result.Rates = c.rates()
+
+	return result, nil
 }
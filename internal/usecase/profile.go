@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/infra/log"
+	"github.com/mj3smile/bank-statement-processor/internal/model/profile"
+	"github.com/mj3smile/bank-statement-processor/internal/parser"
+	"github.com/mj3smile/bank-statement-processor/internal/repository"
+)
+
+var (
+	ErrInvalidProfile  = errors.New("invalid profile")
+	ErrProfileExists   = errors.New("profile already exists")
+	ErrProfileNotFound = errors.New("profile not found")
+)
+
+type Profile interface {
+	Create(ctx context.Context, p *profile.Profile) error
+	Get(ctx context.Context, name string) (*profile.Profile, error)
+	List(ctx context.Context) ([]*profile.Profile, error)
+}
+
+type profileUseCase struct {
+	profileRepo repository.ProfileRepository
+}
+
+func NewProfile(profileRepo repository.ProfileRepository) Profile {
+	return &profileUseCase{
+		profileRepo: profileRepo,
+	}
+}
+
+func (uc *profileUseCase) Create(ctx context.Context, p *profile.Profile) error {
+	if p.Delimiter == 0 {
+		p.Delimiter = profile.DefaultDelimiter
+	}
+	if p.Quote == 0 {
+		p.Quote = profile.DefaultQuote
+	}
+	if p.AmountLayout == "" {
+		p.AmountLayout = profile.AmountLayoutType
+	}
+
+	if err := validateProfile(p); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+	}
+
+	if _, err := uc.profileRepo.GetByName(ctx, p.Name); err == nil {
+		return ErrProfileExists
+	}
+
+	p.CreatedAt = time.Now()
+	if err := uc.profileRepo.Save(ctx, p); err != nil {
+		log.Info(ctx, fmt.Sprint("save profile error: ", err.Error()))
+		return err
+	}
+
+	return nil
+}
+
+func (uc *profileUseCase) Get(ctx context.Context, name string) (*profile.Profile, error) {
+	p, err := uc.profileRepo.GetByName(ctx, name)
+	if err != nil {
+		return nil, ErrProfileNotFound
+	}
+
+	return p, nil
+}
+
+func (uc *profileUseCase) List(ctx context.Context) ([]*profile.Profile, error) {
+	return uc.profileRepo.List(ctx)
+}
+
+func validateProfile(p *profile.Profile) error {
+	if p.Name == "" {
+		return errors.New("name cannot be empty")
+	}
+
+	if p.Delimiter == p.Quote {
+		return errors.New("delimiter and quote must be different characters")
+	}
+
+	if p.Delimiter == '\n' || p.Delimiter == '\r' || p.Quote == '\n' || p.Quote == '\r' {
+		return errors.New("delimiter and quote cannot be line breaks")
+	}
+
+	if p.SkipRows < 0 {
+		return errors.New("skip_rows cannot be negative")
+	}
+
+	switch p.AmountLayout {
+	case profile.AmountLayoutType, profile.AmountLayoutSigned, profile.AmountLayoutDebitCredit:
+	default:
+		return fmt.Errorf("unknown amount_layout %s", p.AmountLayout)
+	}
+
+	if err := validateAmountFormat(p.AmountFormat); err != nil {
+		return err
+	}
+
+	for _, field := range p.RequiredFields() {
+		if p.Columns[field] == "" {
+			return fmt.Errorf("missing column for field %s", field)
+		}
+	}
+
+	for field := range p.Columns {
+		if !isProfileField(field) {
+			return fmt.Errorf("unknown field %s", field)
+		}
+	}
+
+	for _, format := range p.TimestampFormats {
+		if err := validateTimestampFormat(format); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateAmountFormat(format profile.AmountFormat) error {
+	for _, separator := range []rune{format.DecimalSeparator, format.ThousandsSeparator} {
+		if separator == '-' || separator == '+' || separator == '(' || separator == ')' || (separator >= '0' && separator <= '9') {
+			return fmt.Errorf("'%c' cannot be used as a separator", separator)
+		}
+	}
+
+	if format.DecimalSeparator != 0 && format.DecimalSeparator == format.ThousandsSeparator {
+		return errors.New("decimal and thousands separators must be different characters")
+	}
+
+	return nil
+}
+
+func validateTimestampFormat(format string) error {
+	if format == parser.TimestampUnix {
+		return nil
+	}
+	_, err := parser.TimestampLayout(format)
+	return err
+}
+
+func isProfileField(field profile.Field) bool {
+	for _, f := range append(profile.Fields, profile.OptionalFields...) {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/infra/log"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+	"github.com/mj3smile/bank-statement-processor/internal/repository"
+)
+
+// progressInterval is how often the progress of an upload is recorded while it is processed
+const progressInterval = time.Second
+
+// countingReader counts the bytes read through it and keeps the first error reading the file, which
+// tells a file that could not be read from a statement that could not be parsed.
+type countingReader struct {
+	io.Reader
+	n   int64
+	err error
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+// progressTracker records the progress of an upload while it is processed, at most every
+// progressInterval so that the repository is not locked for every row.
+type progressTracker struct {
+	uploadRepo repository.UploadRepository
+	uploadID   upload.ID
+	source     *countingReader
+	progress   upload.Progress
+}
+
+// trackProgress starts recording the progress of an upload as a worker starts processing it.
+func (uc *statement) trackProgress(ctx context.Context, prepared *preparedUpload) *progressTracker {
+	tracker := &progressTracker{
+		uploadRepo: uc.uploadRepo,
+		uploadID:   prepared.task.ID,
+		source:     prepared.counter,
+		progress: upload.Progress{
+			TotalBytes: prepared.size,
+			StartedAt:  time.Now(),
+		},
+	}
+	tracker.record(ctx)
+	return tracker
+}
+
+// row counts a row read and records the progress when it is due.
+func (t *progressTracker) row(ctx context.Context) {
+	t.progress.Rows++
+	if time.Since(t.progress.UpdatedAt) >= progressInterval {
+		t.record(ctx)
+	}
+}
+
+// complete records the progress once the whole file has been read, which tells the size of the file for sure.
+func (t *progressTracker) complete(ctx context.Context) {
+	t.progress.TotalBytes = t.source.n
+	t.record(ctx)
+}
+
+// record stores the progress as of now.
+func (t *progressTracker) record(ctx context.Context) {
+	t.progress.BytesRead = t.source.n
+	t.progress.UpdatedAt = time.Now()
+	if err := t.uploadRepo.UpdateProgress(ctx, t.uploadID, t.progress); err != nil {
+		log.Info(ctx, fmt.Sprint("failed to record upload progress:", err.Error()))
+	}
+}
@@ -0,0 +1,248 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/infra/log"
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+)
+
+// DefaultRulesReloadInterval is how often the rules file is checked for changes when none is configured
+const DefaultRulesReloadInterval = 5 * time.Second
+
+var ErrInvalidRules = errors.New("invalid rules")
+
+type Rules interface {
+	// Current returns the rules in effect, without any when no rules file is configured.
+	Current() *transaction.RuleSet
+	// Reload reads the rules file again. The rules in effect are kept when it is invalid.
+	Reload(ctx context.Context) error
+	// Watch reloads the rules file whenever it changes until ctx is done, checking it every interval.
+	Watch(ctx context.Context, interval time.Duration)
+}
+
+type rulesUseCase struct {
+	path string
+
+	mu      sync.RWMutex
+	current *transaction.RuleSet
+	// modTime and size tell whether the file changed since it was last read
+	modTime time.Time
+	size    int64
+}
+
+// NewRules creates the rules read from the JSON file at path, none until it is loaded with Reload.
+// An empty path means there are no rules. YAML files are turned down by Reload, there is no YAML support.
+func NewRules(path string) Rules {
+	return &rulesUseCase{
+		path:    path,
+		current: transaction.NewRuleSet(nil, time.Now()),
+	}
+}
+
+func (uc *rulesUseCase) Current() *transaction.RuleSet {
+	uc.mu.RLock()
+	defer uc.mu.RUnlock()
+	return uc.current
+}
+
+func (uc *rulesUseCase) Reload(ctx context.Context) error {
+	if uc.path == "" {
+		return nil
+	}
+	if ext := strings.ToLower(filepath.Ext(uc.path)); ext == ".yaml" || ext == ".yml" {
+		return fmt.Errorf("%w: %s is a YAML file, rules files must be JSON", ErrInvalidRules, uc.path)
+	}
+
+	info, err := os.Stat(uc.path)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(uc.path)
+	if err != nil {
+		return err
+	}
+
+	rules, err := parseRules(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.current = transaction.NewRuleSet(rules, time.Now())
+	uc.modTime, uc.size = info.ModTime(), info.Size()
+	log.Info(ctx, fmt.Sprintf("loaded %d business rules from %s", len(rules), uc.path))
+	return nil
+}
+
+func (uc *rulesUseCase) Watch(ctx context.Context, interval time.Duration) {
+	if uc.path == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !uc.changed() {
+			continue
+		}
+		if err := uc.Reload(ctx); err != nil {
+			log.Info(ctx, fmt.Sprint("failed to reload business rules, keeping the rules in effect:", err.Error()))
+			// the broken file is not read again until it changes
+			uc.remember()
+		}
+	}
+}
+
+// changed reports whether the rules file was modified since it was last read.
+func (uc *rulesUseCase) changed() bool {
+	info, err := os.Stat(uc.path)
+	if err != nil {
+		return false
+	}
+
+	uc.mu.RLock()
+	defer uc.mu.RUnlock()
+	return !info.ModTime().Equal(uc.modTime) || info.Size() != uc.size
+}
+
+// remember takes the rules file as read as it is now.
+func (uc *rulesUseCase) remember() {
+	info, err := os.Stat(uc.path)
+	if err != nil {
+		return
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.modTime, uc.size = info.ModTime(), info.Size()
+}
+
+// rulesFile is the layout of a rules file
+type rulesFile struct {
+	Rules []ruleDefinition `json:"rules"`
+}
+
+type ruleDefinition struct {
+	Name           string   `json:"name"`
+	Kind           string   `json:"kind"`
+	Severity       string   `json:"severity"`
+	Type           string   `json:"type"`
+	Currency       string   `json:"currency"`
+	Amount         int64    `json:"amount"`
+	Counterparties []string `json:"counterparties"`
+	Tolerance      string   `json:"tolerance"`
+	Pattern        string   `json:"pattern"`
+	Forbid         bool     `json:"forbid"`
+}
+
+var ruleSeverities = map[transaction.Severity]struct{}{
+	transaction.SeverityReject: {},
+	transaction.SeverityFlag:   {},
+	transaction.SeverityWarn:   {},
+}
+
+// parseRules reads a JSON rules file. Every rule must be valid for any of them to be used.
+func parseRules(r io.Reader) ([]*transaction.Rule, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var file rulesFile
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRules, err)
+	}
+
+	rules := make([]*transaction.Rule, 0, len(file.Rules))
+	names := make(map[string]struct{}, len(file.Rules))
+	for i, definition := range file.Rules {
+		rule, err := parseRule(definition)
+		if err != nil {
+			return nil, fmt.Errorf("%w: rule %d: %v", ErrInvalidRules, i+1, err)
+		}
+		if _, ok := names[rule.Name]; ok {
+			return nil, fmt.Errorf("%w: rule %d: name '%s' is used twice", ErrInvalidRules, i+1, rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func parseRule(definition ruleDefinition) (*transaction.Rule, error) {
+	rule := &transaction.Rule{
+		Name:     strings.TrimSpace(definition.Name),
+		Kind:     transaction.RuleKind(definition.Kind),
+		Severity: transaction.Severity(strings.ToLower(definition.Severity)),
+	}
+	if rule.Name == "" {
+		return nil, errors.New("name cannot be empty")
+	}
+	if _, ok := ruleSeverities[rule.Severity]; !ok {
+		return nil, fmt.Errorf("invalid severity '%s': must be reject, warn or flag", definition.Severity)
+	}
+
+	switch rule.Kind {
+	case transaction.RuleMaxAmount:
+		if definition.Amount <= 0 {
+			return nil, fmt.Errorf("amount must be positive, got %d", definition.Amount)
+		}
+		rule.MaxAmount = definition.Amount
+
+		rule.Type = transaction.Type(strings.ToUpper(definition.Type))
+		if rule.Type != "" && rule.Type != transaction.TypeCredit && rule.Type != transaction.TypeDebit {
+			return nil, fmt.Errorf("invalid transaction type '%s': must be CREDIT or DEBIT", definition.Type)
+		}
+		// amounts are in minor units, which only mean something in one currency
+		if definition.Currency == "" {
+			return nil, errors.New("currency is required: amount is in minor units of a currency")
+		}
+		currency, err := money.ParseCurrency(definition.Currency)
+		if err != nil {
+			return nil, err
+		}
+		rule.Currency = currency
+	case transaction.RuleCounterpartyAllowList, transaction.RuleCounterpartyBlockList:
+		if len(definition.Counterparties) == 0 {
+			return nil, errors.New("counterparties cannot be empty")
+		}
+		rule.Counterparties = definition.Counterparties
+	case transaction.RuleNoFutureTimestamp:
+		if definition.Tolerance != "" {
+			tolerance, err := time.ParseDuration(definition.Tolerance)
+			if err != nil || tolerance < 0 {
+				return nil, fmt.Errorf("invalid tolerance '%s': must be a duration such as 5m", definition.Tolerance)
+			}
+			rule.Tolerance = tolerance
+		}
+	case transaction.RuleDescriptionPattern:
+		pattern, err := regexp.Compile(definition.Pattern)
+		if err != nil || definition.Pattern == "" {
+			return nil, fmt.Errorf("invalid pattern '%s'", definition.Pattern)
+		}
+		rule.Pattern, rule.Forbid = pattern, definition.Forbid
+	default:
+		return nil, fmt.Errorf("unknown kind '%s': must be max_amount, counterparty_allowlist, counterparty_blocklist, no_future_timestamp or description_pattern", definition.Kind)
+	}
+
+	return rule, nil
+}
@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+)
+
+func Test_parseRules(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantRules int
+		wantErr   string
+	}{
+		{
+			name: "it should read every kind of rule",
+			content: `{"rules": [
+				{"name": "large-debit", "kind": "max_amount", "severity": "flag", "type": "debit", "currency": "IDR", "amount": 100000000},
+				{"name": "known", "kind": "counterparty_allowlist", "severity": "warn", "counterparties": ["ACME CORP"]},
+				{"name": "blocked", "kind": "counterparty_blocklist", "severity": "reject", "counterparties": ["SHADY LTD"]},
+				{"name": "no-future", "kind": "no_future_timestamp", "severity": "reject", "tolerance": "5m"},
+				{"name": "invoice", "kind": "description_pattern", "severity": "warn", "pattern": "^INV-\\d+"}
+			]}`,
+			wantRules: 5,
+		},
+		{
+			name:    "it should return error when a rule has an unknown severity",
+			content: `{"rules": [{"name": "no-future", "kind": "no_future_timestamp", "severity": "block"}]}`,
+			wantErr: "rule 1: invalid severity 'block'",
+		},
+		{
+			name:    "it should return error when a pattern does not compile",
+			content: `{"rules": [{"name": "invoice", "kind": "description_pattern", "severity": "warn", "pattern": "INV-("}]}`,
+			wantErr: "rule 1: invalid pattern 'INV-('",
+		},
+		{
+			name: "it should return error when a name is used twice",
+			content: `{"rules": [
+				{"name": "no-future", "kind": "no_future_timestamp", "severity": "reject"},
+				{"name": "no-future", "kind": "no_future_timestamp", "severity": "warn"}
+			]}`,
+			wantErr: "rule 2: name 'no-future' is used twice",
+		},
+		{
+			name:    "it should return error when a max_amount rule has no currency",
+			content: `{"rules": [{"name": "large", "kind": "max_amount", "severity": "flag", "amount": 100}]}`,
+			wantErr: "rule 1: currency is required",
+		},
+		{
+			name:    "it should return error when a rule has an unknown field",
+			content: `{"rules": [{"name": "large", "kind": "max_amount", "severity": "flag", "max": 100}]}`,
+			wantErr: `unknown field "max"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRules(strings.NewReader(tt.content))
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrInvalidRules) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseRules() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRules() error = %v", err)
+			}
+			if len(got) != tt.wantRules {
+				t.Errorf("parseRules() got %v rules, want %v", len(got), tt.wantRules)
+			}
+		})
+	}
+}
+
+func Test_rulesUseCase_Reload(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		wantErr  string
+	}{
+		{name: "it should load a JSON rules file", filename: "rules.json"},
+		{name: "it should return error when the rules file is YAML", filename: "rules.yaml", wantErr: "rules files must be JSON"},
+		{name: "it should return error when the rules file is YAML with a short extension", filename: "rules.YML", wantErr: "rules files must be JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the content is valid JSON, which YAML accepts as well
+			path := filepath.Join(t.TempDir(), tt.filename)
+			if err := os.WriteFile(path, []byte(`{"rules": []}`), 0o644); err != nil {
+				t.Fatalf("error while writing rules file: %v", err)
+			}
+
+			err := NewRules(path).Reload(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Reload() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidRules) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Reload() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_rulesUseCase_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("error while writing rules file: %v", err)
+		}
+	}
+	ruleNames := func(rules *transaction.RuleSet) string {
+		names := make([]string, 0, len(rules.Rules))
+		for _, r := range rules.Rules {
+			names = append(names, r.Name)
+		}
+		return strings.Join(names, ",")
+	}
+
+	write(`{"rules": [{"name": "no-future", "kind": "no_future_timestamp", "severity": "reject"}]}`)
+	rules := NewRules(path)
+	if err := rules.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go rules.Watch(ctx, 10*time.Millisecond)
+
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for ruleNames(rules.Current()) != want && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if got := ruleNames(rules.Current()); got != want {
+			t.Fatalf("Current() rules = %v, want %v", got, want)
+		}
+	}
+
+	// the file is changed in place, with a size that tells it apart even on coarse file times
+	write(`{"rules": [{"name": "blocked", "kind": "counterparty_blocklist", "severity": "reject", "counterparties": ["SHADY LTD"]}]}`)
+	waitFor("blocked")
+
+	// a broken file keeps the rules in effect
+	write(`{"rules": [{"name": "broken"`)
+	time.Sleep(100 * time.Millisecond)
+	waitFor("blocked")
+
+	write(`{"rules": [{"name": "invoice", "kind": "description_pattern", "severity": "warn", "pattern": "^INV-"}]}`)
+	waitFor("invoice")
+}
@@ -1,12 +1,14 @@
 package usecase
 
 import (
+	"bufio"
+	"compress/gzip"
 	"context"
-	"encoding/csv"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
-	"mime/multipart"
 	"strconv"
 	"strings"
 	"time"
@@ -14,61 +16,486 @@ import (
 	"github.com/google/uuid"
 	"github.com/mj3smile/bank-statement-processor/internal/event"
 	"github.com/mj3smile/bank-statement-processor/internal/infra/log"
+	"github.com/mj3smile/bank-statement-processor/internal/model/account"
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/profile"
 	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
 	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+	"github.com/mj3smile/bank-statement-processor/internal/parser"
 	"github.com/mj3smile/bank-statement-processor/internal/repository"
 )
 
+const (
+	// sniffSize is how many bytes of an upload are inspected to detect its format and delimiter
+	sniffSize = 512
+	// encodingSniffSize is how many bytes are inspected to detect the character encoding, larger so that
+	// a few accented names are likely to show up
+	encodingSniffSize = 16 << 10
+)
+
+var (
+	ErrInvalidTimeZone        = errors.New("invalid time zone")
+	ErrInvalidTimestampFormat = errors.New("invalid timestamp format")
+	ErrInvalidArchive         = errors.New("invalid archive")
+	ErrInvalidHeaderRow       = errors.New("header row must be a positive number")
+	ErrInvalidDuplicatePolicy = errors.New("duplicate policy must be existing or reject")
+	ErrDuplicateUpload        = errors.New("statement has already been uploaded")
+	ErrInvalidDedupPolicy     = errors.New("dedup policy must be skip, flag or keep")
+	ErrUploadCancelled        = errors.New("upload cancelled")
+	ErrUploadFinished         = errors.New("upload has already finished")
+)
+
 type Statement interface {
-	Upload(ctx context.Context, file multipart.File, filename string) (upload.ID, error)
+	// Upload reads the head of file to detect its format and processes the rest in the background, so file
+	// may still be arriving. On success the use case owns file and closes it once processing has ended.
+	// Archives holding several statements are processed as a batch with an upload per statement.
+	// Content that was processed before is only found once the whole file has been read, the upload then
+	// ends as a duplicate without adding transactions. With opts.OnDuplicate the file is read to a temporary
+	// file and hashed before Upload returns, which then answers with the earlier upload of the content or a
+	// DuplicateUploadError instead of starting another.
+	// At most a fixed number of uploads are processed at once, the others wait their turn as queued and
+	// a QueueFullError is returned once the queue is full too. The file of a queued upload is read to a
+	// temporary file before Upload returns, so that nothing waits on the sender.
+	Upload(ctx context.Context, file io.ReadCloser, filename string, opts UploadOptions) (*UploadResult, error)
+	// Cancel stops a queued or processing upload at its next row and discards its transactions. It fails
+	// with ErrUploadFinished once the upload is about to be committed or has ended. A batch cannot be
+	// cancelled, each statement of an archive is cancelled as its own upload.
+	Cancel(ctx context.Context, uploadID string) error
+	// Validate reads the whole of file the way Upload would and reports the layout detected and every row
+	// that would be rejected, without saving an upload or a transaction. Archives of several statements
+	// are not validated.
+	Validate(ctx context.Context, file io.Reader, filename string, opts UploadOptions) (*ValidationReport, error)
+}
+
+// UploadResult identifies what an upload started: a single statement upload, or a batch for an archive.
+type UploadResult struct {
+	UploadID upload.ID
+	BatchID  upload.BatchID
+	// Duplicate reports that UploadID is an earlier upload of the same content
+	Duplicate bool
+	// QueuePosition is the place in the ingestion queue when the upload has to wait for a worker
+	QueuePosition int
+	// AssumedCurrency is taken for rows without a currency of their own when the upload declares none,
+	// unless the statement states its currency. It is empty when the upload declares a currency
+	AssumedCurrency money.Currency
+}
+
+type UploadOptions struct {
+	// Profile is the name of the column mapping profile for delimited files
+	Profile string
+	// Lenient quarantines invalid rows and keeps processing the rest instead of failing the upload
+	Lenient bool
+	// Currency applies to rows that do not state their own, money.DefaultCurrency when empty
+	Currency string
+	// TimeZone is the IANA time zone of source timestamps without an offset, UTC when empty
+	TimeZone string
+	// TimestampFormat replaces the timestamp formats of the profile
+	TimestampFormat string
+	// Sheet is the worksheet read from a spreadsheet, replacing the one of the profile
+	Sheet string
+	// HeaderRow is the 1-based row of the column names, replacing the skipped rows of the profile
+	HeaderRow int
+	// OnDuplicate decides what uploading already processed content returns. It does not apply to archives.
+	OnDuplicate DuplicatePolicy
+	// Account is the account the statement belongs to. Transactions already imported by an earlier
+	// upload of the account are handled according to Dedup, upload.DedupSkip when empty.
+	Account string
+	Dedup   upload.DedupPolicy
+	// Size is the size of the file in bytes, 0 when not known. It only serves to report progress.
+	Size int64
+	// OpeningBalance and ClosingBalance declare the balances the statement starts and ends with, in minor
+	// units of Currency. They replace the balances the statement declares itself.
+	OpeningBalance *int64
+	ClosingBalance *int64
 }
 
 type statement struct {
 	appCtx          context.Context
 	transactionRepo repository.TransactionRepository
 	uploadRepo      repository.UploadRepository
+	batchRepo       repository.BatchRepository
+	accountRepo     repository.AccountRepository
+	profileRepo     repository.ProfileRepository
 	eventBus        event.Bus
+	parsers         *parser.Registry
+	rules           Rules
+	ingestion       *ingestionQueue
+	archiveLimits   ArchiveConfig
+	cancellations   *cancellations
 }
 
-func NewStatement(appCtx context.Context, transactionRepo repository.TransactionRepository, uploadRepo repository.UploadRepository, eventBus event.Bus) Statement {
+// StatementConfig holds what the statement use case depends on.
+type StatementConfig struct {
+	TransactionRepo repository.TransactionRepository
+	UploadRepo      repository.UploadRepository
+	BatchRepo       repository.BatchRepository
+	AccountRepo     repository.AccountRepository
+	ProfileRepo     repository.ProfileRepository
+	EventBus        event.Bus
+	Parsers         *parser.Registry
+	Rules           Rules
+	Ingestion       IngestionConfig
+	Archive         ArchiveConfig
+}
+
+func NewStatement(appCtx context.Context, config StatementConfig) Statement {
 	return &statement{
 		appCtx:          appCtx,
-		transactionRepo: transactionRepo,
-		uploadRepo:      uploadRepo,
-		eventBus:        eventBus,
+		transactionRepo: config.TransactionRepo,
+		uploadRepo:      config.UploadRepo,
+		batchRepo:       config.BatchRepo,
+		accountRepo:     config.AccountRepo,
+		profileRepo:     config.ProfileRepo,
+		eventBus:        config.EventBus,
+		parsers:         config.Parsers,
+		rules:           config.Rules,
+		ingestion:       newIngestionQueue(config.Ingestion),
+		archiveLimits:   config.Archive.withDefaults(),
+		cancellations:   newCancellations(),
 	}
 }
 
-func (uc *statement) Upload(ctx context.Context, file multipart.File, filename string) (upload.ID, error) {
-	uploadID := uuid.NewString()
+func (uc *statement) Upload(ctx context.Context, file io.ReadCloser, filename string, opts UploadOptions) (*UploadResult, error) {
+	parserOpts, err := uc.parserOptions(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// the place is taken before the file is read, an upload turned down has cost nothing
+	admission, err := uc.ingestion.admit()
+	if err != nil {
+		return nil, err
+	}
+	// a queued upload does not hold on to its request while it waits, the file is received first. So is
+	// the file of an upload whose answer depends on whether its content was processed before
+	if uc.ingestion.position(admission) > 0 || opts.OnDuplicate != DuplicateRecord {
+		file, err = spool(file)
+		if err != nil {
+			uc.ingestion.withdraw(admission)
+			return nil, err
+		}
+	}
+
+	raw := bufio.NewReaderSize(file, encodingSniffSize)
+	rawHead, err := raw.Peek(encodingSniffSize)
+	if err != nil && err != io.EOF {
+		uc.ingestion.withdraw(admission)
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	archive := parser.DetectArchive(filename, rawHead)
+	if archive.IsMultiFile() {
+		batch := &upload.Batch{
+			ID:        upload.BatchID(uuid.NewString()),
+			Filename:  filename,
+			Archive:   string(archive),
+			Status:    upload.StatusProcessing,
+			Message:   upload.MessageProcessing,
+			StartedAt: time.Now(),
+		}
+		if err := uc.queueBatch(ctx, batch, admission); err != nil {
+			uc.ingestion.withdraw(admission)
+			return nil, err
+		}
+
+		go uc.processArchive(uc.appCtx, batch, admission, file, raw, archive, opts, parserOpts)
+		return &UploadResult{BatchID: batch.ID, QueuePosition: uc.ingestion.position(admission), AssumedCurrency: assumedCurrency(parserOpts)}, nil
+	}
+
+	if spooled, ok := file.(*spooledFile); ok && opts.OnDuplicate != DuplicateRecord {
+		result, err := uc.answerDuplicate(ctx, spooled, archive, opts.OnDuplicate)
+		if result != nil || err != nil {
+			uc.ingestion.withdraw(admission)
+			file.Close()
+			return result, err
+		}
+	}
+
+	prepared, err := uc.prepare(ctx, raw, filename, archive, "", newUnpackLimit(uc.archiveLimits.MaxSize), opts, parserOpts)
+	if err != nil {
+		uc.ingestion.withdraw(admission)
+		return nil, err
+	}
+	prepared.admission, prepared.onDuplicate = admission, opts.OnDuplicate
+
+	// the upload can be cancelled as soon as its task can be found
+	uploadCtx := uc.cancellations.register(uc.appCtx, prepared.task.ID)
+	if err := uc.queueTask(ctx, prepared.task, admission); err != nil {
+		uc.cancellations.release(prepared.task.ID)
+		uc.ingestion.withdraw(admission)
+		return nil, err
+	}
+	go uc.processStatement(uploadCtx, prepared, file)
+	return &UploadResult{UploadID: prepared.task.ID, QueuePosition: uc.ingestion.position(admission), AssumedCurrency: assumedCurrency(parserOpts)}, nil
+}
+
+// assumedCurrency returns the currency taken for rows without one when the upload declares none.
+func assumedCurrency(parserOpts parser.Options) money.Currency {
+	if !parserOpts.StatementCurrency {
+		return ""
+	}
+	return parserOpts.Currency
+}
+
+// parserOptions resolves the upload options that apply to every statement of an upload.
+func (uc *statement) parserOptions(ctx context.Context, opts UploadOptions) (parser.Options, error) {
+	var parserOpts parser.Options
+	if opts.Profile != "" {
+		p, err := uc.profileRepo.GetByName(ctx, opts.Profile)
+		if err != nil {
+			return parserOpts, ErrProfileNotFound
+		}
+		parserOpts.Profile = p
+		parserOpts.TimestampFormats = p.TimestampFormats
+		parserOpts.Sheet = p.Sheet
+	}
+	if opts.Sheet != "" {
+		parserOpts.Sheet = opts.Sheet
+	}
+	if opts.HeaderRow < 0 {
+		return parserOpts, ErrInvalidHeaderRow
+	}
+	if opts.OnDuplicate != DuplicateRecord && opts.OnDuplicate != DuplicateReturnExisting && opts.OnDuplicate != DuplicateReject {
+		return parserOpts, ErrInvalidDuplicatePolicy
+	}
+	if opts.Dedup != "" && !opts.Dedup.IsValid() {
+		return parserOpts, ErrInvalidDedupPolicy
+	}
+	if opts.Dedup != "" && opts.Account == "" {
+		return parserOpts, fmt.Errorf("%w: duplicates are looked for within an account, which is missing", ErrInvalidDedupPolicy)
+	}
+	parserOpts.HeaderRow = opts.HeaderRow
+
+	if opts.TimestampFormat != "" {
+		if err := validateTimestampFormat(opts.TimestampFormat); err != nil {
+			return parserOpts, fmt.Errorf("%w: %v", ErrInvalidTimestampFormat, err)
+		}
+		parserOpts.TimestampFormats = []string{opts.TimestampFormat}
+	}
+
+	if opts.TimeZone != "" {
+		location, err := time.LoadLocation(opts.TimeZone)
+		if err != nil {
+			return parserOpts, fmt.Errorf("%w '%s'", ErrInvalidTimeZone, opts.TimeZone)
+		}
+		parserOpts.Location = location
+	}
+
+	parserOpts.Currency = money.DefaultCurrency
+	if opts.Currency != "" {
+		currency, err := money.ParseCurrency(opts.Currency)
+		if err != nil {
+			return parserOpts, err
+		}
+		parserOpts.Currency = currency
+	}
+	// a currency stated by the statement itself only applies when the upload declares none
+	parserOpts.StatementCurrency = opts.Currency == ""
+
+	return parserOpts, nil
+}
+
+// preparedUpload is a statement whose format has been detected and whose task has been saved.
+type preparedUpload struct {
+	task       *upload.Task
+	source     io.Reader
+	parser     parser.StatementParser
+	parserOpts parser.Options
+	// contentHash is fed every byte of the statement as it is read
+	contentHash hash.Hash
+	// rules are the business rules in effect when the upload started, kept for the whole statement
+	rules *transaction.RuleSet
+	// counter counts the bytes of the file read so far, out of size when it is known
+	counter *countingReader
+	size    int64
+	// onDuplicate decides how the upload ends when another upload claimed its content while it was processed
+	onDuplicate DuplicatePolicy
+	// admission is the place in the ingestion queue, nil for statements of an archive which
+	// are processed by the worker of the archive
+	admission *admission
+}
+
+// prepare detects the encoding and format of a single statement and creates its task, which the
+// caller saves. A gzip compressed statement is decompressed while it is read, counted against limit.
+func (uc *statement) prepare(ctx context.Context, r io.Reader, filename string, archive parser.Archive, batchID upload.BatchID, limit *unpackLimit, opts UploadOptions, parserOpts parser.Options) (*preparedUpload, error) {
+	counter := &countingReader{Reader: r}
+	r = counter
+
+	name := filename
+	if archive == parser.ArchiveGzip {
+		decompressed, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		r, name = limit.Reader(decompressed), parser.TrimArchiveExtension(filename)
+	}
+
+	contentHash := sha256.New()
+	raw := bufio.NewReaderSize(io.TeeReader(r, contentHash), encodingSniffSize)
+	rawHead, err := raw.Peek(encodingSniffSize)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// workbooks are binary and read as they are, everything else is transcoded to UTF-8 first
+	var (
+		source          io.Reader = raw
+		statementParser parser.StatementParser
+		encoding        parser.Encoding
+		bom             bool
+		head            []byte
+	)
+	if p, err := uc.parsers.Resolve(name, rawHead); err == nil && p.Format() == parser.FormatXLSX {
+		statementParser = p
+	} else {
+		encoding, bom = parser.DetectEncoding(rawHead)
+
+		decoded := bufio.NewReaderSize(parser.NewDecoder(raw, encoding), sniffSize)
+		head, err = decoded.Peek(sniffSize)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+
+		statementParser, err = uc.parsers.Resolve(name, head)
+		if err != nil {
+			return nil, err
+		}
+		source = decoded
+	}
+
+	// a workbook is a zip file too, what it unpacks to counts against the limit of the upload
+	parserOpts.Limit = limit
+
+	var delimiter string
+	if statementParser.Format() == parser.FormatCSV && parserOpts.Profile == nil {
+		parserOpts.Delimiter = parser.SniffDelimiter(head, profile.DefaultQuote)
+		delimiter = string(parserOpts.Delimiter)
+	}
+
 	task := &upload.Task{
-		ID:        upload.ID(uploadID),
+		ID:        upload.ID(uuid.NewString()),
+		BatchID:   batchID,
 		Status:    upload.StatusProcessing,
 		Message:   upload.MessageProcessing,
 		Filename:  filename,
+		Archive:   string(archive),
+		Format:    string(statementParser.Format()),
+		Profile:   opts.Profile,
+		Currency:  parserOpts.Currency,
+		TimeZone:  opts.TimeZone,
+		Encoding:  string(encoding),
+		BOM:       bom,
+		Delimiter: delimiter,
+		Lenient:   opts.Lenient,
+		Account:   opts.Account,
 		StartedAt: time.Now(),
 	}
+	if opts.OpeningBalance != nil {
+		task.OpeningBalance = &upload.Balance{Amount: *opts.OpeningBalance, Currency: parserOpts.Currency}
+	}
+	if opts.ClosingBalance != nil {
+		task.ClosingBalance = &upload.Balance{Amount: *opts.ClosingBalance, Currency: parserOpts.Currency}
+	}
+	if opts.Account != "" {
+		task.DedupPolicy = opts.Dedup
+		if task.DedupPolicy == "" {
+			task.DedupPolicy = upload.DedupSkip
+		}
+	}
 
-	err := uc.uploadRepo.Save(ctx, task)
-	if err != nil {
+	return &preparedUpload{
+		task:        task,
+		source:      source,
+		parser:      statementParser,
+		parserOpts:  parserOpts,
+		rules:       uc.rules.Current(),
+		contentHash: contentHash,
+		counter:     counter,
+		size:        opts.Size,
+	}, nil
+}
+
+// queueTask saves the task of an upload, as queued while its admission waits for a worker, and keeps
+// its position in the queue up to date.
+func (uc *statement) queueTask(ctx context.Context, task *upload.Task, admission *admission) error {
+	if position := uc.ingestion.position(admission); position > 0 {
+		task.Status, task.Message, task.QueuePosition = upload.StatusQueued, upload.MessageQueued, position
+	}
+	if err := uc.saveTask(ctx, task); err != nil {
+		return err
+	}
+
+	uc.ingestion.follow(admission, func(position int) {
+		update := &upload.Task{ID: task.ID, Status: upload.StatusQueued, Message: upload.MessageQueued, QueuePosition: position}
+		if err := uc.uploadRepo.Update(uc.appCtx, update); err != nil {
+			log.Info(uc.appCtx, fmt.Sprint("failed to update queue position:", err.Error()))
+		}
+	})
+	return nil
+}
+
+// saveTask stores a new task and adds it to its batch and account, if any.
+func (uc *statement) saveTask(ctx context.Context, task *upload.Task) error {
+	if err := uc.uploadRepo.Save(ctx, task); err != nil {
 		log.Info(ctx, fmt.Sprint("save upload task error:", err.Error()))
-		return "", err
+		return err
+	}
+
+	if task.BatchID != "" {
+		if err := uc.batchRepo.AddUpload(ctx, task.BatchID, task.ID); err != nil {
+			log.Info(ctx, fmt.Sprint("add upload to batch error:", err.Error()))
+			return err
+		}
 	}
 
-	go uc.processStatement(uc.appCtx, task.ID, file)
-	return task.ID, nil
+	if task.Account != "" {
+		if err := uc.accountRepo.AddUpload(ctx, account.ID(task.Account), task.ID); err != nil {
+			log.Info(ctx, fmt.Sprint("add upload to account error:", err.Error()))
+			return err
+		}
+	}
+
+	return nil
 }
 
-func (uc *statement) processStatement(ctx context.Context, uploadID upload.ID, file multipart.File) {
+// processStatement processes a statement with the context its upload was registered with, see cancellations.
+func (uc *statement) processStatement(ctx context.Context, prepared *preparedUpload, file io.Closer) {
+	defer uc.cancellations.release(prepared.task.ID)
 	defer file.Close()
-	csvReader := csv.NewReader(file)
+	task := prepared.task
+	uploadID := task.ID
+
+	if prepared.admission != nil {
+		if !uc.ingestion.wait(ctx, prepared.admission) {
+			uc.markUploadAsFailed(ctx, uploadID, "processing cancelled")
+			return
+		}
+		defer uc.ingestion.done(prepared.admission)
+
+		if err := uc.uploadRepo.Update(ctx, &upload.Task{ID: uploadID, Status: upload.StatusProcessing, Message: upload.MessageProcessing}); err != nil {
+			log.Info(ctx, fmt.Sprint("failed to mark upload as processing:", err.Error()))
+		}
+	}
+	progress := uc.trackProgress(ctx, prepared)
+	defer progress.record(ctx)
 
-	if _, err := csvReader.Read(); err != nil {
-		uc.markUploadAsFailed(ctx, uploadID, "failed to read CSV header: "+err.Error())
+	reader, err := prepared.parser.NewReader(prepared.source, prepared.parserOpts)
+	if err != nil {
+		uc.markUploadAsFailed(ctx, uploadID, err.Error())
 		return
 	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+	adoptStatementCurrency(task, prepared.parserOpts, reader)
 
-	lineNumber := 1
+	// transactions stay staged until the whole statement is processed, so a failure
+	// part way through leaves nothing from this upload visible
+	var failedTransactions []*transaction.Transaction
+	net := statementNet{}
+	totals := newStatementTotals(totalsCurrency(task, reader))
+	var decisions []*upload.DedupDecision
+	lineNumber, rejectedRows := 0, 0
 	for {
 		select {
 		case <-ctx.Done():
@@ -77,61 +504,186 @@ func (uc *statement) processStatement(ctx context.Context, uploadID upload.ID, f
 		default:
 		}
 
-		record, err := csvReader.Read()
+		record, err := reader.Read()
 		if err == io.EOF {
 			break
 		}
+		progress.row(ctx)
+		totals.rows++
+
+		var rowErr *parser.RowError
+		if task.Lenient && errors.As(err, &rowErr) {
+			lineNumber = rowErr.Line
+			if err := uc.rejectRow(ctx, uploadID, rowErr.Line, rowErr.Raw, rowErr.Err); err != nil {
+				uc.markUploadAsFailed(ctx, uploadID, fmt.Sprintf("failed to quarantine line %d: %v", lineNumber, err))
+				return
+			}
+			rejectedRows++
+			continue
+		}
 		if err != nil {
-			uc.markUploadAsFailed(ctx, uploadID, fmt.Sprintf("error at line %d: %v", lineNumber, err))
+			uc.markUploadAsFailed(ctx, uploadID, fmt.Sprintf("error after line %d: %v", lineNumber, err))
 			return
 		}
 
-		lineNumber++
-		//log.Info(ctx, fmt.Sprint(lineNumber, " record:", record, "len:", len(record)))
-
-		t, err := uc.parseTransaction(record, uploadID)
+		lineNumber = record.Line
+		t, err := uc.toTransaction(record, task, prepared.rules)
+		if err != nil && task.Lenient {
+			if err := uc.rejectRow(ctx, uploadID, record.Line, record.Raw, err); err != nil {
+				uc.markUploadAsFailed(ctx, uploadID, fmt.Sprintf("failed to quarantine line %d: %v", lineNumber, err))
+				return
+			}
+			rejectedRows++
+			continue
+		}
 		if err != nil {
 			uc.markUploadAsFailed(ctx, uploadID, fmt.Sprintf("invalid data at line %d: %v", lineNumber, err))
 			return
 		}
+		if err := net.add(t); err != nil {
+			uc.markUploadAsFailed(ctx, uploadID, fmt.Sprintf("invalid data at line %d: %v", lineNumber, err))
+			return
+		}
+		if err := totals.add(t); err != nil {
+			uc.markUploadAsFailed(ctx, uploadID, fmt.Sprintf("invalid data at line %d: %v", lineNumber, err))
+			return
+		}
+
+		if task.Account != "" {
+			if decision := uc.deduplicate(ctx, task, record.Line, t); decision != nil {
+				decisions = append(decisions, decision)
+				if task.DedupPolicy == upload.DedupSkip {
+					continue
+				}
+			}
+		}
 
-		if err := uc.transactionRepo.Save(ctx, t); err != nil {
+		if err := uc.transactionRepo.Stage(ctx, t); err != nil {
 			uc.markUploadAsFailed(ctx, uploadID, fmt.Sprintf("failed to save transaction at line %d: %v", lineNumber, err))
 			return
 		}
 
 		if t.Status == transaction.StatusFailed {
-			failedEvent := event.NewFailedTransactionEvent(t.ID, uploadID, t.Counterparty, t.Description, t.Timestamp, t.Amount)
-			uc.eventBus.Publish(failedEvent)
+			failedTransactions = append(failedTransactions, t)
+		}
+	}
+
+	// the hash covers the whole file, including whatever the parser left unread
+	if _, err := io.Copy(io.Discard, prepared.source); err != nil {
+		uc.markUploadAsFailed(ctx, uploadID, fmt.Sprintf("failed to read file: %v", err))
+		return
+	}
+	progress.complete(ctx)
+	if !uc.cancellations.settle(uploadID) {
+		uc.markUploadAsFailed(ctx, uploadID, ErrUploadCancelled.Error())
+		return
+	}
+	contentHash, ok := uc.claimContent(ctx, prepared)
+	if !ok {
+		return
+	}
+
+	if err := uc.transactionRepo.Commit(ctx, uploadID); err != nil {
+		uc.releaseContent(ctx, contentHash, uploadID)
+		uc.markUploadAsFailed(ctx, uploadID, fmt.Sprintf("failed to commit transactions: %v", err))
+		return
+	}
+	if len(decisions) > 0 {
+		if err := uc.uploadRepo.AddDedupDecisions(ctx, uploadID, decisions); err != nil {
+			log.Info(ctx, fmt.Sprint("failed to record dedup decisions:", err.Error()))
+		}
+	}
+	if task.Account != "" {
+		if err := uc.accountRepo.AddCompletedUpload(ctx, account.ID(task.Account), uploadID, uc.transactionRepo.GetByUploadID(ctx, uploadID)); err != nil {
+			log.Info(ctx, fmt.Sprint("failed to add upload to account balance:", err.Error()))
+		}
+	}
+
+	for _, t := range failedTransactions {
+		failedEvent := event.NewFailedTransactionEvent(t.ID, uploadID, t.Counterparty, t.Description, t.Timestamp, t.Amount)
+		uc.eventBus.Publish(failedEvent)
+	}
+
+	completed := &upload.Task{ID: uploadID, Currency: task.Currency, RejectedRows: rejectedRows, DuplicateRows: len(decisions), DedupPolicy: task.DedupPolicy, ContentHash: contentHash}
+	completed.OpeningBalance, completed.ClosingBalance = statementBalances(task, reader)
+	completed.BalanceCheck, err = checkBalance(completed.OpeningBalance, completed.ClosingBalance, net)
+	if err != nil {
+		log.Info(ctx, fmt.Sprint("failed to check statement balances:", err.Error()))
+	}
+	if details, ok := statementDetails(reader); ok {
+		completed.Details = &details
+		completed.TrailerMismatches, err = totals.check(details)
+		if err != nil {
+			log.Info(ctx, fmt.Sprint("failed to check statement trailer:", err.Error()))
+		}
+	}
+	uc.markUploadAsCompleted(ctx, completed)
+}
+
+// statementBalances returns the balances a statement starts and ends with, those declared with the
+// upload taking precedence over those the statement declares itself.
+func statementBalances(task *upload.Task, reader parser.Reader) (opening, closing *upload.Balance) {
+	opening, closing = task.OpeningBalance, task.ClosingBalance
+	if metadataReader, ok := reader.(parser.MetadataReader); ok {
+		metadata := metadataReader.Metadata()
+		if opening == nil {
+			opening = metadata.OpeningBalance
+		}
+		if closing == nil {
+			closing = metadata.ClosingBalance
+		}
+	}
+	return opening, closing
+}
+
+// toTransaction validates a record, adds what its canonical fields do not carry and checks it against
+// the business rules. A rule that rejects the transaction is returned as the error.
+func (uc *statement) toTransaction(record *parser.Record, task *upload.Task, rules *transaction.RuleSet) (*transaction.Transaction, error) {
+	t, err := uc.parseTransaction(record.Fields, task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Currency = task.Currency
+	if record.Currency != "" {
+		t.Currency, err = money.ParseCurrency(record.Currency)
+		if err != nil {
+			return nil, err
 		}
 	}
+	t.Remittance = record.Remittance
+
+	results, rejected := rules.Check(t, time.Now())
+	if rejected != nil {
+		return nil, rejected
+	}
+	t.RuleResults = results
 
-	//uc.transactionRepo.PrepareDataForFilters(ctx, uploadID)
-	uc.markUploadAsCompleted(ctx, uploadID)
+	return t, nil
 }
 
 func (uc *statement) parseTransaction(record []string, uploadID upload.ID) (*transaction.Transaction, error) {
-	if len(record) != 6 {
-		return nil, fmt.Errorf("invalid CSV format: expected 6 columns, got %d", len(record))
+	if len(record) != parser.ColumnCount {
+		return nil, fmt.Errorf("invalid record: expected %d columns, got %d", parser.ColumnCount, len(record))
 	}
 
-	timestampStr := strings.TrimSpace(record[0])
+	timestampStr := strings.TrimSpace(record[parser.ColumnTimestamp])
 	timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
 	if err != nil {
 		return nil, fmt.Errorf("invalid timestamp '%s': %w", timestampStr, err)
 	}
 
-	counterparty := strings.TrimSpace(record[1])
+	counterparty := strings.TrimSpace(record[parser.ColumnCounterparty])
 	if counterparty == "" {
 		return nil, errors.New("counterparty cannot be empty")
 	}
 
-	transactionType := transaction.Type(strings.ToUpper(strings.TrimSpace(record[2])))
+	transactionType := transaction.Type(strings.ToUpper(strings.TrimSpace(record[parser.ColumnType])))
 	if transactionType != transaction.TypeCredit && transactionType != transaction.TypeDebit {
 		return nil, fmt.Errorf("invalid transaction type '%s': must be CREDIT or DEBIT", transactionType)
 	}
 
-	amountStr := strings.TrimSpace(record[3])
+	amountStr := strings.TrimSpace(record[parser.ColumnAmount])
 	amount, err := strconv.ParseInt(amountStr, 10, 64)
 	if err != nil {
 		return nil, fmt.Errorf("invalid amount '%s': %w", amountStr, err)
@@ -140,12 +692,12 @@ func (uc *statement) parseTransaction(record []string, uploadID upload.ID) (*tra
 		return nil, fmt.Errorf("amount must be positive, got %d", amount)
 	}
 
-	status := transaction.Status(strings.ToUpper(strings.TrimSpace(record[4])))
+	status := transaction.Status(strings.ToUpper(strings.TrimSpace(record[parser.ColumnStatus])))
 	if status != transaction.StatusSuccess && status != transaction.StatusFailed && status != transaction.StatusPending {
 		return nil, fmt.Errorf("invalid status '%s': must be SUCCESS, FAILED, or PENDING", status)
 	}
 
-	description := strings.TrimSpace(record[5])
+	description := strings.TrimSpace(record[parser.ColumnDescription])
 	if description == "" {
 		return nil, errors.New("description cannot be empty")
 	}
@@ -162,13 +714,30 @@ func (uc *statement) parseTransaction(record []string, uploadID upload.ID) (*tra
 	}, nil
 }
 
+func (uc *statement) rejectRow(ctx context.Context, uploadID upload.ID, line int, raw string, reason error) error {
+	return uc.uploadRepo.AddRejection(ctx, uploadID, &upload.Rejection{
+		Line:  line,
+		Raw:   raw,
+		Error: reason.Error(),
+	})
+}
+
+// markUploadAsFailed discards every staged transaction of the upload before recording the failure. An
+// upload that fails because it was cancelled is recorded as cancelled.
 func (uc *statement) markUploadAsFailed(ctx context.Context, uploadID upload.ID, reason string) {
+	if err := uc.transactionRepo.Rollback(ctx, uploadID); err != nil {
+		log.Info(ctx, fmt.Sprint("failed to roll back upload transactions:", err.Error()))
+	}
+
 	info := &upload.Task{
 		ID:          uploadID,
 		Status:      upload.StatusFailed,
 		Message:     reason,
 		CompletedAt: time.Now(),
 	}
+	if errors.Is(context.Cause(ctx), ErrUploadCancelled) {
+		info.Status, info.Message = upload.StatusCancelled, ErrUploadCancelled.Error()
+	}
 
 	err := uc.uploadRepo.Update(ctx, info)
 	if err != nil {
@@ -177,16 +746,29 @@ func (uc *statement) markUploadAsFailed(ctx context.Context, uploadID upload.ID,
 	}
 }
 
-func (uc *statement) markUploadAsCompleted(ctx context.Context, uploadID upload.ID) {
-	info := &upload.Task{
-		ID:          uploadID,
-		Status:      upload.StatusCompleted,
-		Message:     "",
-		CompletedAt: time.Now(),
+func (uc *statement) markUploadAsCompleted(ctx context.Context, info *upload.Task) {
+	info.Status = upload.StatusCompleted
+	var notes []string
+	if info.RejectedRows > 0 {
+		info.Status = upload.StatusCompletedWithErrors
+		notes = append(notes, fmt.Sprintf("%d rows rejected", info.RejectedRows))
+	}
+	if info.DuplicateRows > 0 {
+		notes = append(notes, fmt.Sprintf("%d duplicate transactions %s", info.DuplicateRows, dedupOutcomes[info.DedupPolicy]))
+	}
+	if check := info.BalanceCheck; check != nil && !check.Balanced() {
+		info.Status = upload.StatusCompletedWithErrors
+		notes = append(notes, fmt.Sprintf("closing balance is off by %s, expected %s but transactions add up to %s", money.New(check.Discrepancy, check.Currency), money.New(check.ExpectedClosing, check.Currency), money.New(check.ComputedClosing(), check.Currency)))
 	}
+	for _, mismatch := range info.TrailerMismatches {
+		info.Status = upload.StatusCompletedWithErrors
+		notes = append(notes, trailerNote(mismatch, info.Currency, info.Details))
+	}
+	info.Message = strings.Join(notes, ", ")
+	info.CompletedAt = time.Now()
 
 	err := uc.uploadRepo.Update(ctx, info)
 	if err != nil {
-		log.Info(ctx, fmt.Sprint("failed to mark upload as failed:", err.Error()))
+		log.Info(ctx, fmt.Sprint("failed to mark upload as completed:", err.Error()))
 	}
 }
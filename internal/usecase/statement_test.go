@@ -1,11 +1,15 @@
 package usecase
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
 	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
 	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+	"github.com/mj3smile/bank-statement-processor/internal/parser"
 )
 
 func Test_statement_parseTransaction(t *testing.T) {
@@ -207,3 +211,67 @@ func Test_statement_parseTransaction(t *testing.T) {
 		})
 	}
 }
+
+func Test_statement_toTransaction(t *testing.T) {
+	task := &upload.Task{ID: upload.ID(uuid.NewString()), Currency: "EUR"}
+	fields := []string{"1674507883", "JOHN DOE", "DEBIT", "250000", "SUCCESS", "restaurant"}
+
+	rules := transaction.NewRuleSet([]*transaction.Rule{
+		{Name: "large-usd", Kind: transaction.RuleMaxAmount, Severity: transaction.SeverityReject, Currency: "USD", MaxAmount: 100000},
+		{Name: "large-eur", Kind: transaction.RuleMaxAmount, Severity: transaction.SeverityFlag, Currency: "EUR", MaxAmount: 100000},
+	}, time.Now())
+
+	tests := []struct {
+		name            string
+		record          *parser.Record
+		rules           *transaction.RuleSet
+		wantCurrency    money.Currency
+		wantRuleResults []transaction.RuleResult
+		wantErr         bool
+	}{
+		{
+			name:         "it should use the currency of the upload when record has none",
+			record:       &parser.Record{Fields: fields},
+			wantCurrency: "EUR",
+		},
+		{
+			name:         "it should use the currency of the record when given one",
+			record:       &parser.Record{Fields: fields, Currency: "usd"},
+			wantCurrency: "USD",
+		},
+		{
+			name:    "it should return error when record has unknown currency",
+			record:  &parser.Record{Fields: fields, Currency: "XYZ"},
+			wantErr: true,
+		},
+		{
+			name:            "it should attach the rules the transaction broke",
+			record:          &parser.Record{Fields: fields},
+			rules:           rules,
+			wantCurrency:    "EUR",
+			wantRuleResults: []transaction.RuleResult{{Rule: "large-eur", Severity: transaction.SeverityFlag, Message: "amount 250000 exceeds 100000"}},
+		},
+		{
+			name:    "it should return error when a rule rejects the transaction",
+			record:  &parser.Record{Fields: fields, Currency: "USD"},
+			rules:   rules,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &statement{}
+			got, err := uc.toTransaction(tt.record, task, tt.rules)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("toTransaction() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != nil && got.Currency != tt.wantCurrency {
+				t.Errorf("toTransaction() currency = %v, want %v", got.Currency, tt.wantCurrency)
+			}
+			if got != nil && !reflect.DeepEqual(got.RuleResults, tt.wantRuleResults) {
+				t.Errorf("toTransaction() rule results = %v, want %v", got.RuleResults, tt.wantRuleResults)
+			}
+		})
+	}
+}
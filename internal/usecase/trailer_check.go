@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/money"
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+	"github.com/mj3smile/bank-statement-processor/internal/parser"
+)
+
+// statementTotals adds up a statement the way its trailer states it: every row read counts, rejected
+// ones included, and so does every transaction in the statement currency whatever its status.
+type statementTotals struct {
+	currency money.Currency
+	rows     int64
+	credits  money.Money
+	debits   money.Money
+}
+
+func newStatementTotals(currency money.Currency) *statementTotals {
+	return &statementTotals{currency: currency, credits: money.New(0, currency), debits: money.New(0, currency)}
+}
+
+func (s *statementTotals) add(t *transaction.Transaction) error {
+	if t.Currency != s.currency {
+		return nil
+	}
+
+	var err error
+	switch t.Type {
+	case transaction.TypeCredit:
+		s.credits, err = s.credits.Add(t.Money())
+	case transaction.TypeDebit:
+		s.debits, err = s.debits.Add(t.Money())
+	}
+	return err
+}
+
+// check compares the totals a trailer states with those of the rows read and returns those that disagree.
+func (s *statementTotals) check(details upload.StatementDetails) ([]upload.TrailerMismatch, error) {
+	net, err := s.credits.Sub(s.debits)
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatches []upload.TrailerMismatch
+	for _, total := range []struct {
+		field    string
+		stated   *int64
+		ingested int64
+	}{
+		{upload.TrailerRowCount, details.RowCount, s.rows},
+		{upload.TrailerTotalCredits, details.TotalCredits, s.credits.Amount},
+		{upload.TrailerTotalDebits, details.TotalDebits, s.debits.Amount},
+		{upload.TrailerNetTotal, details.NetTotal, net.Amount},
+	} {
+		if total.stated != nil && *total.stated != total.ingested {
+			mismatches = append(mismatches, upload.TrailerMismatch{Field: total.field, Stated: *total.stated, Ingested: total.ingested})
+		}
+	}
+	return mismatches, nil
+}
+
+// trailerNote describes a trailer total that disagrees with the rows read.
+func trailerNote(mismatch upload.TrailerMismatch, currency money.Currency, details *upload.StatementDetails) string {
+	if mismatch.Field == upload.TrailerRowCount {
+		return fmt.Sprintf("trailer states %d rows but %d were read", mismatch.Stated, mismatch.Ingested)
+	}
+	if details != nil && details.Currency != "" {
+		currency = details.Currency
+	}
+	return fmt.Sprintf("trailer states %s of %s but transactions add up to %s", strings.ReplaceAll(mismatch.Field, "_", " "), money.New(mismatch.Stated, currency), money.New(mismatch.Ingested, currency))
+}
+
+// totalsCurrency is the currency a trailer states its totals in.
+func totalsCurrency(task *upload.Task, reader parser.Reader) money.Currency {
+	if details, ok := statementDetails(reader); ok && details.Currency != "" {
+		return details.Currency
+	}
+	return task.Currency
+}
+
+// statementDetails returns what a statement states about itself, false when it states nothing.
+func statementDetails(reader parser.Reader) (upload.StatementDetails, bool) {
+	metadataReader, ok := reader.(parser.MetadataReader)
+	if !ok {
+		return upload.StatementDetails{}, false
+	}
+	details := metadataReader.Metadata().Details
+	return details, !details.IsZero()
+}
+
+// adoptStatementCurrency applies the currency a statement states in its preamble to an upload that did
+// not declare one, and to the balances declared with it.
+func adoptStatementCurrency(task *upload.Task, opts parser.Options, reader parser.Reader) {
+	details, ok := statementDetails(reader)
+	if !opts.StatementCurrency || !ok || details.Currency == "" {
+		return
+	}
+
+	task.Currency = details.Currency
+	if task.OpeningBalance != nil {
+		task.OpeningBalance = &upload.Balance{Amount: task.OpeningBalance.Amount, Currency: details.Currency}
+	}
+	if task.ClosingBalance != nil {
+		task.ClosingBalance = &upload.Balance{Amount: task.ClosingBalance.Amount, Currency: details.Currency}
+	}
+}
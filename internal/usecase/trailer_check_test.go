@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/transaction"
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+)
+
+func Test_statementTotals_check(t *testing.T) {
+	transactions := []*transaction.Transaction{
+		{Type: transaction.TypeCredit, Amount: 500000, Currency: "IDR", Status: transaction.StatusSuccess},
+		{Type: transaction.TypeDebit, Amount: 250000, Currency: "IDR", Status: transaction.StatusSuccess},
+		{Type: transaction.TypeDebit, Amount: 75000, Currency: "IDR", Status: transaction.StatusFailed},
+		{Type: transaction.TypeCredit, Amount: 1000, Currency: "USD", Status: transaction.StatusSuccess},
+	}
+	totals := newStatementTotals("IDR")
+	for _, tx := range transactions {
+		totals.rows++
+		if err := totals.add(tx); err != nil {
+			t.Fatalf("add() error = %v", err)
+		}
+	}
+	int64Ptr := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		name    string
+		details upload.StatementDetails
+		want    []upload.TrailerMismatch
+	}{
+		{
+			name: "it should agree with a trailer counting every row and every transaction in the statement currency",
+			details: upload.StatementDetails{
+				RowCount:     int64Ptr(4),
+				TotalCredits: int64Ptr(500000),
+				TotalDebits:  int64Ptr(325000),
+				NetTotal:     int64Ptr(175000),
+			},
+		},
+		{
+			name:    "it should report the totals that disagree",
+			details: upload.StatementDetails{RowCount: int64Ptr(5), TotalCredits: int64Ptr(500000), TotalDebits: int64Ptr(400000)},
+			want: []upload.TrailerMismatch{
+				{Field: upload.TrailerRowCount, Stated: 5, Ingested: 4},
+				{Field: upload.TrailerTotalDebits, Stated: 400000, Ingested: 325000},
+			},
+		},
+		{
+			name:    "it should not check totals the trailer does not state",
+			details: upload.StatementDetails{AccountNumber: "1234567890"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := totals.check(tt.details)
+			if err != nil {
+				t.Fatalf("check() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+	"github.com/mj3smile/bank-statement-processor/internal/repository"
+)
+
+var (
+	ErrUploadNotFound = errors.New("upload not found")
+	ErrBatchNotFound  = errors.New("batch not found")
+)
+
+type Upload interface {
+	// GetUpload returns the task of an upload with the progress of its processing.
+	GetUpload(ctx context.Context, uploadID string) (*upload.Task, error)
+	GetRejections(ctx context.Context, uploadID string, page, pageSize int) (*RejectionsResult, error)
+	// GetDuplicates returns the transactions of an upload that an earlier upload of its account imported already.
+	GetDuplicates(ctx context.Context, uploadID string, page, pageSize int) (*DuplicatesResult, error)
+	// GetBatch returns a batch with the uploads of its statements, in archive order.
+	GetBatch(ctx context.Context, batchID string) (*BatchResult, error)
+}
+
+type uploads struct {
+	uploadRepo repository.UploadRepository
+	batchRepo  repository.BatchRepository
+}
+
+type RejectionsResult struct {
+	UploadTaskStatus string
+	Rejections       []*upload.Rejection
+	TotalCount       int
+}
+
+type DuplicatesResult struct {
+	Task       *upload.Task
+	Decisions  []*upload.DedupDecision
+	TotalCount int
+}
+
+type BatchResult struct {
+	Batch   *upload.Batch
+	Uploads []*upload.Task
+	// StatusCounts holds the number of uploads in each status
+	StatusCounts map[upload.Status]int
+}
+
+func NewUpload(uploadRepo repository.UploadRepository, batchRepo repository.BatchRepository) Upload {
+	return &uploads{
+		uploadRepo: uploadRepo,
+		batchRepo:  batchRepo,
+	}
+}
+
+func (u *uploads) GetUpload(ctx context.Context, uploadID string) (*upload.Task, error) {
+	task, err := u.uploadRepo.GetByID(ctx, upload.ID(uploadID))
+	if err != nil {
+		return nil, ErrUploadNotFound
+	}
+	return task, nil
+}
+
+func (u *uploads) GetRejections(ctx context.Context, uploadID string, page, pageSize int) (*RejectionsResult, error) {
+	task, err := u.uploadRepo.GetByID(ctx, upload.ID(uploadID))
+	if err != nil {
+		return nil, errors.New("upload not found")
+	}
+
+	rejections, totalCount, err := u.uploadRepo.GetRejections(ctx, task.ID, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RejectionsResult{
+		UploadTaskStatus: string(task.Status),
+		Rejections:       rejections,
+		TotalCount:       totalCount,
+	}, nil
+}
+
+func (u *uploads) GetDuplicates(ctx context.Context, uploadID string, page, pageSize int) (*DuplicatesResult, error) {
+	task, err := u.uploadRepo.GetByID(ctx, upload.ID(uploadID))
+	if err != nil {
+		return nil, errors.New("upload not found")
+	}
+
+	decisions, totalCount, err := u.uploadRepo.GetDedupDecisions(ctx, task.ID, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DuplicatesResult{
+		Task:       task,
+		Decisions:  decisions,
+		TotalCount: totalCount,
+	}, nil
+}
+
+func (u *uploads) GetBatch(ctx context.Context, batchID string) (*BatchResult, error) {
+	batch, err := u.batchRepo.GetByID(ctx, upload.BatchID(batchID))
+	if err != nil {
+		return nil, ErrBatchNotFound
+	}
+
+	result := &BatchResult{
+		Batch:        batch,
+		Uploads:      make([]*upload.Task, 0, len(batch.UploadIDs)),
+		StatusCounts: make(map[upload.Status]int),
+	}
+	for _, uploadID := range batch.UploadIDs {
+		task, err := u.uploadRepo.GetByID(ctx, uploadID)
+		if err != nil {
+			return nil, err
+		}
+		result.Uploads = append(result.Uploads, task)
+		result.StatusCounts[task.Status]++
+	}
+
+	return result, nil
+}
@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+	"github.com/mj3smile/bank-statement-processor/internal/parser"
+)
+
+// maxValidationErrors is how many invalid rows a validation report lists, the rest are only counted
+const maxValidationErrors = 10000
+
+// ValidationReport is what a dry run found in a statement.
+type ValidationReport struct {
+	// Layout is the task the statement would have been uploaded as. It is never saved.
+	Layout *upload.Task
+	// Rows counts the rows read, ValidRows those that would become transactions and InvalidRows
+	// those that would fail a strict upload or be rejected by a lenient one
+	Rows        int
+	ValidRows   int
+	InvalidRows int
+	// Errors lists the invalid rows in the order they were read, Truncated is set when there were
+	// more than maxValidationErrors of them
+	Errors    []*upload.Rejection
+	Truncated bool
+	// Fatal is set when the statement could not be read to the end, the counts then cover the rows
+	// before it
+	Fatal string
+	// ContentHash is the hash the upload would be known by, empty when the file was not read to the end
+	ContentHash string
+	// BalanceCheck is set when the file was read to the end and both statement balances are known
+	BalanceCheck *upload.BalanceCheck
+	// Details is what the statement states about itself, TrailerMismatches the trailer totals that
+	// disagree with the rows read. Both are only set when the file was read to the end.
+	Details           *upload.StatementDetails
+	TrailerMismatches []upload.TrailerMismatch
+}
+
+// Valid reports whether uploading the statement in strict mode would succeed.
+func (r *ValidationReport) Valid() bool {
+	return r.Fatal == "" && r.InvalidRows == 0
+}
+
+func (uc *statement) Validate(ctx context.Context, file io.Reader, filename string, opts UploadOptions) (*ValidationReport, error) {
+	parserOpts, err := uc.parserOptions(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := bufio.NewReaderSize(file, encodingSniffSize)
+	rawHead, err := raw.Peek(encodingSniffSize)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	archive := parser.DetectArchive(filename, rawHead)
+	if archive.IsMultiFile() {
+		return nil, fmt.Errorf("%w: statements of an archive are validated one at a time", ErrInvalidArchive)
+	}
+
+	prepared, err := uc.prepare(ctx, raw, filename, archive, "", newUnpackLimit(uc.archiveLimits.MaxSize), opts, parserOpts)
+	if err != nil {
+		return nil, err
+	}
+	report := &ValidationReport{Layout: prepared.task}
+
+	reader, err := prepared.parser.NewReader(prepared.source, prepared.parserOpts)
+	if err != nil {
+		return uc.validationFailed(prepared, report, err.Error())
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+	adoptStatementCurrency(prepared.task, prepared.parserOpts, reader)
+
+	net := statementNet{}
+	totals := newStatementTotals(totalsCurrency(prepared.task, reader))
+	lineNumber := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		totals.rows++
+
+		var rowErr *parser.RowError
+		if errors.As(err, &rowErr) {
+			lineNumber = rowErr.Line
+			report.invalid(rowErr.Line, rowErr.Raw, rowErr.Err)
+			continue
+		}
+		if err != nil {
+			return uc.validationFailed(prepared, report, fmt.Sprintf("error after line %d: %v", lineNumber, err))
+		}
+
+		lineNumber = record.Line
+		t, err := uc.toTransaction(record, prepared.task, prepared.rules)
+		if err == nil {
+			err = net.add(t)
+		}
+		if err == nil {
+			err = totals.add(t)
+		}
+		if err != nil {
+			report.invalid(record.Line, record.Raw, err)
+			continue
+		}
+		report.Rows++
+		report.ValidRows++
+	}
+
+	if _, err := io.Copy(io.Discard, prepared.source); err != nil {
+		return uc.validationFailed(prepared, report, fmt.Sprintf("failed to read file: %v", err))
+	}
+	report.ContentHash = hex.EncodeToString(prepared.contentHash.Sum(nil))
+	// balances in different currencies are left unchecked, as they are by an upload
+	opening, closing := statementBalances(prepared.task, reader)
+	report.BalanceCheck, _ = checkBalance(opening, closing, net)
+	if details, ok := statementDetails(reader); ok {
+		report.Details = &details
+		report.TrailerMismatches, _ = totals.check(details)
+	}
+	return report, nil
+}
+
+// validationFailed ends a dry run that could not read the statement to the end. A file that could not be
+// read at all, because the request was cut short or too large, is an error rather than a finding.
+func (uc *statement) validationFailed(prepared *preparedUpload, report *ValidationReport, reason string) (*ValidationReport, error) {
+	if prepared.counter.err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", prepared.counter.err)
+	}
+	report.Fatal = reason
+	return report, nil
+}
+
+// invalid counts a row that would be rejected and lists it while the report has room.
+func (r *ValidationReport) invalid(line int, raw string, reason error) {
+	r.Rows++
+	r.InvalidRows++
+	if len(r.Errors) == maxValidationErrors {
+		r.Truncated = true
+		return
+	}
+	r.Errors = append(r.Errors, &upload.Rejection{Line: line, Raw: raw, Error: reason.Error()})
+}
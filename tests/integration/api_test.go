@@ -1,17 +1,26 @@
 package integration
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf16"
 
 	"github.com/mj3smile/bank-statement-processor/internal/event"
 	"github.com/mj3smile/bank-statement-processor/internal/event/consumer"
@@ -19,32 +28,198 @@ import (
 	"github.com/mj3smile/bank-statement-processor/internal/infra/log"
 	"github.com/mj3smile/bank-statement-processor/internal/infra/server"
 	"github.com/mj3smile/bank-statement-processor/internal/model/upload"
+	"github.com/mj3smile/bank-statement-processor/internal/parser"
 	repository "github.com/mj3smile/bank-statement-processor/internal/repository/memory"
 	"github.com/mj3smile/bank-statement-processor/internal/usecase"
 )
 
-func TestFullWorkflow_UploadProcessQuery(t *testing.T) {
+// maxUploadSize is the upload size limit of the test server
+const maxUploadSize = 1 << 20
+
+type testApp struct {
+	appCtx                 context.Context
+	router                 http.Handler
+	reconciliationConsumer *consumer.ReconciliationConsumer
+}
+
+// testAppOption changes the configuration the test server processes statements with.
+type testAppOption func(*usecase.StatementConfig)
+
+// withIngestion limits concurrent processing.
+func withIngestion(ingestion usecase.IngestionConfig) testAppOption {
+	return func(config *usecase.StatementConfig) { config.Ingestion = ingestion }
+}
+
+// withArchive limits what archives and compressed statements may unpack to.
+func withArchive(archive usecase.ArchiveConfig) testAppOption {
+	return func(config *usecase.StatementConfig) { config.Archive = archive }
+}
+
+// withRules checks transactions against the given business rules.
+func withRules(rules usecase.Rules) testAppOption {
+	return func(config *usecase.StatementConfig) { config.Rules = rules }
+}
+
+func newTestApp(t *testing.T, opts ...testAppOption) *testApp {
 	appCtx, appCancel := context.WithCancel(context.Background())
-	defer appCancel()
 
 	eventBus := event.NewBus(appCtx)
 	uploadRepo := repository.NewUploadRepository()
+	batchRepo := repository.NewBatchRepository()
+	accountRepo := repository.NewAccountRepository()
 	transactionRepo := repository.NewTransactionRepository()
-	defer eventBus.Close()
+	profileRepo := repository.NewProfileRepository()
+	fxRateRepo := repository.NewFXRateRepository()
+	t.Cleanup(func() {
+		eventBus.Close()
+		appCancel()
+	})
 
-	statementUseCase := usecase.NewStatement(appCtx, transactionRepo, uploadRepo, eventBus)
-	balanceUseCase := usecase.NewBalance(transactionRepo, uploadRepo)
-	issuesUseCase := usecase.NewIssues(transactionRepo, uploadRepo)
+	config := usecase.StatementConfig{
+		TransactionRepo: transactionRepo,
+		UploadRepo:      uploadRepo,
+		BatchRepo:       batchRepo,
+		AccountRepo:     accountRepo,
+		ProfileRepo:     profileRepo,
+		EventBus:        eventBus,
+		Parsers:         parser.NewDefaultRegistry(),
+		Rules:           usecase.NewRules(""),
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	statementUseCase := usecase.NewStatement(appCtx, config)
+	balanceUseCase := usecase.NewBalance(transactionRepo, uploadRepo, fxRateRepo)
+	issuesUseCase := usecase.NewIssues(transactionRepo, uploadRepo, fxRateRepo)
+	profileUseCase := usecase.NewProfile(profileRepo)
+	uploadUseCase := usecase.NewUpload(uploadRepo, batchRepo)
+	fxUseCase := usecase.NewFX(fxRateRepo)
+	accountUseCase := usecase.NewAccount(accountRepo, fxRateRepo)
 
-	statementHandler := handler.NewStatementHandler(statementUseCase)
+	statementHandler := handler.NewStatementHandler(statementUseCase, maxUploadSize)
 	balanceHandler := handler.NewBalanceHandler(balanceUseCase)
 	issuesHandler := handler.NewIssuesHandler(issuesUseCase)
 	healthHandler := handler.NewHealthHandler()
+	profileHandler := handler.NewProfileHandler(profileUseCase)
+	uploadHandler := handler.NewUploadHandler(uploadUseCase)
+	fxHandler := handler.NewFXHandler(fxUseCase)
+	accountHandler := handler.NewAccountHandler(accountUseCase)
 
 	reconciliationConsumer := consumer.NewReconciliationConsumer(eventBus, 3)
 	go reconciliationConsumer.Start(appCtx)
+	// the consumer subscribes when it starts, events published before that are not delivered to it
+	for eventBus.(interface{ GetSubscriberCount() int }).GetSubscriberCount() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	return &testApp{
+		appCtx:                 appCtx,
+		router:                 server.NewRouter(statementHandler, balanceHandler, issuesHandler, healthHandler, profileHandler, uploadHandler, fxHandler, accountHandler),
+		reconciliationConsumer: reconciliationConsumer,
+	}
+}
+
+// uploadStatement posts a statement file together with the given form fields and returns the upload ID.
+func uploadStatement(t *testing.T, router http.Handler, filename, content string, fields map[string]string) string {
+	response := postStatement(t, router, filename, content, fields)
+	if response.UploadID == "" {
+		t.Fatalf("upload_id is empty")
+	}
+
+	return response.UploadID
+}
+
+// postStatement posts a statement file together with the given form fields and returns the response.
+func postStatement(t *testing.T, router http.Handler, filename, content string, fields map[string]string) handler.UploadStatementResponse {
+	w := sendStatement(t, router, filename, content, fields)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("http response: status code: got = %v, want %v (%s)", w.Code, http.StatusAccepted, w.Body.String())
+	}
+
+	var response handler.UploadStatementResponse
+	json.NewDecoder(w.Body).Decode(&response)
+	return response
+}
+
+// sendStatement posts a statement file together with the given form fields and returns the answer, whatever it is.
+func sendStatement(t *testing.T, router http.Handler, filename, content string, fields map[string]string) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			t.Fatalf("error while writing form field %s: %v", name, err)
+		}
+	}
+
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("error while creating form file: %v", err)
+	}
+	if _, err := io.WriteString(part, content); err != nil {
+		t.Fatalf("error while writing file content: %v", err)
+	}
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/statements", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
 
-	router := server.NewRouter(statementHandler, balanceHandler, issuesHandler, healthHandler)
+// getJSON sends a GET request to path and decodes the response into v. It returns the status code.
+func getJSON(t *testing.T, router http.Handler, path string, v any) int {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	json.NewDecoder(w.Body).Decode(v)
+	return w.Code
+}
+
+// waitForUpload waits until an upload has finished processing and returns it.
+func waitForUpload(t *testing.T, router http.Handler, uploadID string) handler.GetUploadResponse {
+	t.Helper()
+	var response handler.GetUploadResponse
+	waitUntilFinished(t, "upload "+uploadID, func() string {
+		response = handler.GetUploadResponse{}
+		getJSON(t, router, "/uploads/"+uploadID, &response)
+		return response.Status
+	})
+	return response
+}
+
+// waitForBatch waits until every statement of a batch has been processed and returns the batch.
+func waitForBatch(t *testing.T, router http.Handler, batchID string) handler.GetBatchResponse {
+	t.Helper()
+	var response handler.GetBatchResponse
+	waitUntilFinished(t, "batch "+batchID, func() string {
+		response = handler.GetBatchResponse{}
+		getJSON(t, router, "/batches/"+batchID, &response)
+		return response.Status
+	})
+	return response
+}
+
+// waitUntilFinished polls status until it is neither queued nor processing.
+func waitUntilFinished(t *testing.T, name string, status func() string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		switch upload.Status(status()) {
+		case "", upload.StatusQueued, upload.StatusProcessing:
+			time.Sleep(10 * time.Millisecond)
+		default:
+			return
+		}
+	}
+	t.Fatalf("%s is still processing", name)
+}
+
+func TestFullWorkflow_UploadProcessQuery(t *testing.T) {
+	app := newTestApp(t)
+	appCtx, router, reconciliationConsumer := app.appCtx, app.router, app.reconciliationConsumer
 
 	csvContent := `timestamp,counterparty,type,amount,status,description
 1674507883,JOHN DOE,DEBIT,250000,SUCCESS,restaurant
@@ -152,3 +327,1856 @@ func TestFullWorkflow_UploadProcessQuery(t *testing.T) {
 		log.Info(appCtx, fmt.Sprint("processed count:", processedCount))
 	})
 }
+
+func TestLenientUpload_QuarantinesInvalidRows(t *testing.T) {
+	app := newTestApp(t)
+
+	csvContent := `timestamp,counterparty,type,amount,status,description
+1674507883,JOHN DOE,DEBIT,250000,SUCCESS,restaurant
+not-a-timestamp,ACME CORP,CREDIT,1500000,SUCCESS,salary
+1674508456,JANE SMITH,DEBIT,75000,SUCCESS,gift
+1674508789,BOB BROWN,REFUND,100000,SUCCESS,processing
+1674509012,ALICE GREEN,CREDIT,500000,SUCCESS,consulting`
+
+	uploadID := uploadStatement(t, app.router, "test.csv", csvContent, map[string]string{"mode": "lenient"})
+
+	waitForUpload(t, app.router, uploadID)
+
+	t.Run("Get Balance", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/balance?upload_id="+uploadID, nil)
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, req)
+
+		var response handler.GetBalanceResponse
+		json.NewDecoder(w.Body).Decode(&response)
+
+		if !reflect.DeepEqual(upload.StatusCompletedWithErrors, upload.Status(response.Status)) {
+			t.Errorf("http response: field status: got = %v, want %v", response.Status, upload.StatusCompletedWithErrors)
+		}
+
+		// 500000 (credit) - 250000 (debit) - 75000 (debit) = 175000
+		if response.Balance == nil || *response.Balance != 175000 {
+			t.Errorf("http response: field balance: got = %v, want %v", response.Balance, int64(175000))
+		}
+	})
+
+	t.Run("Get Rejections", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/uploads/"+uploadID+"/rejections", nil)
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("http response status code: got = %v, want %v", w.Code, http.StatusOK)
+		}
+
+		var response handler.GetRejectionsResponse
+		json.NewDecoder(w.Body).Decode(&response)
+
+		gotLines := make([]int, 0)
+		for _, rejection := range response.Rejections {
+			gotLines = append(gotLines, rejection.Line)
+		}
+		if !reflect.DeepEqual([]int{3, 5}, gotLines) {
+			t.Errorf("http response: rejected lines: got = %v, want %v", gotLines, []int{3, 5})
+		}
+
+		if len(response.Rejections) > 0 && response.Rejections[0].Raw != "not-a-timestamp,ACME CORP,CREDIT,1500000,SUCCESS,salary" {
+			t.Errorf("http response: field raw: got = %v", response.Rejections[0].Raw)
+		}
+	})
+}
+
+func TestStrictUpload_FailureLeavesNothingVisible(t *testing.T) {
+	app := newTestApp(t)
+
+	csvContent := `timestamp,counterparty,type,amount,status,description
+1674507883,JOHN DOE,DEBIT,250000,SUCCESS,restaurant
+1674508456,JANE SMITH,DEBIT,75000,FAILED,payment failed
+1674508789,BOB BROWN,DEBIT,100000,PENDING,processing
+1674509012,ALICE GREEN,REFUND,500000,SUCCESS,consulting`
+
+	uploadID := uploadStatement(t, app.router, "test.csv", csvContent, nil)
+
+	waitForUpload(t, app.router, uploadID)
+
+	t.Run("Get Balance", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/balance?upload_id="+uploadID, nil)
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, req)
+
+		var response handler.GetBalanceResponse
+		json.NewDecoder(w.Body).Decode(&response)
+
+		if !reflect.DeepEqual(upload.StatusFailed, upload.Status(response.Status)) {
+			t.Errorf("http response: field status: got = %v, want %v", response.Status, upload.StatusFailed)
+		}
+		if response.Balance != nil {
+			t.Errorf("http response: field balance: got = %v, want nil", *response.Balance)
+		}
+	})
+
+	t.Run("Get Issues", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/transactions/issues?upload_id="+uploadID, nil)
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, req)
+
+		var response handler.GetIssuesResponse
+		json.NewDecoder(w.Body).Decode(&response)
+
+		if response.Pagination.TotalItems != 0 {
+			t.Errorf("http response: field total_items: got = %v, want %v", response.Pagination.TotalItems, 0)
+		}
+	})
+}
+
+func TestMultiCurrencyUpload_BalancesPerCurrency(t *testing.T) {
+	app := newTestApp(t)
+
+	csvContent := `timestamp,counterparty,type,amount,status,description,currency
+1674507883,JOHN DOE,DEBIT,2500,SUCCESS,restaurant,USD
+1674508123,ACME CORP,CREDIT,150000,SUCCESS,salary,USD
+1674508456,JANE SMITH,CREDIT,7550,SUCCESS,gift,EUR
+1674508789,BOB BROWN,CREDIT,100000,SUCCESS,refund,`
+
+	uploadID := uploadStatement(t, app.router, "test.csv", csvContent, map[string]string{"currency": "JPY"})
+
+	waitForUpload(t, app.router, uploadID)
+
+	req := httptest.NewRequest("GET", "/balance?upload_id="+uploadID, nil)
+	w := httptest.NewRecorder()
+	app.router.ServeHTTP(w, req)
+
+	var response handler.GetBalanceResponse
+	json.NewDecoder(w.Body).Decode(&response)
+
+	if !reflect.DeepEqual(upload.StatusCompleted, upload.Status(response.Status)) {
+		t.Fatalf("http response: field status: got = %v, want %v (%s)", response.Status, upload.StatusCompleted, response.Message)
+	}
+	if response.Balance != nil {
+		t.Errorf("http response: field balance: got = %v, want nil for several currencies", *response.Balance)
+	}
+
+	want := []handler.CurrencyBalanceDTO{
+		{Currency: "EUR", Amount: 7550, Decimal: "75.50"},
+		{Currency: "JPY", Amount: 100000, Decimal: "100000"},
+		{Currency: "USD", Amount: 147500, Decimal: "1475.00"},
+	}
+	if !reflect.DeepEqual(want, response.Balances) {
+		t.Errorf("http response: field balances: got = %v, want %v", response.Balances, want)
+	}
+}
+
+func TestUpload_ReportsAssumedCurrency(t *testing.T) {
+	csvContent := "timestamp,counterparty,type,amount,status,description\n1674507883,JOHN DOE,DEBIT,250000,SUCCESS,restaurant\n"
+
+	tests := []struct {
+		name         string
+		fields       map[string]string
+		wantAssumed  string
+		wantCurrency string
+	}{
+		{name: "it should return the currency assumed for amounts when the upload declares none", wantAssumed: "IDR", wantCurrency: "IDR"},
+		{name: "it should not return an assumed currency when the upload declares one", fields: map[string]string{"currency": "USD"}, wantCurrency: "USD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t)
+
+			response := postStatement(t, app.router, "statement.csv", csvContent, tt.fields)
+			if response.AssumedCurrency != tt.wantAssumed {
+				t.Errorf("http response: field assumed_currency: got = %q, want %q", response.AssumedCurrency, tt.wantAssumed)
+			}
+
+			waitForUpload(t, app.router, response.UploadID)
+
+			req := httptest.NewRequest("GET", "/balance?upload_id="+response.UploadID, nil)
+			w := httptest.NewRecorder()
+			app.router.ServeHTTP(w, req)
+
+			var balance handler.GetBalanceResponse
+			json.NewDecoder(w.Body).Decode(&balance)
+			if len(balance.Balances) != 1 || balance.Balances[0].Currency != tt.wantCurrency {
+				t.Errorf("http response: field balances: got = %+v, want one in %v", balance.Balances, tt.wantCurrency)
+			}
+		})
+	}
+}
+
+func TestReportingCurrency_ConvertsAtDailyRates(t *testing.T) {
+	app := newTestApp(t)
+
+	rates := `date,base,quote,rate
+2023-01-20,USD,IDR,15000
+2023-01-23,USD,IDR,15100
+2023-01-22,IDR,EUR,0.00005`
+
+	t.Run("Load Rates", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/admin/fx-rates", bytes.NewBufferString(rates))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("http response status code: got = %v, want %v (%s)", w.Code, http.StatusOK, w.Body.String())
+		}
+
+		var response handler.LoadRatesResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		if response.Loaded != 3 {
+			t.Errorf("http response: field loaded: got = %v, want %v", response.Loaded, 3)
+		}
+	})
+
+	csvContent := `timestamp,counterparty,type,amount,status,description,currency
+1674507883,ACME CORP,CREDIT,100000,SUCCESS,invoice,USD
+1674507883,EURO GMBH,CREDIT,1000,SUCCESS,refund,EUR
+1674507883,JOHN DOE,DEBIT,5000000,SUCCESS,restaurant,IDR
+1674300000,JANE SMITH,DEBIT,1000,FAILED,payment failed,USD`
+
+	uploadID := uploadStatement(t, app.router, "test.csv", csvContent, nil)
+
+	waitForUpload(t, app.router, uploadID)
+
+	t.Run("Get Balance", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/balance?upload_id="+uploadID+"&reporting_currency=IDR", nil)
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("http response status code: got = %v, want %v (%s)", w.Code, http.StatusOK, w.Body.String())
+		}
+
+		var response handler.GetBalanceResponse
+		json.NewDecoder(w.Body).Decode(&response)
+
+		// 100000 USD cents at 15100 + 1000 EUR cents at 1/0.00005 - 5000000 IDR cents
+		want := &handler.ReportingBalanceDTO{
+			Currency: "IDR",
+			Amount:   1525000000,
+			Decimal:  "15250000.00",
+			Rates: []handler.FXRateDTO{
+				{Date: "2023-01-22", Base: "EUR", Quote: "IDR", Rate: "20000"},
+				{Date: "2023-01-23", Base: "USD", Quote: "IDR", Rate: "15100"},
+			},
+		}
+		if !reflect.DeepEqual(want, response.Reporting) {
+			t.Errorf("http response: field reporting: got = %+v, want %+v", response.Reporting, want)
+		}
+	})
+
+	t.Run("Get Issues", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/transactions/issues?upload_id="+uploadID+"&reporting_currency=IDR", nil)
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, req)
+
+		var response handler.GetIssuesResponse
+		json.NewDecoder(w.Body).Decode(&response)
+
+		if len(response.Transactions) != 1 || response.Transactions[0].ReportingAmount == nil {
+			t.Fatalf("http response: field transactions: got = %+v", response.Transactions)
+		}
+		if got := *response.Transactions[0].ReportingAmount; got != 15000000 {
+			t.Errorf("http response: field reporting_amount: got = %v, want %v", got, 15000000)
+		}
+		wantRates := []handler.FXRateDTO{{Date: "2023-01-20", Base: "USD", Quote: "IDR", Rate: "15000"}}
+		if !reflect.DeepEqual(wantRates, response.Rates) {
+			t.Errorf("http response: field rates: got = %v, want %v", response.Rates, wantRates)
+		}
+	})
+
+	t.Run("Amount Filters", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			query     string
+			wantCode  int
+			wantCount int
+		}{
+			{
+				name:     "it should reject amount filters without a currency",
+				query:    "&min_amount=500",
+				wantCode: http.StatusBadRequest,
+			},
+			{
+				name:      "it should compare amounts in minor units of the given currency",
+				query:     "&min_amount=500&currency=USD",
+				wantCode:  http.StatusOK,
+				wantCount: 1,
+			},
+			{
+				name:      "it should skip transactions in other currencies",
+				query:     "&min_amount=500&currency=IDR",
+				wantCode:  http.StatusOK,
+				wantCount: 0,
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var response handler.GetIssuesResponse
+				code := getJSON(t, app.router, "/transactions/issues?upload_id="+uploadID+tt.query, &response)
+				if code != tt.wantCode {
+					t.Fatalf("http response status code: got = %v, want %v", code, tt.wantCode)
+				}
+				if code == http.StatusOK && len(response.Transactions) != tt.wantCount {
+					t.Errorf("http response: field transactions: got = %+v, want %v transactions", response.Transactions, tt.wantCount)
+				}
+			})
+		}
+	})
+
+	t.Run("Missing Rate", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/balance?upload_id="+uploadID+"&reporting_currency=JPY", nil)
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, req)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("http response status code: got = %v, want %v", w.Code, http.StatusUnprocessableEntity)
+		}
+	})
+}
+
+func TestUploadWithTimestampFormatAndTimeZone(t *testing.T) {
+	app := newTestApp(t)
+
+	csvContent := `timestamp,counterparty,type,amount,status,description
+23/01/2024 14:05,JOHN DOE,DEBIT,250000,FAILED,restaurant
+24/01/2024 06:30,JANE SMITH,DEBIT,75000,FAILED,payment failed`
+
+	uploadID := uploadStatement(t, app.router, "test.csv", csvContent, map[string]string{
+		"timestamp_format": "DD/MM/YYYY HH:mm",
+		"timezone":         "Asia/Jakarta",
+	})
+
+	waitForUpload(t, app.router, uploadID)
+
+	tests := []struct {
+		name           string
+		query          string
+		wantTimestamps []int64
+	}{
+		{
+			name:           "it should normalize timestamps to UTC",
+			query:          "",
+			wantTimestamps: []int64{1705993500, 1706052600},
+		},
+		{
+			name:           "it should filter by RFC 3339 dates",
+			query:          "&from_date=2024-01-24T00:00:00%2B07:00",
+			wantTimestamps: []int64{1706052600},
+		},
+		{
+			name:           "it should filter by epoch dates",
+			query:          "&to_date=1706000000",
+			wantTimestamps: []int64{1705993500},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/transactions/issues?upload_id="+uploadID+tt.query, nil)
+			w := httptest.NewRecorder()
+			app.router.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("http response status code: got = %v, want %v (%s)", w.Code, http.StatusOK, w.Body.String())
+			}
+
+			var response handler.GetIssuesResponse
+			json.NewDecoder(w.Body).Decode(&response)
+
+			gotTimestamps := make([]int64, 0)
+			for _, transaction := range response.Transactions {
+				gotTimestamps = append(gotTimestamps, transaction.Timestamp)
+			}
+			if !reflect.DeepEqual(tt.wantTimestamps, gotTimestamps) {
+				t.Errorf("http response: timestamps: got = %v, want %v", gotTimestamps, tt.wantTimestamps)
+			}
+		})
+	}
+}
+
+func TestUploadWithSignedLocaleAmounts(t *testing.T) {
+	app := newTestApp(t)
+
+	profileBody := `{
+		"name": "euro-bank",
+		"delimiter": ";",
+		"columns": {
+			"timestamp": "Datum",
+			"counterparty": "Empfänger",
+			"amount": "Betrag",
+			"status": "Status",
+			"description": "Text"
+		},
+		"amount_layout": "signed",
+		"decimal_separator": ",",
+		"thousands_separator": ".",
+		"negative_parentheses": true
+	}`
+	req := httptest.NewRequest("POST", "/profiles", bytes.NewBufferString(profileBody))
+	w := httptest.NewRecorder()
+	app.router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("http response status code: got = %v, want %v (%s)", w.Code, http.StatusCreated, w.Body.String())
+	}
+
+	csvContent := `Datum;Empfänger;Betrag;Status;Text
+1674507883;ACME CORP;15.000,00;SUCCESS;salary
+1674508123;JOHN DOE;-1.234,56;SUCCESS;restaurant
+1674508456;JANE SMITH;(250,00);SUCCESS;fee`
+
+	uploadID := uploadStatement(t, app.router, "test.csv", csvContent, map[string]string{
+		"profile":  "euro-bank",
+		"currency": "EUR",
+	})
+
+	waitForUpload(t, app.router, uploadID)
+
+	req = httptest.NewRequest("GET", "/balance?upload_id="+uploadID, nil)
+	w = httptest.NewRecorder()
+	app.router.ServeHTTP(w, req)
+
+	var response handler.GetBalanceResponse
+	json.NewDecoder(w.Body).Decode(&response)
+
+	if !reflect.DeepEqual(upload.StatusCompleted, upload.Status(response.Status)) {
+		t.Fatalf("http response: field status: got = %v, want %v (%s)", response.Status, upload.StatusCompleted, response.Message)
+	}
+	want := []handler.CurrencyBalanceDTO{{Currency: "EUR", Amount: 1351544, Decimal: "13515.44"}}
+	if !reflect.DeepEqual(want, response.Balances) {
+		t.Errorf("http response: field balances: got = %v, want %v", response.Balances, want)
+	}
+}
+
+func TestUploadWithDetectedEncodingAndDelimiter(t *testing.T) {
+	app := newTestApp(t)
+
+	utf16Content := []byte{0xFF, 0xFE}
+	for _, unit := range utf16.Encode([]rune("timestamp;counterparty;type;amount;status;description\r\n" +
+		"1674507883;JÜRGEN MÜLLER;DEBIT;250000;FAILED;café\r\n")) {
+		utf16Content = append(utf16Content, byte(unit), byte(unit>>8))
+	}
+	windows1252Content := "timestamp\tcounterparty\ttype\tamount\tstatus\tdescription\n" +
+		"1674507883\tJOS\xc9 GARC\xcdA\tDEBIT\t75000\tFAILED\tpayment \x80\n"
+
+	tests := []struct {
+		name             string
+		content          string
+		wantCounterparty string
+		wantDescription  string
+	}{
+		{
+			name:             "it should read UTF-16 with BOM and semicolon delimiter",
+			content:          string(utf16Content),
+			wantCounterparty: "JÜRGEN MÜLLER",
+			wantDescription:  "café",
+		},
+		{
+			name:             "it should read Windows-1252 with tab delimiter",
+			content:          windows1252Content,
+			wantCounterparty: "JOSÉ GARCÍA",
+			wantDescription:  "payment €",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploadID := uploadStatement(t, app.router, "export.csv", tt.content, nil)
+
+			waitForUpload(t, app.router, uploadID)
+
+			req := httptest.NewRequest("GET", "/transactions/issues?upload_id="+uploadID, nil)
+			w := httptest.NewRecorder()
+			app.router.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("http response status code: got = %v, want %v (%s)", w.Code, http.StatusOK, w.Body.String())
+			}
+
+			var response handler.GetIssuesResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			if len(response.Transactions) != 1 {
+				t.Fatalf("http response: transactions: got = %d, want 1", len(response.Transactions))
+			}
+			if got := response.Transactions[0].Counterparty; got != tt.wantCounterparty {
+				t.Errorf("http response: counterparty: got = %q, want %q", got, tt.wantCounterparty)
+			}
+			if got := response.Transactions[0].Description; got != tt.wantDescription {
+				t.Errorf("http response: description: got = %q, want %q", got, tt.wantDescription)
+			}
+		})
+	}
+}
+
+// startStreamingUpload posts the head of a statement and returns the response while the rest of the
+// file is still to be sent. finish sends the rest.
+func startStreamingUpload(t *testing.T, url, filename string) (resp *http.Response, finish func()) {
+	t.Helper()
+
+	body, bodyWriter := io.Pipe()
+	writer := multipart.NewWriter(bodyWriter)
+	responses := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Post(url+"/statements", writer.FormDataContentType(), body)
+		if err != nil {
+			t.Errorf("error while sending upload: %v", err)
+			close(responses)
+			return
+		}
+		responses <- resp
+	}()
+
+	filePart, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("error while creating form file: %v", err)
+	}
+	writeRows := func(from, to int) {
+		for i := from; i < to; i++ {
+			fmt.Fprintf(filePart, "%d,COUNTERPARTY %d,CREDIT,100,SUCCESS,%s row %d\n", 1674507883+i, i, filename, i)
+		}
+	}
+	io.WriteString(filePart, "timestamp,counterparty,type,amount,status,description\n")
+	// enough rows for the format to be detected
+	writeRows(0, 500)
+
+	select {
+	case resp, ok := <-responses:
+		if !ok {
+			t.FailNow()
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp, func() {
+			writeRows(500, 1000)
+			writer.Close()
+			bodyWriter.Close()
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("upload was not answered while the file was still being sent")
+		return nil, nil
+	}
+}
+
+func TestStreamingUpload_ReturnsUploadIDBeforeFileIsSent(t *testing.T) {
+	app := newTestApp(t)
+	server := httptest.NewServer(app.router)
+	t.Cleanup(server.Close)
+
+	// the response arrives while the rest of the file is still to be sent
+	resp, finish := startStreamingUpload(t, server.URL, "large.csv")
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("http response: status code: got = %v, want %v", resp.StatusCode, http.StatusAccepted)
+	}
+	var uploaded handler.UploadStatementResponse
+	json.NewDecoder(resp.Body).Decode(&uploaded)
+	finish()
+
+	waitForUpload(t, app.router, uploaded.UploadID)
+
+	req := httptest.NewRequest("GET", "/balance?upload_id="+uploaded.UploadID, nil)
+	w := httptest.NewRecorder()
+	app.router.ServeHTTP(w, req)
+
+	var response handler.GetBalanceResponse
+	json.NewDecoder(w.Body).Decode(&response)
+	if !reflect.DeepEqual(upload.StatusCompleted, upload.Status(response.Status)) {
+		t.Fatalf("http response: field status: got = %v, want %v (%s)", response.Status, upload.StatusCompleted, response.Message)
+	}
+	if response.Balance == nil || *response.Balance != 100000 {
+		t.Errorf("http response: field balance: got = %v, want %v", response.Balance, 100000)
+	}
+}
+
+func TestUpload_RejectsFileOverSizeLimit(t *testing.T) {
+	rows := "timestamp,counterparty,type,amount,status,description\n" +
+		strings.Repeat("1674507883,JOHN DOE,DEBIT,250000,SUCCESS,restaurant\n", maxUploadSize/50)
+
+	form := &bytes.Buffer{}
+	writer := multipart.NewWriter(form)
+	part, err := writer.CreateFormFile("file", "test.csv")
+	if err != nil {
+		t.Fatalf("error while creating form file: %v", err)
+	}
+	io.WriteString(part, rows)
+	writer.Close()
+
+	var ndjson strings.Builder
+	for ndjson.Len() <= maxUploadSize {
+		ndjson.WriteString(`{"timestamp": 1674507883, "counterparty": "JOHN DOE", "type": "DEBIT", "amount": 250000, "status": "SUCCESS", "description": "restaurant"}` + "\n")
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{name: "it should turn down a form whose file is over the limit", contentType: writer.FormDataContentType(), body: form.String()},
+		{name: "it should turn down a JSON body over the limit", contentType: "application/x-ndjson", body: ndjson.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t)
+
+			req := httptest.NewRequest("POST", "/statements", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			app.router.ServeHTTP(w, req)
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("http response: status code: got = %v, want %v", w.Code, http.StatusRequestEntityTooLarge)
+			}
+		})
+	}
+}
+
+func gzipContent(t *testing.T, content string) string {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := io.WriteString(writer, content); err != nil {
+		t.Fatalf("error while compressing content: %v", err)
+	}
+	writer.Close()
+	return compressed.String()
+}
+
+func TestCompressedUpload_ProcessesGzipStatement(t *testing.T) {
+	csvContent := `timestamp,counterparty,type,amount,status,description
+1674507883,JOHN DOE,DEBIT,250000,SUCCESS,restaurant
+1674508123,ACME CORP,CREDIT,1500000,SUCCESS,salary`
+
+	tests := []struct {
+		name        string
+		opts        []testAppOption
+		wantCode    int
+		wantBalance int64
+	}{
+		{
+			name:        "it should process a gzip compressed statement",
+			wantCode:    http.StatusAccepted,
+			wantBalance: 1250000,
+		},
+		{
+			name:     "it should turn down a statement that unpacks to more than the limit",
+			opts:     []testAppOption{withArchive(usecase.ArchiveConfig{MaxSize: 64})},
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t, tt.opts...)
+
+			w := sendStatement(t, app.router, "statement.csv.gz", gzipContent(t, csvContent), nil)
+			if w.Code != tt.wantCode {
+				t.Fatalf("http response: status code: got = %v, want %v (%s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantCode != http.StatusAccepted {
+				return
+			}
+
+			var uploaded handler.UploadStatementResponse
+			json.NewDecoder(w.Body).Decode(&uploaded)
+
+			waitForUpload(t, app.router, uploaded.UploadID)
+
+			var response handler.GetBalanceResponse
+			getJSON(t, app.router, "/balance?upload_id="+uploaded.UploadID, &response)
+			if !reflect.DeepEqual(upload.StatusCompleted, upload.Status(response.Status)) {
+				t.Fatalf("http response: field status: got = %v, want %v (%s)", response.Status, upload.StatusCompleted, response.Message)
+			}
+			if response.Balance == nil || *response.Balance != tt.wantBalance {
+				t.Errorf("http response: field balance: got = %v, want %v", response.Balance, tt.wantBalance)
+			}
+		})
+	}
+}
+
+func TestArchiveUpload_FansOutIntoBatch(t *testing.T) {
+	statements := []struct {
+		name    string
+		content string
+	}{
+		{name: "account-1.csv", content: "timestamp,counterparty,type,amount,status,description\n1674507883,JOHN DOE,DEBIT,250000,SUCCESS,restaurant\n"},
+		{name: "nested/account-2.csv.gz", content: gzipContent(t, "timestamp;counterparty;type;amount;status;description\n1674508123;ACME CORP;CREDIT;1500000;SUCCESS;salary\n")},
+		{name: "readme.pdf", content: "%PDF-1.7"},
+	}
+
+	var zipped bytes.Buffer
+	zipWriter := zip.NewWriter(&zipped)
+	for _, statement := range statements {
+		w, err := zipWriter.Create(statement.name)
+		if err != nil {
+			t.Fatalf("error while creating zip entry: %v", err)
+		}
+		io.WriteString(w, statement.content)
+	}
+	zipWriter.Create("__MACOSX/._account-1.csv")
+	zipWriter.Close()
+
+	var tarball bytes.Buffer
+	tarWriter := tar.NewWriter(&tarball)
+	for _, statement := range statements {
+		tarWriter.WriteHeader(&tar.Header{Name: statement.name, Mode: 0600, Size: int64(len(statement.content)), Typeflag: tar.TypeReg})
+		io.WriteString(tarWriter, statement.content)
+	}
+	tarWriter.Close()
+
+	processed := map[string]int{string(upload.StatusCompleted): 2, string(upload.StatusFailed): 1}
+	tests := []struct {
+		name        string
+		opts        []testAppOption
+		filename    string
+		content     string
+		wantStatus  upload.Status
+		wantMessage string
+		wantCounts  map[string]int
+		wantFiles   []string
+	}{
+		{
+			name:       "it should process every statement of a zip archive",
+			filename:   "bundle.zip",
+			content:    zipped.String(),
+			wantStatus: upload.StatusCompletedWithErrors,
+			wantCounts: processed,
+			wantFiles:  []string{"account-1.csv", "nested/account-2.csv.gz", "readme.pdf"},
+		},
+		{
+			name:       "it should process every statement of a tar.gz archive",
+			filename:   "bundle.tar.gz",
+			content:    gzipContent(t, tarball.String()),
+			wantStatus: upload.StatusCompletedWithErrors,
+			wantCounts: processed,
+			wantFiles:  []string{"account-1.csv", "nested/account-2.csv.gz", "readme.pdf"},
+		},
+		{
+			name:        "it should fail an archive holding more statements than allowed",
+			opts:        []testAppOption{withArchive(usecase.ArchiveConfig{MaxEntries: 2})},
+			filename:    "bundle.zip",
+			content:     zipped.String(),
+			wantStatus:  upload.StatusFailed,
+			wantMessage: "more than 2 statements",
+			wantFiles:   []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t, tt.opts...)
+
+			response := postStatement(t, app.router, tt.filename, tt.content, nil)
+			if response.BatchID == "" || response.UploadID != "" {
+				t.Fatalf("http response: got upload_id = %q, batch_id = %q, want only batch_id", response.UploadID, response.BatchID)
+			}
+
+			batch := waitForBatch(t, app.router, response.BatchID)
+			if batch.Status != string(tt.wantStatus) || !strings.Contains(batch.Message, tt.wantMessage) {
+				t.Errorf("http response: fields status, message: got = %v, %v, want %v, %v", batch.Status, batch.Message, tt.wantStatus, tt.wantMessage)
+			}
+			if len(tt.wantCounts) > 0 && !reflect.DeepEqual(tt.wantCounts, batch.StatusCounts) {
+				t.Errorf("http response: field status_counts: got = %v, want %v", batch.StatusCounts, tt.wantCounts)
+			}
+
+			gotFiles := make([]string, 0, len(batch.Uploads))
+			for _, u := range batch.Uploads {
+				gotFiles = append(gotFiles, u.Filename)
+			}
+			if !reflect.DeepEqual(tt.wantFiles, gotFiles) {
+				t.Fatalf("http response: uploads: got = %v, want %v", gotFiles, tt.wantFiles)
+			}
+
+			for _, u := range batch.Uploads {
+				if u.Filename != "nested/account-2.csv.gz" {
+					continue
+				}
+				var balance handler.GetBalanceResponse
+				getJSON(t, app.router, "/balance?upload_id="+u.UploadID, &balance)
+				if balance.Balance == nil || *balance.Balance != 1500000 {
+					t.Errorf("http response: field balance: got = %v, want %v", balance.Balance, 1500000)
+				}
+			}
+		})
+	}
+}
+
+// workbookContent writes a workbook with one worksheet of inline string cells. Cells starting
+// with "=" are written as numbers formatted as dates, plain numbers as number cells.
+func workbookContent(t *testing.T, sheet string, rows [][]string) string {
+	var sheetData strings.Builder
+	for i, row := range rows {
+		fmt.Fprintf(&sheetData, `<row r="%d">`, i+1)
+		for j, cell := range row {
+			ref := fmt.Sprintf("%c%d", 'A'+j, i+1)
+			switch {
+			case strings.HasPrefix(cell, "="):
+				fmt.Fprintf(&sheetData, `<c r="%s" s="1"><v>%s</v></c>`, ref, cell[1:])
+			case cell != "" && strings.Trim(cell, "-.0123456789") == "":
+				fmt.Fprintf(&sheetData, `<c r="%s"><v>%s</v></c>`, ref, cell)
+			default:
+				fmt.Fprintf(&sheetData, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, cell)
+			}
+		}
+		sheetData.WriteString(`</row>`)
+	}
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Cover" sheetId="1" r:id="rId1"/><sheet name="` + sheet + `" sheetId="2" r:id="rId2"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="worksheets/sheet2.xml"/></Relationships>`},
+		{"xl/styles.xml", `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><cellXfs><xf numFmtId="0"/><xf numFmtId="22"/></cellXfs></styleSheet>`},
+		{"xl/worksheets/sheet1.xml", `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/></worksheet>`},
+		{"xl/worksheets/sheet2.xml", `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + sheetData.String() + `</sheetData></worksheet>`},
+	}
+
+	var workbook bytes.Buffer
+	writer := zip.NewWriter(&workbook)
+	for _, part := range parts {
+		w, err := writer.Create(part.name)
+		if err != nil {
+			t.Fatalf("error while creating workbook part: %v", err)
+		}
+		io.WriteString(w, part.content)
+	}
+	writer.Close()
+	return workbook.String()
+}
+
+func TestUploadWorkbook_ReadsSelectedSheetAndHeaderRow(t *testing.T) {
+	// 2023-01-23 21:04:43 and 21:08:43 as serial dates
+	workbook := workbookContent(t, "Transactions", [][]string{
+		{"Account statement"},
+		{"timestamp", "counterparty", "type", "amount", "status", "description"},
+		{"=44949.878275462965", "JOHN DOE", "DEBIT", "250000", "SUCCESS", "restaurant"},
+		{"=44949.88105324074", "ACME CORP", "CREDIT", "1500000", "SUCCESS", "salary"},
+		{"=44949.88105324074", "JANE SMITH", "DEBIT", "75000", "PENDING", "transfer"},
+	})
+
+	tests := []struct {
+		name        string
+		fields      map[string]string
+		wantStatus  upload.Status
+		wantBalance int64
+		wantIssues  []int64
+	}{
+		{
+			name:        "it should read the selected sheet from its header row",
+			fields:      map[string]string{"sheet": "Transactions", "header_row": "2"},
+			wantStatus:  upload.StatusCompleted,
+			wantBalance: 1250000,
+			wantIssues:  []int64{1674508123},
+		},
+		{
+			name:       "it should fail the upload when the workbook has no such sheet",
+			fields:     map[string]string{"sheet": "Summary", "header_row": "2"},
+			wantStatus: upload.StatusFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t)
+
+			uploadID := uploadStatement(t, app.router, "export", workbook, tt.fields)
+			got := waitForUpload(t, app.router, uploadID)
+			if got.Status != string(tt.wantStatus) {
+				t.Fatalf("http response: field status: got = %v, want %v (%s)", got.Status, tt.wantStatus, got.Message)
+			}
+			if tt.wantStatus != upload.StatusCompleted {
+				return
+			}
+
+			var response handler.GetBalanceResponse
+			getJSON(t, app.router, "/balance?upload_id="+uploadID, &response)
+			if response.Balance == nil || *response.Balance != tt.wantBalance {
+				t.Errorf("http response: field balance: got = %v, want %v", response.Balance, tt.wantBalance)
+			}
+
+			var issues handler.GetIssuesResponse
+			getJSON(t, app.router, "/transactions/issues?upload_id="+uploadID, &issues)
+			gotIssues := make([]int64, 0, len(issues.Transactions))
+			for _, tx := range issues.Transactions {
+				gotIssues = append(gotIssues, tx.Timestamp)
+			}
+			if !reflect.DeepEqual(gotIssues, tt.wantIssues) {
+				t.Errorf("http response: timestamps of issues: got = %v, want %v", gotIssues, tt.wantIssues)
+			}
+		})
+	}
+}
+
+func TestJSONUpload_ProcessesTransactions(t *testing.T) {
+	tests := []struct {
+		name           string
+		contentType    string
+		query          string
+		body           string
+		wantStatus     upload.Status
+		wantBalance    int64
+		wantRejections []int
+	}{
+		{
+			name:        "it should process a JSON array and quarantine invalid transactions",
+			contentType: "application/json",
+			query:       "?mode=lenient",
+			body: `[
+  {"timestamp": 1674507883, "counterparty": "JOHN DOE", "type": "DEBIT", "amount": 250000, "status": "SUCCESS", "description": "restaurant"},
+  {"timestamp": 1674508123, "counterparty": "ACME CORP", "type": "CREDIT", "amount": -1, "status": "SUCCESS", "description": "salary"},
+  {"timestamp": 1674508123, "counterparty": "ACME CORP", "type": "CREDIT", "amount": 1500000, "status": "SUCCESS", "description": "salary"}
+]`,
+			wantStatus:     upload.StatusCompletedWithErrors,
+			wantBalance:    1250000,
+			wantRejections: []int{2},
+		},
+		{
+			name:        "it should process newline-delimited JSON",
+			contentType: "application/x-ndjson; charset=utf-8",
+			body: `{"timestamp": 1674507883, "counterparty": "JOHN DOE", "type": "DEBIT", "amount": 250000, "status": "SUCCESS", "description": "restaurant"}
+{"timestamp": 1674508123, "counterparty": "ACME CORP", "type": "CREDIT", "amount": 1500000, "status": "SUCCESS", "description": "salary"}
+`,
+			wantStatus:  upload.StatusCompleted,
+			wantBalance: 1250000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t)
+
+			req := httptest.NewRequest("POST", "/statements"+tt.query, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			app.router.ServeHTTP(w, req)
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("http response: status code: got = %v, want %v (%s)", w.Code, http.StatusAccepted, w.Body.String())
+			}
+			var uploaded handler.UploadStatementResponse
+			json.NewDecoder(w.Body).Decode(&uploaded)
+
+			if got := waitForUpload(t, app.router, uploaded.UploadID); upload.Status(got.Status) != tt.wantStatus {
+				t.Fatalf("http response: field status: got = %v, want %v (%s)", got.Status, tt.wantStatus, got.Message)
+			}
+			var response handler.GetBalanceResponse
+			getJSON(t, app.router, "/balance?upload_id="+uploaded.UploadID, &response)
+			if response.Balance == nil || *response.Balance != tt.wantBalance {
+				t.Errorf("http response: field balance: got = %v, want %v", response.Balance, tt.wantBalance)
+			}
+
+			var rejections handler.GetRejectionsResponse
+			getJSON(t, app.router, "/uploads/"+uploaded.UploadID+"/rejections", &rejections)
+			var gotLines []int
+			for _, rejection := range rejections.Rejections {
+				gotLines = append(gotLines, rejection.Line)
+			}
+			if !reflect.DeepEqual(gotLines, tt.wantRejections) {
+				t.Errorf("http response: rejected lines: got = %v, want %v", gotLines, tt.wantRejections)
+			}
+		})
+	}
+}
+
+func TestDuplicateUpload_DetectedByContentHash(t *testing.T) {
+	csvContent := `timestamp,counterparty,type,amount,status,description
+1674507883,JOHN DOE,DEBIT,250000,SUCCESS,restaurant
+1674508123,ACME CORP,CREDIT,1500000,SUCCESS,salary`
+
+	tests := []struct {
+		name        string
+		onDuplicate string
+		// wantCode is the answer to the second upload, wantStatus the status it ends with when it is started
+		wantCode   int
+		wantStatus upload.Status
+	}{
+		{name: "it should accept the upload and mark it as duplicate by default", wantCode: http.StatusAccepted, wantStatus: upload.StatusDuplicate},
+		{name: "it should return the existing upload", onDuplicate: "existing", wantCode: http.StatusOK},
+		{name: "it should reject the upload", onDuplicate: "reject", wantCode: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t)
+
+			firstID := uploadStatement(t, app.router, "statement.csv", csvContent, nil)
+			waitForUpload(t, app.router, firstID)
+
+			fields := map[string]string{}
+			if tt.onDuplicate != "" {
+				fields["on_duplicate"] = tt.onDuplicate
+			}
+			// the same content compressed is still the same statement
+			w := sendStatement(t, app.router, "copy.csv.gz", gzipContent(t, csvContent), fields)
+			if w.Code != tt.wantCode {
+				t.Fatalf("http response: status code: got = %v, want %v (%s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			var answer handler.UploadStatementResponse
+			json.NewDecoder(w.Body).Decode(&answer)
+			if tt.wantCode != http.StatusAccepted {
+				if answer.UploadID != firstID {
+					t.Errorf("http response: field upload_id: got = %v, want %v", answer.UploadID, firstID)
+				}
+				return
+			}
+			if answer.UploadID == firstID {
+				t.Fatalf("http response: field upload_id: got = %v, want a new upload", answer.UploadID)
+			}
+
+			response := waitForUpload(t, app.router, answer.UploadID)
+			if upload.Status(response.Status) != tt.wantStatus {
+				t.Errorf("http response: field status: got = %v, want %v (%s)", response.Status, tt.wantStatus, response.Message)
+			}
+			if response.DuplicateOf != firstID {
+				t.Errorf("http response: field duplicate_of: got = %v, want %v", response.DuplicateOf, firstID)
+			}
+			if !strings.Contains(response.Message, firstID) {
+				t.Errorf("http response: field message: got = %v, want it to name upload %v", response.Message, firstID)
+			}
+
+			var balance handler.GetBalanceResponse
+			getJSON(t, app.router, "/balance?upload_id="+answer.UploadID, &balance)
+			if len(balance.Balances) != 0 {
+				t.Errorf("http response: field balances: got = %v, want none", balance.Balances)
+			}
+		})
+	}
+}
+
+func TestDuplicateUpload_AnsweredWithoutWaitingForTheQueue(t *testing.T) {
+	app := newTestApp(t, withIngestion(usecase.IngestionConfig{Workers: 1, QueueSize: 2}))
+	server := httptest.NewServer(app.router)
+	t.Cleanup(server.Close)
+
+	csvContent := "timestamp,counterparty,type,amount,status,description\n1674507883,JOHN DOE,DEBIT,250000,SUCCESS,restaurant\n"
+	firstID := uploadStatement(t, app.router, "statement.csv", csvContent, nil)
+	waitForUpload(t, app.router, firstID)
+
+	// the only worker stays busy until finish is called
+	resp, finish := startStreamingUpload(t, server.URL, "busy.csv")
+	var busy handler.UploadStatementResponse
+	json.NewDecoder(resp.Body).Decode(&busy)
+
+	tests := []struct {
+		name              string
+		filename, content string
+		onDuplicate       string
+		wantCode          int
+		wantUploadID      string
+		wantQueuePosition int
+	}{
+		{
+			name:         "it should return the existing upload while the worker is busy",
+			filename:     "copy.csv",
+			content:      csvContent,
+			onDuplicate:  "existing",
+			wantCode:     http.StatusOK,
+			wantUploadID: firstID,
+		},
+		{
+			name:         "it should reject the upload while the worker is busy",
+			filename:     "copy.csv",
+			content:      csvContent,
+			onDuplicate:  "reject",
+			wantCode:     http.StatusConflict,
+			wantUploadID: firstID,
+		},
+		{
+			name:              "it should queue new content",
+			filename:          "new.csv",
+			content:           "timestamp,counterparty,type,amount,status,description\n1674508123,ACME CORP,CREDIT,1500000,SUCCESS,salary\n",
+			onDuplicate:       "reject",
+			wantCode:          http.StatusAccepted,
+			wantQueuePosition: 1,
+		},
+	}
+	var queuedID string
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := sendStatement(t, app.router, tt.filename, tt.content, map[string]string{"on_duplicate": tt.onDuplicate})
+			if w.Code != tt.wantCode {
+				t.Fatalf("http response: status code: got = %v, want %v (%s)", w.Code, tt.wantCode, w.Body.String())
+			}
+
+			var response handler.UploadStatementResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			if tt.wantUploadID != "" && response.UploadID != tt.wantUploadID {
+				t.Errorf("http response: field upload_id: got = %v, want %v", response.UploadID, tt.wantUploadID)
+			}
+			if response.QueuePosition != tt.wantQueuePosition {
+				t.Errorf("http response: field queue_position: got = %v, want %v", response.QueuePosition, tt.wantQueuePosition)
+			}
+			if tt.wantCode == http.StatusAccepted {
+				queuedID = response.UploadID
+			}
+		})
+	}
+
+	finish()
+	for _, uploadID := range []string{busy.UploadID, queuedID} {
+		if got := waitForUpload(t, app.router, uploadID); got.Status != string(upload.StatusCompleted) {
+			t.Errorf("http response: field status of upload %v: got = %v, want %v (%s)", uploadID, got.Status, upload.StatusCompleted, got.Message)
+		}
+	}
+}
+
+func TestOverlappingStatements_DeduplicatedPerAccount(t *testing.T) {
+	january := `timestamp,counterparty,type,amount,status,description
+1672563600,ACME CORP,CREDIT,1000000,SUCCESS,salary
+1673773200,JOHN DOE,DEBIT,250000,SUCCESS,restaurant`
+	// the second statement overlaps the first by one transaction
+	february := `timestamp,counterparty,type,amount,status,description
+1673773200,John Doe,DEBIT,250000,SUCCESS,restaurant
+1675242000,JANE SMITH,CREDIT,500000,SUCCESS,refund`
+
+	tests := []struct {
+		name           string
+		account        string
+		dedup          string
+		wantBalance    int64
+		wantDuplicates int
+		wantIssues     int
+	}{
+		{name: "it should skip transactions imported before", account: "acc-1", dedup: "skip", wantBalance: 500000, wantDuplicates: 1},
+		{name: "it should keep and flag transactions imported before without counting them", account: "acc-1", dedup: "flag", wantBalance: 500000, wantDuplicates: 1, wantIssues: 1},
+		{name: "it should keep transactions imported before", account: "acc-1", dedup: "keep", wantBalance: 250000, wantDuplicates: 1},
+		{name: "it should not take the same transaction of another account for a duplicate", account: "acc-2", dedup: "skip", wantBalance: 250000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t)
+
+			firstID := uploadStatement(t, app.router, "january.csv", january, map[string]string{"account": "acc-1"})
+			waitForUpload(t, app.router, firstID)
+
+			uploadID := uploadStatement(t, app.router, "february.csv", february, map[string]string{"account": tt.account, "dedup": tt.dedup})
+			if got := waitForUpload(t, app.router, uploadID); upload.Status(got.Status) != upload.StatusCompleted {
+				t.Fatalf("http response: field status: got = %v, want %v (%s)", got.Status, upload.StatusCompleted, got.Message)
+			}
+
+			var response handler.GetBalanceResponse
+			getJSON(t, app.router, "/balance?upload_id="+uploadID, &response)
+			if response.Balance == nil || *response.Balance != tt.wantBalance {
+				t.Errorf("http response: field balance: got = %v, want %v", response.Balance, tt.wantBalance)
+			}
+			wantDuplicates := &handler.DuplicatesDTO{Policy: tt.dedup, Count: tt.wantDuplicates}
+			if !reflect.DeepEqual(response.Duplicates, wantDuplicates) {
+				t.Errorf("http response: field duplicates: got = %+v, want %+v", response.Duplicates, wantDuplicates)
+			}
+
+			var duplicates handler.GetDuplicatesResponse
+			getJSON(t, app.router, "/uploads/"+uploadID+"/duplicates", &duplicates)
+			if len(duplicates.Duplicates) != tt.wantDuplicates {
+				t.Fatalf("http response: field duplicates: got = %v, want %v", len(duplicates.Duplicates), tt.wantDuplicates)
+			}
+			for _, decision := range duplicates.Duplicates {
+				if decision.Line != 2 || decision.Action != tt.dedup || decision.DuplicateOfUpload != firstID {
+					t.Errorf("http response: duplicate: got = %+v, want line 2, action %v, upload %v", decision, tt.dedup, firstID)
+				}
+			}
+
+			var issues handler.GetIssuesResponse
+			getJSON(t, app.router, "/transactions/issues?upload_id="+uploadID, &issues)
+			if len(issues.Transactions) != tt.wantIssues {
+				t.Fatalf("http response: field transactions: got = %v, want %v", len(issues.Transactions), tt.wantIssues)
+			}
+			if tt.wantIssues > 0 && issues.Transactions[0].DuplicateOf != duplicates.Duplicates[0].DuplicateOf {
+				t.Errorf("http response: field duplicate_of: got = %v, want %v", issues.Transactions[0].DuplicateOf, duplicates.Duplicates[0].DuplicateOf)
+			}
+		})
+	}
+}
+
+func TestIngestionQueue_QueuesUploadsAndTurnsDownWhenFull(t *testing.T) {
+	app := newTestApp(t, withIngestion(usecase.IngestionConfig{Workers: 1, QueueSize: 1}))
+	server := httptest.NewServer(app.router)
+	t.Cleanup(server.Close)
+
+	var first, second handler.UploadStatementResponse
+	var finishFirst func()
+	t.Run("it should answer the upload holding the only worker while its file is sent", func(t *testing.T) {
+		resp, finish := startStreamingUpload(t, server.URL, "first.csv")
+		finishFirst = finish
+		json.NewDecoder(resp.Body).Decode(&first)
+		if first.QueuePosition != 0 {
+			t.Errorf("http response: field queue_position: got = %v, want %v", first.QueuePosition, 0)
+		}
+	})
+	if finishFirst == nil {
+		t.FailNow()
+	}
+
+	t.Run("it should queue the next upload and end its request once the file is received", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", "second.csv")
+		io.WriteString(part, "timestamp,counterparty,type,amount,status,description\n")
+		for i := 0; i < 1000; i++ {
+			fmt.Fprintf(part, "%d,COUNTERPARTY %d,CREDIT,100,SUCCESS,second.csv row %d\n", 1674507883+i, i, i)
+		}
+		writer.Close()
+
+		answered := make(chan handler.UploadStatementResponse, 1)
+		go func() {
+			resp, err := http.Post(server.URL+"/statements", writer.FormDataContentType(), body)
+			if err != nil {
+				t.Errorf("error while sending upload: %v", err)
+				close(answered)
+				return
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusAccepted {
+				t.Errorf("http response: status code: got = %v, want %v", resp.StatusCode, http.StatusAccepted)
+			}
+
+			var response handler.UploadStatementResponse
+			// the body only ends once the handler has returned
+			content, _ := io.ReadAll(resp.Body)
+			json.Unmarshal(content, &response)
+			answered <- response
+		}()
+
+		select {
+		case second = <-answered:
+		case <-time.After(5 * time.Second):
+			t.Fatal("queued upload kept its request open")
+		}
+		if second.QueuePosition != 1 {
+			t.Errorf("http response: field queue_position: got = %v, want %v", second.QueuePosition, 1)
+		}
+
+		var queued handler.GetBalanceResponse
+		getJSON(t, app.router, "/balance?upload_id="+second.UploadID, &queued)
+		if queued.Status != string(upload.StatusQueued) || queued.QueuePosition != 1 {
+			t.Errorf("http response: queued upload: got status = %v, queue_position = %v, want %v, %v", queued.Status, queued.QueuePosition, upload.StatusQueued, 1)
+		}
+	})
+
+	t.Run("it should turn down an upload while the queue is full", func(t *testing.T) {
+		w := sendStatement(t, app.router, "third.csv", "timestamp,counterparty,type,amount,status,description\n1674507883,JOHN DOE,DEBIT,250000,SUCCESS,restaurant\n", nil)
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("http response: status code: got = %v, want %v (%s)", w.Code, http.StatusTooManyRequests, w.Body.String())
+		}
+		if got := w.Header().Get("Retry-After"); got != "10" {
+			t.Errorf("http response: header Retry-After: got = %q, want %q", got, "10")
+		}
+	})
+
+	t.Run("it should process the queued upload as soon as the worker is free", func(t *testing.T) {
+		finishFirst()
+		if got := waitForUpload(t, app.router, first.UploadID); got.Status != string(upload.StatusCompleted) {
+			t.Errorf("http response: field status of first upload: got = %v, want %v (%s)", got.Status, upload.StatusCompleted, got.Message)
+		}
+		if got := waitForUpload(t, app.router, second.UploadID); got.Status != string(upload.StatusCompleted) {
+			t.Fatalf("http response: field status of second upload: got = %v, want %v (%s)", got.Status, upload.StatusCompleted, got.Message)
+		}
+
+		var balance handler.GetBalanceResponse
+		getJSON(t, app.router, "/balance?upload_id="+second.UploadID, &balance)
+		if balance.Balance == nil || *balance.Balance != 100000 {
+			t.Errorf("http response: field balance of second upload: got = %v, want %v", balance.Balance, 100000)
+		}
+	})
+}
+
+func TestGetUpload_ReportsProgress(t *testing.T) {
+	app := newTestApp(t)
+	server := httptest.NewServer(app.router)
+	t.Cleanup(server.Close)
+
+	resp, finish := startStreamingUpload(t, server.URL, "large.csv")
+	var accepted handler.UploadStatementResponse
+	json.NewDecoder(resp.Body).Decode(&accepted)
+
+	t.Run("it should report the rows processed while the file is sent", func(t *testing.T) {
+		var processing handler.GetUploadResponse
+		if code := getJSON(t, app.router, "/uploads/"+accepted.UploadID, &processing); code != http.StatusOK {
+			t.Fatalf("http response: status code: got = %v, want %v", code, http.StatusOK)
+		}
+		if processing.Status != string(upload.StatusProcessing) || processing.Progress == nil {
+			t.Fatalf("http response: got status = %v, progress = %+v, want %v with progress", processing.Status, processing.Progress, upload.StatusProcessing)
+		}
+		if processing.Progress.RowsProcessed >= 1000 {
+			t.Errorf("http response: field rows_processed: got = %v, want less than %v", processing.Progress.RowsProcessed, 1000)
+		}
+		if processing.Format != "csv" || processing.Delimiter != "," || processing.Encoding != "utf-8" || processing.Mode != "strict" {
+			t.Errorf("http response: detected layout: got format = %q, delimiter = %q, encoding = %q, mode = %q", processing.Format, processing.Delimiter, processing.Encoding, processing.Mode)
+		}
+	})
+
+	t.Run("it should report the whole file once completed", func(t *testing.T) {
+		finish()
+		completed := waitForUpload(t, app.router, accepted.UploadID)
+		if completed.Status != string(upload.StatusCompleted) {
+			t.Fatalf("http response: field status: got = %v, want %v (%s)", completed.Status, upload.StatusCompleted, completed.Message)
+		}
+		progress := completed.Progress
+		if progress.RowsProcessed != 1000 {
+			t.Errorf("http response: field rows_processed: got = %v, want %v", progress.RowsProcessed, 1000)
+		}
+		if progress.BytesRead == 0 || progress.TotalBytes != progress.BytesRead {
+			t.Errorf("http response: field bytes_read: got = %v of %v, want the whole file", progress.BytesRead, progress.TotalBytes)
+		}
+		if progress.Percent == nil || *progress.Percent != 100 {
+			t.Errorf("http response: field percent: got = %v, want %v", progress.Percent, 100)
+		}
+		if progress.ETASeconds != nil {
+			t.Errorf("http response: field eta_seconds: got = %v, want none once completed", *progress.ETASeconds)
+		}
+		if completed.ContentHash == "" || completed.CompletedAt == 0 {
+			t.Errorf("http response: completed upload: got content_hash = %q, completed_at = %v", completed.ContentHash, completed.CompletedAt)
+		}
+	})
+
+	t.Run("it should answer 404 for an unknown upload", func(t *testing.T) {
+		var response handler.GetUploadResponse
+		if code := getJSON(t, app.router, "/uploads/unknown", &response); code != http.StatusNotFound {
+			t.Errorf("http response: status code: got = %v, want %v", code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestCancelUpload_DiscardsTransactions(t *testing.T) {
+	app := newTestApp(t, withIngestion(usecase.IngestionConfig{Workers: 1, QueueSize: 1}))
+	server := httptest.NewServer(app.router)
+	t.Cleanup(server.Close)
+
+	cancel := func(method, path string) int {
+		req := httptest.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	processingResp, finishProcessing := startStreamingUpload(t, server.URL, "wrong.csv")
+	var processing handler.UploadStatementResponse
+	json.NewDecoder(processingResp.Body).Decode(&processing)
+	queuedID := uploadStatement(t, app.router, "queued.csv", "timestamp,counterparty,type,amount,status,description\n1674507883,JANE DOE,DEBIT,100000,SUCCESS,rent\n", nil)
+
+	t.Run("it should take a queued upload out of the queue", func(t *testing.T) {
+		if code := cancel("POST", "/uploads/"+queuedID+"/cancel"); code != http.StatusAccepted {
+			t.Fatalf("http response: status code: got = %v, want %v", code, http.StatusAccepted)
+		}
+		if got := waitForUpload(t, app.router, queuedID); got.Status != string(upload.StatusCancelled) {
+			t.Errorf("http response: field status: got = %v, want %v (%s)", got.Status, upload.StatusCancelled, got.Message)
+		}
+	})
+
+	t.Run("it should stop a processing upload at its next row", func(t *testing.T) {
+		if code := cancel("DELETE", "/uploads/"+processing.UploadID); code != http.StatusAccepted {
+			t.Fatalf("http response: status code: got = %v, want %v", code, http.StatusAccepted)
+		}
+		finishProcessing()
+
+		got := waitForUpload(t, app.router, processing.UploadID)
+		if got.Status != string(upload.StatusCancelled) {
+			t.Fatalf("http response: field status: got = %v, want %v (%s)", got.Status, upload.StatusCancelled, got.Message)
+		}
+		if got.Progress == nil || got.Progress.RowsProcessed >= 1000 {
+			t.Errorf("http response: field progress: got = %+v, want less than %v rows", got.Progress, 1000)
+		}
+
+		var balance handler.GetBalanceResponse
+		getJSON(t, app.router, "/balance?upload_id="+processing.UploadID, &balance)
+		if balance.Balance != nil || len(balance.Balances) != 0 {
+			t.Errorf("http response: balance: got = %v, %v, want none", balance.Balance, balance.Balances)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+	}{
+		{name: "it should answer 409 for an upload cancelled before", method: "DELETE", path: "/uploads/" + processing.UploadID, wantCode: http.StatusConflict},
+		{name: "it should answer 409 for an upload cancelled before with POST", method: "POST", path: "/uploads/" + queuedID + "/cancel", wantCode: http.StatusConflict},
+		{name: "it should answer 404 for an unknown upload", method: "DELETE", path: "/uploads/unknown", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := cancel(tt.method, tt.path); code != tt.wantCode {
+				t.Errorf("http response: status code: got = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+
+	t.Run("it should free the worker and the place in the queue", func(t *testing.T) {
+		uploadID := uploadStatement(t, app.router, "right.csv", "timestamp,counterparty,type,amount,status,description\n1674507883,JOHN DOE,DEBIT,250000,SUCCESS,restaurant\n", nil)
+		if got := waitForUpload(t, app.router, uploadID); got.Status != string(upload.StatusCompleted) {
+			t.Errorf("http response: field status: got = %v, want %v (%s)", got.Status, upload.StatusCompleted, got.Message)
+		}
+	})
+}
+
+// validateStatement posts a statement file to the dry run with the given query string.
+func validateStatement(t *testing.T, router http.Handler, filename, content, query string) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("error while creating form file: %v", err)
+	}
+	io.WriteString(part, content)
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/statements/validate"+query, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestValidateStatement_ReportsWithoutStoring(t *testing.T) {
+	app := newTestApp(t)
+
+	csvContent := "timestamp;counterparty;type;amount;status;description\n" +
+		"1674507883;JOHN DOE;DEBIT;250000;SUCCESS;restaurant\n" +
+		"not-a-timestamp;ACME CORP;CREDIT;1500000;SUCCESS;salary\n" +
+		"1674508456;JANE SMITH;DEBIT;75000;SUCCESS;gift\n" +
+		"1674508789;BOB BROWN;REFUND;100000;SUCCESS;processing\n"
+
+	tests := []struct {
+		name      string
+		filename  string
+		content   string
+		query     string
+		wantCode  int
+		wantLines []int
+		wantRaw   []string
+	}{
+		{
+			name:      "it should report the rows that would be rejected as JSON",
+			filename:  "march.csv",
+			content:   csvContent,
+			wantCode:  http.StatusOK,
+			wantLines: []int{3, 5},
+		},
+		{
+			name:      "it should report the rows that would be rejected as CSV",
+			filename:  "march.csv",
+			content:   csvContent,
+			query:     "?format=csv",
+			wantCode:  http.StatusOK,
+			wantLines: []int{3, 5},
+			wantRaw:   []string{"not-a-timestamp;ACME CORP;CREDIT;1500000;SUCCESS;salary", "1674508789;BOB BROWN;REFUND;100000;SUCCESS;processing"},
+		},
+		{
+			name:     "it should turn down a file that is not a statement",
+			filename: "notes.txt",
+			content:  "just some notes",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "it should turn down an unknown report format",
+			filename: "march.csv",
+			content:  csvContent,
+			query:    "?format=xml",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := validateStatement(t, app.router, tt.filename, tt.content, tt.query)
+			if w.Code != tt.wantCode {
+				t.Fatalf("http response: status code: got = %v, want %v (%s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			if tt.wantRaw != nil {
+				if got := w.Header().Get("Content-Disposition"); got != "attachment; filename=march-errors.csv" {
+					t.Errorf("http response: header Content-Disposition: got = %v, want %v", got, "attachment; filename=march-errors.csv")
+				}
+				records, err := csv.NewReader(w.Body).ReadAll()
+				if err != nil {
+					t.Fatalf("error while reading report: %v", err)
+				}
+				if len(records) != len(tt.wantLines)+1 || !reflect.DeepEqual(records[0], []string{"line", "error", "raw"}) {
+					t.Fatalf("http response: report: got = %v, want a header and %v rows", records, len(tt.wantLines))
+				}
+				for i, record := range records[1:] {
+					if record[0] != strconv.Itoa(tt.wantLines[i]) || record[2] != tt.wantRaw[i] {
+						t.Errorf("http response: report row %d: got = %v, want line %v with %q", i+1, record, tt.wantLines[i], tt.wantRaw[i])
+					}
+				}
+				return
+			}
+
+			var response handler.ValidateStatementResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			if response.Valid {
+				t.Errorf("http response: field valid: got = %v, want %v", response.Valid, false)
+			}
+			if response.Layout.Format != "csv" || response.Layout.Delimiter != ";" {
+				t.Errorf("http response: field layout: got = %+v, want csv with delimiter ;", response.Layout)
+			}
+			if response.Rows != 4 || response.ValidRows != 2 || response.InvalidRows != 2 {
+				t.Errorf("http response: row counts: got = %v, %v, %v, want %v, %v, %v", response.Rows, response.ValidRows, response.InvalidRows, 4, 2, 2)
+			}
+			gotLines := make([]int, 0)
+			for _, rejection := range response.Errors {
+				gotLines = append(gotLines, rejection.Line)
+			}
+			if !reflect.DeepEqual(tt.wantLines, gotLines) {
+				t.Errorf("http response: invalid lines: got = %v, want %v", gotLines, tt.wantLines)
+			}
+			if response.ContentHash == "" {
+				t.Errorf("http response: field content_hash is empty")
+			}
+		})
+	}
+
+	t.Run("it should leave the content to the first real upload of it", func(t *testing.T) {
+		uploadID := uploadStatement(t, app.router, "march.csv", csvContent, map[string]string{"mode": "lenient", "on_duplicate": "reject"})
+		if got := waitForUpload(t, app.router, uploadID); got.Status != string(upload.StatusCompletedWithErrors) {
+			t.Errorf("http response: field status of upload after dry run: got = %v, want %v (%s)", got.Status, upload.StatusCompletedWithErrors, got.Message)
+		}
+	})
+}
+
+func TestBusinessRules_RejectFlagAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	writeRules := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("error while writing rules file: %v", err)
+		}
+	}
+	initialRules := `{"rules": [
+		{"name": "blocked", "kind": "counterparty_blocklist", "severity": "reject", "counterparties": ["Shady Ltd"]},
+		{"name": "large-debit", "kind": "max_amount", "severity": "flag", "type": "DEBIT", "currency": "IDR", "amount": 1000000},
+		{"name": "invoice", "kind": "description_pattern", "severity": "warn", "pattern": "^INV-"}
+	]}`
+	writeRules(initialRules)
+
+	rules := usecase.NewRules(path)
+	if err := rules.Reload(context.Background()); err != nil {
+		t.Fatalf("error while loading rules: %v", err)
+	}
+	app := newTestApp(t, withRules(rules))
+	go rules.Watch(app.appCtx, 10*time.Millisecond)
+
+	csvContent := `timestamp,counterparty,type,amount,status,description
+1674507883,JOHN DOE,DEBIT,2500000,SUCCESS,INV-1 laptop
+1674508456,SHADY LTD,CREDIT,75000,SUCCESS,INV-2 refund
+1674509012,ALICE GREEN,CREDIT,500000,SUCCESS,consulting`
+
+	// the cases run in order against the same server, each changing the rules file before its upload
+	tests := []struct {
+		name           string
+		rules          string
+		ruleCount      int
+		content        string
+		wantRejections []handler.RejectionDTO
+		wantResults    map[string][]handler.RuleResultDTO
+	}{
+		{
+			name:           "it should reject, flag and warn about transactions by the rules",
+			rules:          initialRules,
+			ruleCount:      3,
+			content:        csvContent,
+			wantRejections: []handler.RejectionDTO{{Line: 3, Error: "rule blocked: counterparty 'SHADY LTD' is on the block-list"}},
+			wantResults: map[string][]handler.RuleResultDTO{
+				"JOHN DOE":    {{Rule: "large-debit", Severity: "flag", Message: "amount 2500000 exceeds 1000000"}},
+				"ALICE GREEN": {{Rule: "invoice", Severity: "warn", Message: "description does not match '^INV-'"}},
+			},
+		},
+		{
+			name:      "it should apply a changed rules file to the next upload without a restart",
+			rules:     `{"rules": [{"name": "blocked", "kind": "counterparty_blocklist", "severity": "reject", "counterparties": ["John Doe"]}]}`,
+			ruleCount: 1,
+			// a trailing line break keeps the upload from being taken for a duplicate of the first
+			content:        csvContent + "\n",
+			wantRejections: []handler.RejectionDTO{{Line: 2, Error: "rule blocked: counterparty 'JOHN DOE' is on the block-list"}},
+			wantResults:    map[string][]handler.RuleResultDTO{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeRules(tt.rules)
+			// wait for the watcher to pick the file up
+			deadline := time.Now().Add(5 * time.Second)
+			for len(rules.Current().Rules) != tt.ruleCount {
+				if time.Now().After(deadline) {
+					t.Fatalf("rules in effect: got = %d, want %d", len(rules.Current().Rules), tt.ruleCount)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			uploadID := uploadStatement(t, app.router, "rules.csv", tt.content, map[string]string{"mode": "lenient"})
+			waitForUpload(t, app.router, uploadID)
+
+			var rejections handler.GetRejectionsResponse
+			getJSON(t, app.router, "/uploads/"+uploadID+"/rejections", &rejections)
+			gotRejections := make([]handler.RejectionDTO, 0, len(rejections.Rejections))
+			for _, rejection := range rejections.Rejections {
+				gotRejections = append(gotRejections, handler.RejectionDTO{Line: rejection.Line, Error: rejection.Error})
+			}
+			if !reflect.DeepEqual(gotRejections, tt.wantRejections) {
+				t.Errorf("http response: rejections: got = %+v, want %+v", gotRejections, tt.wantRejections)
+			}
+
+			var issues handler.GetIssuesResponse
+			if code := getJSON(t, app.router, "/transactions/issues?upload_id="+uploadID, &issues); code != http.StatusOK {
+				t.Fatalf("http response: status code: got = %v, want %v", code, http.StatusOK)
+			}
+			gotResults := make(map[string][]handler.RuleResultDTO)
+			for _, tx := range issues.Transactions {
+				gotResults[tx.Counterparty] = tx.RuleResults
+			}
+			if !reflect.DeepEqual(gotResults, tt.wantResults) {
+				t.Errorf("http response: rule results: got = %+v, want %+v", gotResults, tt.wantResults)
+			}
+		})
+	}
+}
+
+func TestBalanceAssertion_ReportsDiscrepancy(t *testing.T) {
+	csvContent := `timestamp,counterparty,type,amount,status,description
+1674507883,JOHN DOE,DEBIT,250000,SUCCESS,restaurant
+1674508456,JANE SMITH,DEBIT,75000,FAILED,gift
+1674509012,ALICE GREEN,CREDIT,500000,SUCCESS,consulting`
+	// the last row is missing, so the statement ends 500000 short of its closing balance
+	truncated := csvContent[:strings.LastIndex(csvContent, "\n")]
+	balances := map[string]string{"opening_balance": "1000000", "closing_balance": "1250000"}
+
+	tests := []struct {
+		name        string
+		content     string
+		fields      map[string]string
+		wantCode    int
+		wantStatus  upload.Status
+		wantCheck   *handler.BalanceCheckDTO
+		wantMessage string
+	}{
+		{
+			name:       "it should check the statement against its balances",
+			content:    csvContent,
+			fields:     balances,
+			wantCode:   http.StatusAccepted,
+			wantStatus: upload.StatusCompleted,
+			wantCheck:  &handler.BalanceCheckDTO{Currency: "IDR", Opening: 1000000, Net: 250000, ExpectedClosing: 1250000, ComputedClosing: 1250000, Balanced: true},
+		},
+		{
+			name:        "it should report the discrepancy of a truncated statement",
+			content:     truncated,
+			fields:      balances,
+			wantCode:    http.StatusAccepted,
+			wantStatus:  upload.StatusCompletedWithErrors,
+			wantCheck:   &handler.BalanceCheckDTO{Currency: "IDR", Opening: 1000000, Net: -250000, ExpectedClosing: 1250000, ComputedClosing: 750000, Discrepancy: -500000},
+			wantMessage: "closing balance is off by -5000.00 IDR",
+		},
+		{
+			name:       "it should not check a statement without balances",
+			content:    csvContent,
+			wantCode:   http.StatusAccepted,
+			wantStatus: upload.StatusCompleted,
+		},
+		{
+			name:     "it should turn down a balance that is not in minor units",
+			content:  csvContent,
+			fields:   map[string]string{"opening_balance": "1000.50"},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t)
+
+			w := sendStatement(t, app.router, "statement.csv", tt.content, tt.fields)
+			if w.Code != tt.wantCode {
+				t.Fatalf("http response: status code: got = %v, want %v (%s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantCode != http.StatusAccepted {
+				return
+			}
+
+			var uploaded handler.UploadStatementResponse
+			json.NewDecoder(w.Body).Decode(&uploaded)
+			waitForUpload(t, app.router, uploaded.UploadID)
+
+			var response handler.GetBalanceResponse
+			getJSON(t, app.router, "/balance?upload_id="+uploaded.UploadID, &response)
+			if response.Status != string(tt.wantStatus) || !strings.Contains(response.Message, tt.wantMessage) {
+				t.Errorf("http response: fields status, message: got = %v, %v, want %v, %v", response.Status, response.Message, tt.wantStatus, tt.wantMessage)
+			}
+			if !reflect.DeepEqual(response.BalanceCheck, tt.wantCheck) {
+				t.Errorf("http response: field balance_check: got = %+v, want %+v", response.BalanceCheck, tt.wantCheck)
+			}
+			if tt.wantCheck != nil && (response.OpeningBalance == nil || response.OpeningBalance.Amount != tt.wantCheck.Opening || response.OpeningBalance.Date != "") {
+				t.Errorf("http response: field opening_balance: got = %+v, want %v without a date", response.OpeningBalance, tt.wantCheck.Opening)
+			}
+		})
+	}
+}
+
+func TestStatementPreambleAndTrailer_ValidatesTotals(t *testing.T) {
+	app := newTestApp(t)
+
+	preamble := `Account Number,1234567890
+IBAN,DE89 3704 0044 0532 0130 00
+Statement Period,2024-01-01 - 2024-01-31
+Currency,USD
+Opening Balance,100000
+`
+	transactions := `timestamp,counterparty,type,amount,status,description
+1674507883,JOHN DOE,DEBIT,2500,SUCCESS,restaurant
+1674509012,ALICE GREEN,CREDIT,50000,SUCCESS,consulting
+`
+	total := func(v int64) *int64 { return &v }
+	statement := func(trailer *handler.TrailerDTO, mismatches ...handler.TrailerMismatchDTO) *handler.StatementDetailsDTO {
+		return &handler.StatementDetailsDTO{
+			AccountNumber:     "1234567890",
+			IBAN:              "DE89370400440532013000",
+			PeriodStart:       "2024-01-01",
+			PeriodEnd:         "2024-01-31",
+			Currency:          "USD",
+			Trailer:           trailer,
+			TrailerMismatches: mismatches,
+		}
+	}
+
+	tests := []struct {
+		name          string
+		filename      string
+		trailer       string
+		dryRun        bool
+		wantStatus    upload.Status
+		wantStatement *handler.StatementDetailsDTO
+		wantBalanced  bool
+		wantMessage   string
+	}{
+		{
+			name:          "it should read the preamble and check the trailer of an upload",
+			filename:      "statement.csv",
+			trailer:       "Total Rows,2\nTotal Credits,50000\nTotal Debits,2500\nClosing Balance,147500\n",
+			wantStatus:    upload.StatusCompleted,
+			wantStatement: statement(&handler.TrailerDTO{RowCount: total(2), TotalCredits: total(50000), TotalDebits: total(2500)}),
+			wantBalanced:  true,
+		},
+		{
+			name:       "it should report the totals of an upload that do not match its trailer",
+			filename:   "short.csv",
+			trailer:    "Total Rows,3\nTotal Credits,50000\n",
+			wantStatus: upload.StatusCompletedWithErrors,
+			wantStatement: statement(&handler.TrailerDTO{RowCount: total(3), TotalCredits: total(50000)},
+				handler.TrailerMismatchDTO{Field: "row_count", Stated: 3, Ingested: 2}),
+			wantMessage: "trailer states 3 rows but 2 were read",
+		},
+		{
+			name:     "it should report the totals that do not match the trailer in a dry run",
+			filename: "dry-run.csv",
+			trailer:  "Total Debits,3000\n",
+			dryRun:   true,
+			wantStatement: statement(&handler.TrailerDTO{TotalDebits: total(3000)},
+				handler.TrailerMismatchDTO{Field: "total_debits", Stated: 3000, Ingested: 2500}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := preamble + transactions + tt.trailer
+			if tt.dryRun {
+				var response handler.ValidateStatementResponse
+				json.NewDecoder(validateStatement(t, app.router, tt.filename, content, "").Body).Decode(&response)
+				if !response.Valid || response.Rows != 2 {
+					t.Errorf("http response: fields valid, rows: got = %v, %v, want %v, %v", response.Valid, response.Rows, true, 2)
+				}
+				if !reflect.DeepEqual(response.Statement, tt.wantStatement) {
+					t.Errorf("http response: field statement: got = %+v, want %+v", response.Statement, tt.wantStatement)
+				}
+				return
+			}
+
+			uploadID := uploadStatement(t, app.router, tt.filename, content, nil)
+			response := waitForUpload(t, app.router, uploadID)
+			if response.Status != string(tt.wantStatus) || !strings.Contains(response.Message, tt.wantMessage) {
+				t.Errorf("http response: fields status, message: got = %v, %v, want %v, %v", response.Status, response.Message, tt.wantStatus, tt.wantMessage)
+			}
+			if response.Currency != "USD" {
+				t.Errorf("http response: field currency: got = %v, want %v", response.Currency, "USD")
+			}
+			if !reflect.DeepEqual(response.Statement, tt.wantStatement) {
+				t.Errorf("http response: field statement: got = %+v, want %+v", response.Statement, tt.wantStatement)
+			}
+			if tt.wantBalanced && (response.BalanceCheck == nil || !response.BalanceCheck.Balanced || response.BalanceCheck.Currency != "USD") {
+				t.Errorf("http response: field balance_check: got = %+v, want a balanced check in USD", response.BalanceCheck)
+			}
+		})
+	}
+}
+
+func TestAccounts_AggregateUploads(t *testing.T) {
+	app := newTestApp(t)
+
+	january := `timestamp,counterparty,type,amount,status,description
+1674500000,ACME CORP,CREDIT,500000,SUCCESS,salary
+1674700000,JOHN DOE,DEBIT,100000,SUCCESS,rent`
+	// overlaps january with the rent payment, which is skipped as a duplicate
+	february := `timestamp,counterparty,type,amount,status,description
+1674600000,JANE SMITH,DEBIT,50000,SUCCESS,groceries
+1674700000,JOHN DOE,DEBIT,100000,SUCCESS,rent
+1674800000,ALICE GREEN,DEBIT,20000,FAILED,gift`
+	// repeats the salary, flagged as a duplicate and so left out of the account
+	corrected := `timestamp,counterparty,type,amount,status,description
+1674500000,ACME CORP,CREDIT,500000,SUCCESS,salary`
+
+	// each upload finishes before the next, so that the later ones find the transactions of the earlier
+	for _, u := range []struct{ filename, content, account, dedup string }{
+		{filename: "january.csv", content: january, account: "acc-1"},
+		{filename: "february.csv", content: february, account: "acc-1"},
+		{filename: "other.csv", content: january + "\n", account: "acc-2"},
+		{filename: "corrected.csv", content: corrected, account: "acc-1", dedup: "flag"},
+	} {
+		fields := map[string]string{"account": u.account}
+		if u.dedup != "" {
+			fields["dedup"] = u.dedup
+		}
+		waitForUpload(t, app.router, uploadStatement(t, app.router, u.filename, u.content, fields))
+	}
+
+	balances := []struct {
+		name        string
+		account     string
+		wantBalance int64
+		wantUploads int
+	}{
+		{name: "it should sum up the uploads of an account without its duplicates", account: "acc-1", wantBalance: 350000, wantUploads: 3},
+		{name: "it should keep the uploads of another account apart", account: "acc-2", wantBalance: 400000, wantUploads: 1},
+	}
+	for _, tt := range balances {
+		t.Run(tt.name, func(t *testing.T) {
+			var response handler.GetAccountBalanceResponse
+			if code := getJSON(t, app.router, "/accounts/"+tt.account+"/balance", &response); code != http.StatusOK {
+				t.Fatalf("http response: status code: got = %v, want %v", code, http.StatusOK)
+			}
+			if response.Balance == nil || *response.Balance != tt.wantBalance {
+				t.Errorf("http response: field balance: got = %v, want %v", response.Balance, tt.wantBalance)
+			}
+			if response.Uploads != tt.wantUploads || response.CompletedUploads != tt.wantUploads {
+				t.Errorf("http response: fields uploads, completed_uploads: got = %v, %v, want %v, %v", response.Uploads, response.CompletedUploads, tt.wantUploads, tt.wantUploads)
+			}
+		})
+	}
+
+	t.Run("it should list the transactions of an account in chronological order", func(t *testing.T) {
+		var timestamps []int64
+		for page := 1; page <= 2; page++ {
+			var response handler.GetAccountTransactionsResponse
+			if code := getJSON(t, app.router, fmt.Sprintf("/accounts/acc-1/transactions?page=%d&page_size=2", page), &response); code != http.StatusOK {
+				t.Fatalf("http response: status code: got = %v, want %v", code, http.StatusOK)
+			}
+			if response.Pagination.TotalItems != 4 || response.Pagination.TotalPages != 2 {
+				t.Errorf("http response: field pagination: got = %+v, want %v items on %v pages", response.Pagination, 4, 2)
+			}
+			for _, tx := range response.Transactions {
+				if tx.UploadID == "" || tx.DuplicateOf != "" {
+					t.Errorf("http response: transaction %s: got upload_id = %q, duplicate_of = %q", tx.ID, tx.UploadID, tx.DuplicateOf)
+				}
+				timestamps = append(timestamps, tx.Timestamp)
+			}
+		}
+
+		want := []int64{1674500000, 1674600000, 1674700000, 1674800000}
+		if !reflect.DeepEqual(timestamps, want) {
+			t.Errorf("http response: transaction timestamps: got = %v, want %v", timestamps, want)
+		}
+	})
+
+	unknown := []struct {
+		name string
+		path string
+	}{
+		{name: "it should answer 404 for the balance of an unknown account", path: "/accounts/acc-3/balance"},
+		{name: "it should answer 404 for the transactions of an unknown account", path: "/accounts/acc-3/transactions"},
+	}
+	for _, tt := range unknown {
+		t.Run(tt.name, func(t *testing.T) {
+			var response map[string]any
+			if code := getJSON(t, app.router, tt.path, &response); code != http.StatusNotFound {
+				t.Errorf("http response: status code: got = %v, want %v", code, http.StatusNotFound)
+			}
+		})
+	}
+}